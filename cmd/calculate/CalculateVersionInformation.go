@@ -2,16 +2,56 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log"
 	"os"
+
+	"sbom-processor/internal/catalogue"
+	"sbom-processor/internal/db"
+	"sbom-processor/internal/lag"
+	"sbom-processor/internal/logging"
+	"sbom-processor/internal/metrics"
 	"sbom-processor/internal/mvn"
+	"sbom-processor/internal/report"
+	"sbom-processor/internal/sbom"
+	"sbom-processor/internal/shutdown"
 
+	"github.com/janniclas/beehive"
+	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
 
+var fillCache = flag.Bool("fillCache", false, "fill the maven cache before calculating the technical lag")
+var lookup = flag.String("lookup", "name", "name or digest. defines whether java archives are resolved by name or by their sha1 digest with name as fallback.")
+var logLevel = flag.Int("logLevel", 0, "Can be 0 for INFO, -4 for DEBUG, 4 for WARN, or 8 for ERROR. Defaults to INFO.")
+var metricsAddr = flag.String("metricsAddr", "", "address to serve Prometheus metrics on, e.g., :9090. metrics aren't served if empty.")
+var reportPath = flag.String("report", "", "file to write the JSON run report to. written to stderr if empty.")
+var runsCollectionName = flag.String("runsCollection", "", "collection name to store the run report in, e.g., runs. the report isn't stored if empty.")
+var dbName = flag.String("db", "sbom_metadata", "database name to connect to")
+var collectionName = flag.String("collection", "sboms", "collection name for SBOMs")
+var componentsCollectionName = flag.String("componentsCollection", "", "collection name of the component catalogue. set it to read SBOMs stored in the normalized layout, they are read as embedded if empty.")
+var lagCollectionName = flag.String("lagCollection", "lag", "collection name to store the technical lag in")
+
 func main() {
 
+	flag.Parse()
+
+	ctx, done := shutdown.Context()
+	defer done()
+
+	run := report.New("lag")
+
+	logger := logging.SetUpLogging(*logLevel)
+
+	if err := metrics.Serve(*metricsAddr); err != nil {
+		log.Fatalf("Unable to serve metrics %s\n", err.Error())
+	}
+
+	if *lookup != "name" && *lookup != "digest" {
+		log.Fatalf("Unknown lookup %s, choose name or digest\n", *lookup)
+	}
+
 	// INPUT VALIDATION
 	uri := os.Getenv("MONGO_URI")
 	usr := os.Getenv("MONGO_USERNAME")
@@ -38,25 +78,112 @@ func main() {
 		}
 	}()
 
-	db := client.Database("sbom_metadata")
+	database := client.Database(*dbName)
+	sbomsColl := database.Collection(*collectionName)
+	sbomStore := catalogue.Store{Sboms: sbomsColl, Components: catalogue.Collection(database, *componentsCollectionName)}
+	if err := sbomStore.Verify(ctx); err != nil {
+		log.Fatalf("Unable to read the SBOMs %s\n", err.Error())
+	}
+
+	publish := func() {
+		if err := run.Publish(ctx, *reportPath, report.Runs(database, *runsCollectionName)); err != nil {
+			logger.Error("Unable to publish the run report", "err", err)
+		}
+	}
+
+	// SBOMs in flight are calculated and stored when ctx is canceled
+	drain := context.WithoutCancel(ctx)
 
 	cache := mvn.MvnCache{
-		MvnMirror:   db.Collection("mvn_mirror"),
-		MultiResult: db.Collection("multi_result"),
-		Blacklist:   db.Collection("blacklist"),
-		Ctx:         context.Background(),
+		MvnMirror:   database.Collection("mvn_mirror"),
+		MultiResult: database.Collection("multi_result"),
+		Blacklist:   database.Collection("blacklist"),
+		Ctx:         ctx,
 	}
 
-	sbomsColl := db.Collection("sboms")
+	if *fillCache {
+		logger.Info("Fill maven cache", "lookup", *lookup)
+		if *lookup == "digest" {
+			err = cache.FillCacheByDigest(&sbomStore)
+		} else {
+			err = cache.FillCache(&sbomStore)
+		}
+		run.Track(cache.Progress())
+		if err != nil && ctx.Err() == nil {
+			run.Fail(err)
+			logger.Error("Filling the maven cache failed", "err", err)
+			publish()
+			os.Exit(1)
+		}
+	}
+
+	// the lag depends on the cache, don't start with a partially filled one
+	if ctx.Err() != nil {
+		publish()
+		return
+	}
+
+	versionLookup := lag.MongoLookup{
+		Versions:     database.Collection("versions"),
+		DepsMetadata: database.Collection("deps_metadata"),
+		MvnMirror:    cache.MvnMirror,
+		Ctx:          drain,
+	}
+	lagColl := database.Collection(*lagCollectionName)
+
+	for _, idx := range []struct {
+		coll *mongo.Collection
+		spec string
+	}{
+		{versionLookup.Versions, "versions"},
+		{versionLookup.DepsMetadata, "deps_metadata"},
+		{cache.MvnMirror, "mvn_mirror"},
+		{cache.MultiResult, "multi_result"},
+		{cache.Blacklist, "blacklist"},
+		{lagColl, "lag"},
+	} {
+		if err := db.Verify(ctx, idx.coll, db.Spec(idx.spec)); err != nil {
+			logger.Warn("Index verification failed", "collection", idx.coll.Name(), "err", err)
+		}
+	}
+
+	logger.Info("Calculate technical lag called", "db", *dbName, "collection", *collectionName, "lagCollection", *lagCollectionName)
+
+	progress := metrics.NewProgress("lag")
+	if total, err := sbomsColl.EstimatedDocumentCount(ctx); err == nil {
+		progress.SetTotal(total)
+	}
+	progress.Start(logger, metrics.LogInterval)
+	run.Track(progress)
+	run.Output(*dbName + "." + *lagCollectionName)
+
+	cursor, err := sbomStore.Find(ctx, bson.D{})
+	if err != nil {
+		panic(err)
+	}
+	defer cursor.Close(drain)
+
+	it := db.MongodbIterator[sbom.StoredSbom](ctx, cursor)
+
+	worker := beehive.Worker[sbom.StoredSbom, lag.SbomLag]{
+		Work: metrics.Track(progress, func(s *sbom.StoredSbom) (*lag.SbomLag, error) {
+			l := lag.Compute(s, &versionLookup)
+			logger.Debug("Calculated technical lag", "source", s.Source.Name, "aggregate", l.Aggregate)
+			return l, nil
+		}),
+	}
 
-	cache.FillCache(sbomsColl)
+	buffer := 100
+	writer := beehive.NewBufferedCollector(
+		metrics.TrackWrite(progress, func(t []*lag.SbomLag) error {
+			return lag.Store(drain, lagColl, t)
+		}),
+		beehive.BufferedCollectorConfig{BufferSize: &buffer})
 
-	// var results []bson.M
-	// if err = cursor.All(context.TODO(), &results); err != nil {
-	// 	panic(err)
-	// }
+	dispatcher := beehive.NewDispatcher(worker, it, *writer, beehive.DispatcherConfig{})
 
-	// fmt.Printf("%+v\n", results)
-	// fmt.Printf("length %d", len(results))
+	dispatcher.Dispatch()
+	progress.Stop(logger)
 
+	publish()
 }
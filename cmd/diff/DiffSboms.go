@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"sbom-processor/internal/catalogue"
+	"sbom-processor/internal/logging"
+	"sbom-processor/internal/metrics"
+	"sbom-processor/internal/report"
+	"sbom-processor/internal/sbom"
+	"sbom-processor/internal/shutdown"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var oldPath = flag.String("old", "", "path to the old CycloneDX SBOM")
+var newPath = flag.String("new", "", "path to the new CycloneDX SBOM")
+var oldSource = flag.String("oldSource", "", "id, name, or version of the old SBOM source in the database")
+var newSource = flag.String("newSource", "", "id, name, or version of the new SBOM source in the database")
+var matchBy = flag.String("matchBy", "name", "name or purl. defines how components of both SBOMs are matched.")
+var format = flag.String("format", "text", "text or json")
+var out = flag.String("out", "", "File to write the diff to. Defaults to stdout.")
+var logLevel = flag.Int("logLevel", 0, "Can be 0 for INFO, -4 for DEBUG, 4 for WARN, or 8 for ERROR. Defaults to INFO.")
+var metricsAddr = flag.String("metricsAddr", "", "address to serve Prometheus metrics on, e.g., :9090. metrics aren't served if empty.")
+var reportPath = flag.String("report", "", "file to write the JSON run report to. written to stderr if empty.")
+var runsCollectionName = flag.String("runsCollection", "", "collection name to store the run report in, e.g., runs. the report isn't stored if empty.")
+var dbName = flag.String("db", "sbom_metadata", "database name to connect to")
+var collectionName = flag.String("collection", "sboms", "collection name for SBOMs")
+var componentsCollectionName = flag.String("componentsCollection", "", "collection name of the component catalogue. set it to read SBOMs stored in the normalized layout, they are read as embedded if empty.")
+
+func main() {
+
+	flag.Parse()
+
+	ctx, done := shutdown.Context()
+	defer done()
+
+	run := report.New("diff")
+
+	logger := logging.SetUpLogging(*logLevel)
+
+	if err := metrics.Serve(*metricsAddr); err != nil {
+		log.Fatalf("Unable to serve metrics %s\n", err.Error())
+	}
+
+	if *matchBy != string(sbom.MatchByName) && *matchBy != string(sbom.MatchByPurl) {
+		log.Fatalf("Unknown match %s, choose name or purl\n", *matchBy)
+	}
+
+	if *format != "text" && *format != "json" {
+		log.Fatalf("Unknown format %s, choose text or json\n", *format)
+	}
+
+	fromFiles := *oldPath != "" && *newPath != ""
+	fromDb := *oldSource != "" && *newSource != ""
+	if fromFiles == fromDb {
+		log.Fatalf("either --old and --new or --oldSource and --newSource are required\n")
+	}
+
+	var old, new *sbom.CyclonedxSbom
+	var err error
+	// only set when reading from the db
+	var database *mongo.Database
+
+	if fromFiles {
+		old, err = sbom.ReadCyclonedx(*oldPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		new, err = sbom.ReadCyclonedx(*newPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		// INPUT VALIDATION
+		uri := os.Getenv("MONGO_URI")
+		usr := os.Getenv("MONGO_USERNAME")
+		pwd := os.Getenv("MONGO_PWD")
+
+		if usr == "" || pwd == "" || uri == "" {
+			log.Fatalf("uri, username or password not found. Make sure MONGO_USERNAME, MONGO_PWD, and MONGO_URI are set\n")
+		}
+
+		// DB CONNECTION
+		client, err := mongo.Connect(options.Client().
+			ApplyURI(uri).
+			SetAuth(options.Credential{
+				Username: usr,
+				Password: pwd,
+			}))
+		if err != nil {
+			panic(err)
+		}
+
+		defer func() {
+			if err := client.Disconnect(context.Background()); err != nil {
+				panic(err)
+			}
+		}()
+
+		database = client.Database(*dbName)
+		coll := &catalogue.Store{Sboms: database.Collection(*collectionName), Components: catalogue.Collection(database, *componentsCollectionName)}
+		if err := coll.Verify(ctx); err != nil {
+			log.Fatalf("Unable to read the SBOMs %s\n", err.Error())
+		}
+
+		old, err = findBySource(ctx, coll, *oldSource)
+		if err != nil {
+			log.Fatalf("old SBOM %s: %s\n", *oldSource, err)
+		}
+		new, err = findBySource(ctx, coll, *newSource)
+		if err != nil {
+			log.Fatalf("new SBOM %s: %s\n", *newSource, err)
+		}
+	}
+
+	diff := sbom.Diff(old, new, sbom.DiffOptions{MatchBy: sbom.MatchBy(*matchBy)})
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		w = f
+		run.Output(*out)
+	}
+
+	if *format == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(diff)
+	} else {
+		_, err = fmt.Fprint(w, diff.Summary())
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var runs *mongo.Collection
+	if database != nil {
+		runs = report.Runs(database, *runsCollectionName)
+	}
+	if err := run.Publish(ctx, *reportPath, runs); err != nil {
+		logger.Error("Unable to publish the run report", "err", err)
+	}
+}
+
+// returns the latest SBOM whose source id, name, or version matches s
+func findBySource(ctx context.Context, coll *catalogue.Store, s string) (*sbom.CyclonedxSbom, error) {
+	filter := bson.D{{Key: "$or", Value: bson.A{
+		bson.D{{Key: "source.id", Value: s}},
+		bson.D{{Key: "source.name", Value: s}},
+		bson.D{{Key: "source.version", Value: s}},
+	}}}
+
+	var latest struct {
+		Id bson.ObjectID `bson:"_id"`
+	}
+	err := coll.Sboms.FindOne(ctx, filter, options.FindOne().
+		SetSort(bson.D{{Key: "_id", Value: -1}}).
+		SetProjection(bson.D{{Key: "_id", Value: 1}})).Decode(&latest)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := coll.Sbom(ctx, latest.Id)
+	if err != nil {
+		return nil, err
+	}
+	return &res.CyclonedxSbom, nil
+}
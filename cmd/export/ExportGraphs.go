@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"sbom-processor/internal/catalogue"
+	"sbom-processor/internal/db"
+	"sbom-processor/internal/graph"
+	"sbom-processor/internal/metrics"
+	"sbom-processor/internal/report"
+	"sbom-processor/internal/sbom"
+
+	"github.com/janniclas/beehive"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type sbomGraph struct {
+	id    string
+	graph *graph.Graph
+}
+
+// writes the dependency graph of every selected SBOM. dot and graphml
+// write one file per SBOM, neo4j writes all SBOMs into one node and
+// one relationship file.
+func exportGraphs(ctx context.Context, sboms *catalogue.Store, run *report.Report, logger *slog.Logger) {
+	filter := bson.D{}
+	if *source != "" {
+		filter = bson.D{{Key: "$or", Value: bson.A{
+			bson.D{{Key: "source.id", Value: *source}},
+			bson.D{{Key: "source.name", Value: *source}},
+		}}}
+	}
+
+	progress := metrics.NewProgress("export_graphs")
+	if total, err := sboms.Sboms.CountDocuments(ctx, filter); err == nil {
+		progress.SetTotal(total)
+	}
+	progress.Start(logger, metrics.LogInterval)
+	run.Track(progress)
+
+	cursor, err := sboms.Find(ctx, filter)
+	if err != nil {
+		panic(err)
+	}
+	defer cursor.Close(context.WithoutCancel(ctx))
+
+	it := db.MongodbIterator[sbom.StoredSbom](ctx, cursor)
+
+	worker := beehive.Worker[sbom.StoredSbom, sbomGraph]{
+		Work: metrics.Track(progress, func(s *sbom.StoredSbom) (*sbomGraph, error) {
+			g := graph.New(&s.CyclonedxSbom)
+			if *packagesOnly {
+				g = g.PackageView()
+			}
+			return &sbomGraph{id: s.Id.Hex(), graph: g}, nil
+		}),
+	}
+
+	var write func(t []*sbomGraph) error
+
+	if *format == "neo4j" {
+		nodes, err := os.Create(filepath.Join(*out, "nodes.csv"))
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer nodes.Close()
+
+		relationships, err := os.Create(filepath.Join(*out, "relationships.csv"))
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer relationships.Close()
+
+		neo4j, err := graph.NewNeo4jWriter(nodes, relationships)
+		if err != nil {
+			log.Fatal(err)
+		}
+		run.Output(nodes.Name(), relationships.Name())
+
+		write = metrics.TrackWrite(progress, func(t []*sbomGraph) error {
+			for _, g := range t {
+				if err := neo4j.Write(g.id, g.graph); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	} else {
+		write = func(t []*sbomGraph) error {
+			// keep writing the other graphs, all failures are returned
+			var errs []error
+			for _, g := range t {
+				outPath := filepath.Join(*out, fmt.Sprintf("%s.%s", g.id, *format))
+				if err := writeGraphFile(outPath, g); err != nil {
+					logger.Error("err during file storage", "file", outPath, "error", err)
+					progress.WriteFailed(1, err)
+					errs = append(errs, err)
+				}
+			}
+			return errors.Join(errs...)
+		}
+		// one file per SBOM
+		run.Output(*out)
+	}
+
+	buffer := 10
+	writer := beehive.NewBufferedCollector(write, beehive.BufferedCollectorConfig{BufferSize: &buffer})
+	dispatcher := beehive.NewDispatcher(worker, it, *writer, beehive.DispatcherConfig{})
+
+	dispatcher.Dispatch()
+	progress.Stop(logger)
+}
+
+func writeGraphFile(p string, g *sbomGraph) error {
+	f, err := os.Create(p)
+	if err != nil {
+		return err
+	}
+
+	var writeGraph func(io.Writer, string, *graph.Graph) error = graph.WriteDot
+	if *format == "graphml" {
+		writeGraph = graph.WriteGraphML
+	}
+
+	return errors.Join(writeGraph(f, g.id, g.graph), f.Close())
+}
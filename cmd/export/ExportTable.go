@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+
+	"sbom-processor/internal/catalogue"
+	"sbom-processor/internal/db"
+	"sbom-processor/internal/json"
+	"sbom-processor/internal/metrics"
+	"sbom-processor/internal/report"
+	"sbom-processor/internal/sbom"
+	"sbom-processor/internal/segment"
+	"sbom-processor/internal/table"
+
+	"github.com/janniclas/beehive"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// returns a writer for the selected format rolling over to a new
+// file after maxFileSize. tabular formats write records as rows of
+// schema, json writes them as array.
+func newOutput[T any](name string, schema table.Schema, row func(T) []any) *segment.Writer[T] {
+	ext := ".json"
+	if *format != "json" {
+		ext = table.Format(*format).Ext()
+	}
+
+	return segment.NewWriter(segment.Config[T]{
+		Dir:      *out,
+		Name:     name,
+		Ext:      ext,
+		MaxBytes: *maxFileSize * 1024 * 1024,
+		New: func(w io.Writer) (segment.RecordWriter[T], error) {
+			if *format == "json" {
+				return json.NewArrayWriter[T](w)
+			}
+			tw, err := table.NewWriter(w, table.Format(*format), schema)
+			if err != nil {
+				return nil, err
+			}
+			return table.NewRecordWriter(tw, row), nil
+		},
+	})
+}
+
+// streams the flattened image × component table of all SBOMs
+func exportTable(ctx context.Context, sboms *catalogue.Store, run *report.Report, logger *slog.Logger) {
+	output := newOutput("image_components", table.ImageComponentSchema, func(r []any) []any { return r })
+
+	progress := metrics.NewProgress("export_table")
+	if total, err := sboms.Sboms.EstimatedDocumentCount(ctx); err == nil {
+		progress.SetTotal(total)
+	}
+	progress.Start(logger, metrics.LogInterval)
+	run.Track(progress)
+
+	cursor, err := sboms.Find(ctx, bson.D{})
+	if err != nil {
+		panic(err)
+	}
+	defer cursor.Close(context.WithoutCancel(ctx))
+
+	it := db.MongodbIterator[sbom.StoredSbom](ctx, cursor)
+
+	worker := beehive.Worker[sbom.StoredSbom, [][]any]{
+		Work: metrics.Track(progress, func(s *sbom.StoredSbom) (*[][]any, error) {
+			rows := table.ImageComponentRows(s)
+			return &rows, nil
+		}),
+	}
+
+	buffer := 100
+	writer := beehive.NewBufferedCollector(
+		metrics.TrackWrite(progress, func(t []*[][]any) error {
+			for _, rows := range t {
+				for _, r := range *rows {
+					if err := output.Write(r); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		}),
+		beehive.BufferedCollectorConfig{BufferSize: &buffer})
+
+	dispatcher := beehive.NewDispatcher(worker, it, *writer, beehive.DispatcherConfig{})
+
+	if errs := dispatcher.Dispatch(); errs != nil && len(*errs) > 0 {
+		logger.Error("Table export failed", "errors", len(*errs), "first", (*errs)[0])
+	}
+	progress.Stop(logger)
+
+	if err := output.Close(); err != nil {
+		panic(err)
+	}
+	run.Output(output.Files()...)
+	logger.Info("Wrote image component table", "files", output.Files())
+}
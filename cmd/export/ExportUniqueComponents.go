@@ -4,17 +4,18 @@ import (
 	"context"
 	"flag"
 	"log"
-	"math"
+	"log/slog"
 	"os"
-	"path/filepath"
+	"sbom-processor/internal/catalogue"
 	"sbom-processor/internal/db"
-	"sbom-processor/internal/json"
 	"sbom-processor/internal/logging"
+	"sbom-processor/internal/metrics"
+	"sbom-processor/internal/report"
+	"sbom-processor/internal/shutdown"
+	"sbom-processor/internal/table"
 	"sbom-processor/internal/validator"
-	"time"
 
 	"github.com/janniclas/beehive"
-	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
@@ -26,18 +27,60 @@ type UniqueNames struct {
 var out = flag.String("out", "", "File to write the SBOM to")
 var componentType = flag.String("componentType", "java-archive", "Component type to filter on")
 var logLevel = flag.Int("logLevel", 0, "Can be 0 for INFO, -4 for DEBUG, 4 for WARN, or 8 for ERROR. Defaults to INFO.")
+var metricsAddr = flag.String("metricsAddr", "", "address to serve Prometheus metrics on, e.g., :9090. metrics aren't served if empty.")
+var reportPath = flag.String("report", "", "file to write the JSON run report to. written to stderr if empty.")
+var runsCollectionName = flag.String("runsCollection", "", "collection name to store the run report in, e.g., runs. the report isn't stored if empty.")
 var dbName = flag.String("db", "sbom_metadata", "database name to connect to")
 var collectionName = flag.String("collection", "sboms", "collection name for SBOMs")
+var componentsCollectionName = flag.String("componentsCollection", "", "collection name of the component catalogue. set it to read SBOMs stored in the normalized layout, they are read as embedded if empty.")
+var mode = flag.String("mode", "unique", "unique, graph, or table. defines whether to export the unique component names, the dependency graphs, or the flattened image × component table.")
+var format = flag.String("format", "", "output format. dot (default), graphml, or neo4j for graphs. json (default for unique), csv, ndjson, or parquet (default for table) otherwise.")
+var source = flag.String("source", "", "id or name of the SBOM source to export the graph for. exports all SBOMs if empty.")
+var maxFileSize = flag.Int64("maxFileSize", 0, "size in MiB after which the output continues in a new file. 0 writes a single file.")
+var packagesOnly = flag.Bool("packagesOnly", false, "collapse syft file nodes in the graph export")
 
 func main() {
 
-	start := time.Now()
 	flag.Parse()
 
+	ctx, done := shutdown.Context()
+	defer done()
+
+	run := report.New("export")
+
 	logger := logging.SetUpLogging(*logLevel)
 
+	if err := metrics.Serve(*metricsAddr); err != nil {
+		log.Fatalf("Unable to serve metrics %s\n", err.Error())
+	}
+
 	validator.ValidateOutPath(out)
 
+	switch *mode {
+	case "graph":
+		if *format == "" {
+			*format = "dot"
+		}
+		if *format != "dot" && *format != "graphml" && *format != "neo4j" {
+			log.Fatalf("Unknown format %s, choose dot, graphml, or neo4j\n", *format)
+		}
+	case "unique", "table":
+		if *format == "" && *mode == "unique" {
+			*format = "json"
+		}
+		if *format == "" {
+			*format = string(table.Parquet)
+		}
+		if *format == "json" && *mode == "table" {
+			log.Fatalf("The table export requires csv, ndjson, or parquet\n")
+		}
+		if _, err := table.ParseFormat(*format); err != nil && *format != "json" {
+			log.Fatalf("Unknown format %s, choose json, csv, ndjson, or parquet\n", *format)
+		}
+	default:
+		log.Fatalf("Unknown mode %s, choose unique, graph, or table\n", *mode)
+	}
+
 	// INPUT VALIDATION
 	uri := os.Getenv("MONGO_URI")
 	usr := os.Getenv("MONGO_USERNAME")
@@ -64,50 +107,72 @@ func main() {
 		}
 	}()
 
-	sboms := client.Database(*dbName).Collection(*collectionName)
-
-	logger.Info("Export unique components called", "db", *dbName, "collection", *collectionName, "componentType", *componentType)
-
-	// prep db query
-	pipeline := mongo.Pipeline{
-		{
-			{Key: "$match", Value: bson.D{{Key: "components.type", Value: *componentType}}},
-		},
-		{
-			{Key: "$unwind", Value: "$components"},
-		},
-		{{Key: "$match", Value: bson.D{{Key: "components.type", Value: *componentType}}}},
-		{
-			{Key: "$group", Value: bson.D{
-				{Key: "_id", Value: "$components.name"},
-			}},
-		},
+	database := client.Database(*dbName)
+	sboms := database.Collection(*collectionName)
+	// all modes read the SBOMs in either layout
+	sbomStore := &catalogue.Store{Sboms: sboms, Components: catalogue.Collection(database, *componentsCollectionName)}
+	if err := sbomStore.Verify(ctx); err != nil {
+		log.Fatalf("Unable to read the SBOMs %s\n", err.Error())
+	}
+
+	switch *mode {
+	case "graph":
+		logger.Info("Export graphs called", "db", *dbName, "collection", *collectionName, "format", *format, "source", *source)
+		exportGraphs(ctx, sbomStore, run, logger)
+	case "table":
+		logger.Info("Export image component table called", "db", *dbName, "collection", *collectionName, "format", *format)
+		exportTable(ctx, sbomStore, run, logger)
+	default:
+		logger.Info("Export unique components called", "db", *dbName, "collection", *collectionName, "componentType", *componentType)
+		exportUnique(ctx, sbomStore, run, logger)
 	}
 
-	cursor, err := sboms.Aggregate(context.TODO(), pipeline, options.Aggregate().SetBatchSize(1000))
+	if err := run.Publish(ctx, *reportPath, report.Runs(database, *runsCollectionName)); err != nil {
+		logger.Error("Unable to publish the run report", "err", err)
+	}
+}
+
+// writes the unique names of all components of componentType
+func exportUnique(ctx context.Context, sboms *catalogue.Store, run *report.Report, logger *slog.Logger) {
+	cursor, err := sboms.Aggregate(ctx, catalogue.UniqueNames(*componentType), options.Aggregate().SetBatchSize(1000))
 	if err != nil {
 		panic(err)
 	}
-	defer cursor.Close(context.TODO())
+	defer cursor.Close(context.WithoutCancel(ctx))
 
-	it := db.MongodbIterator[UniqueNames](cursor)
+	it := db.MongodbIterator[UniqueNames](ctx, cursor)
+
+	progress := metrics.NewProgress("export_unique")
+	progress.Start(logger, metrics.LogInterval)
+	run.Track(progress)
 
 	worker := beehive.Worker[UniqueNames, UniqueNames]{
-		Work: beehive.DoNothing[UniqueNames],
+		Work: metrics.Track(progress, beehive.DoNothing[UniqueNames]),
 	}
 
+	output := newOutput("productNames", table.Schema{{Name: "name", Type: table.String}},
+		func(n *UniqueNames) []any { return []any{n.Name} })
+
+	// results are streamed to the output, only the buffer is kept in memory
 	DoWrite := func(t []*UniqueNames) error {
-		outPath := filepath.Join(*out, "productNames.json")
-		logger.Info("write output called", "out path", outPath)
-		return json.StoreFile(outPath, t)
+		for _, n := range t {
+			if err := output.Write(n); err != nil {
+				return err
+			}
+		}
+		return nil
 	}
-	buffer := math.MaxInt
+	buffer := 1000
 
-	writer := beehive.NewBufferedCollector(DoWrite, beehive.BufferedCollectorConfig{BufferSize: &buffer})
+	writer := beehive.NewBufferedCollector(metrics.TrackWrite(progress, DoWrite), beehive.BufferedCollectorConfig{BufferSize: &buffer})
 	dispatcher := beehive.NewDispatcher(worker, it, *writer, beehive.DispatcherConfig{})
 
 	dispatcher.Dispatch()
+	progress.Stop(logger)
 
-	elapsed := time.Since(start)
-	logger.Info("Finished syft transform", "time elapsed", elapsed)
+	if err := output.Close(); err != nil {
+		panic(err)
+	}
+	run.Output(output.Files()...)
+	logger.Info("write output finished", "files", output.Files())
 }
@@ -2,16 +2,17 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
-	"math"
 	"os"
 	"sbom-processor/internal/deps"
+	"sbom-processor/internal/json"
 	"sbom-processor/internal/logging"
+	"sbom-processor/internal/metrics"
+	"sbom-processor/internal/report"
+	"sbom-processor/internal/shutdown"
 	"sbom-processor/internal/validator"
-	"slices"
 	"strings"
 	"time"
 
@@ -22,6 +23,9 @@ import (
 
 var in = flag.String("in", "", "path to input file containing mvn metadata")
 var logLevel = flag.Int("logLevel", 0, "Can be 0 for INFO, -4 for DEBUG, 4 for WARN, or 8 for ERROR. Defaults to INFO.")
+var metricsAddr = flag.String("metricsAddr", "", "address to serve Prometheus metrics on, e.g., :9090. metrics aren't served if empty.")
+var reportPath = flag.String("report", "", "file to write the JSON run report to. written to stderr if empty.")
+var runsCollectionName = flag.String("runsCollection", "", "collection name to store the run report in, e.g., runs. the report isn't stored if empty.")
 var dbName = flag.String("db", "sbom_metadata", "database name to connect to")
 var collectionName = flag.String("collection", "deps_metadata", "collection name for SBOMs")
 
@@ -32,13 +36,20 @@ type MvnIdentifier struct {
 
 func main() {
 
-	start := time.Now()
-
 	flag.Parse()
 
+	ctx, done := shutdown.Context()
+	defer done()
+
+	run := report.New("import_mvn")
+
 	validator.ValidateInPath(in)
 	logger := logging.SetUpLogging(*logLevel)
 
+	if err := metrics.Serve(*metricsAddr); err != nil {
+		log.Fatalf("Unable to serve metrics %s\n", err.Error())
+	}
+
 	// INPUT VALIDATION
 	uri := os.Getenv("MONGO_URI")
 	usr := os.Getenv("MONGO_USERNAME")
@@ -65,7 +76,8 @@ func main() {
 		}
 	}()
 
-	coll := client.Database(*dbName).Collection(*collectionName)
+	database := client.Database(*dbName)
+	coll := database.Collection(*collectionName)
 
 	logger.Info("Import unique components called", "db", *dbName, "collection", *collectionName)
 
@@ -76,15 +88,33 @@ func main() {
 
 	defer file.Close()
 
-	decoder := json.NewDecoder(file)
-	var mvn []MvnIdentifier
+	// identifiers in flight are queried and stored when ctx is canceled
+	drain := context.WithoutCancel(ctx)
 
-	if err := decoder.Decode(&mvn); err != nil {
-		panic(err)
+	// the identifiers are decoded one by one instead of reading the whole file
+	var decodeErr error
+	identifiers := func(yield func(MvnIdentifier) bool) {
+		for id, err := range json.DecodeArray[MvnIdentifier](file) {
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				decodeErr = err
+				return
+			}
+			if !yield(*id) {
+				return
+			}
+		}
 	}
 
+	progress := metrics.NewProgress("import_mvn")
+	progress.Start(logger, metrics.LogInterval)
+	run.Track(progress)
+	run.Output(*dbName + "." + *collectionName)
+
 	worker := beehive.Worker[MvnIdentifier, deps.Deps]{
-		Work: func(t *MvnIdentifier) (*deps.Deps, error) {
+		Work: metrics.Track(progress, func(t *MvnIdentifier) (*deps.Deps, error) {
 			// first tranform u to CacheRequest
 			// the format is system dependent
 			split := strings.Split(t.Idenfitier, "|")
@@ -100,29 +130,36 @@ func main() {
 
 			// then query api
 			logger.Debug("Querying deps.dev", "name", c.Name, "system", c.System)
-			dep, err := deps.DepsWorkerDo(c)
+			dep, err := deps.DepsWorkerDo(drain, c)
 			if err != nil {
 				logger.Error("Query failed", "name", c.Name, "system", c.System, "err", err)
 			}
 			logger.Debug("Query result", "dep", dep, "err", err)
 
 			return dep, err
-		},
+		}),
 	}
 
 	writer := beehive.BufferedCollector[deps.Deps]{
-		BufferSize: math.MaxInt,
-		Collect: func(t []*deps.Deps) error {
-			_, err := coll.InsertMany(context.TODO(), t)
+		BufferSize: 100,
+		Collect: metrics.TrackWrite(progress, func(t []*deps.Deps) error {
+			_, err := coll.InsertMany(drain, t)
 			return err
-		},
+		}),
 	}
 
 	throttle := time.Second / 10
-	dispatcher := beehive.NewDispatcher(worker, slices.Values(mvn), writer, beehive.DispatcherConfig{RateLimit: &throttle})
+	dispatcher := beehive.NewDispatcher(worker, identifiers, writer, beehive.DispatcherConfig{RateLimit: &throttle})
 
 	dispatcher.Dispatch()
+	progress.Stop(logger)
 
-	elapsed := time.Since(start)
-	logger.Info("Execution finished", "runtime", elapsed)
+	if decodeErr != nil {
+		logger.Error("Reading the input stopped early", "in", *in, "err", decodeErr)
+		run.Fail(decodeErr)
+	}
+
+	if err := run.Publish(ctx, *reportPath, report.Runs(database, *runsCollectionName)); err != nil {
+		logger.Error("Unable to publish the run report", "err", err)
+	}
 }
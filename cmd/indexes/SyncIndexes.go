@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"os"
+
+	"sbom-processor/internal/db"
+	"sbom-processor/internal/logging"
+	"sbom-processor/internal/metrics"
+	"sbom-processor/internal/report"
+	"sbom-processor/internal/shutdown"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var mode = flag.String("mode", "sync", "sync or check. sync creates the missing indexes, check only reports the drift.")
+var drop = flag.Bool("drop", false, "drop unknown indexes and recreate indexes that differ from the specification. only used in sync mode.")
+var logLevel = flag.Int("logLevel", 0, "Can be 0 for INFO, -4 for DEBUG, 4 for WARN, or 8 for ERROR. Defaults to INFO.")
+var metricsAddr = flag.String("metricsAddr", "", "address to serve Prometheus metrics on, e.g., :9090. metrics aren't served if empty.")
+var reportPath = flag.String("report", "", "file to write the JSON run report to. written to stderr if empty.")
+var runsCollectionName = flag.String("runsCollection", "", "collection name to store the run report in, e.g., runs. the report isn't stored if empty.")
+var dbName = flag.String("db", "sbom_metadata", "database name to connect to")
+
+var errDrift = errors.New("indexes differ from the specification")
+
+func main() {
+
+	flag.Parse()
+
+	ctx, done := shutdown.Context()
+	defer done()
+
+	run := report.New("indexes")
+
+	logger := logging.SetUpLogging(*logLevel)
+
+	if err := metrics.Serve(*metricsAddr); err != nil {
+		log.Fatalf("Unable to serve metrics %s\n", err.Error())
+	}
+
+	if *mode != "sync" && *mode != "check" {
+		log.Fatalf("Unknown mode %s, choose sync or check\n", *mode)
+	}
+
+	// INPUT VALIDATION
+	uri := os.Getenv("MONGO_URI")
+	usr := os.Getenv("MONGO_USERNAME")
+	pwd := os.Getenv("MONGO_PWD")
+
+	if usr == "" || pwd == "" || uri == "" {
+		log.Fatalf("uri, username or password not found. Make sure MONGO_USERNAME, MONGO_PWD, and MONGO_URI are set\n")
+	}
+
+	// DB CONNECTION
+	client, err := mongo.Connect(options.Client().
+		ApplyURI(uri).
+		SetAuth(options.Credential{
+			Username: usr,
+			Password: pwd,
+		}))
+	if err != nil {
+		panic(err)
+	}
+
+	defer func() {
+		if err := client.Disconnect(context.Background()); err != nil {
+			panic(err)
+		}
+	}()
+
+	database := client.Database(*dbName)
+
+	logger.Info("Indexes called", "db", *dbName, "mode", *mode, "drop", *drop)
+
+	collections := db.Collections()
+	progress := metrics.NewProgress("indexes")
+	progress.SetTotal(int64(len(collections)))
+	run.Track(progress)
+
+	for _, name := range collections {
+		if ctx.Err() != nil {
+			break
+		}
+
+		coll := database.Collection(name)
+		var drift *db.Drift
+		if *mode == "check" {
+			drift, err = db.Check(ctx, coll, db.Spec(name))
+		} else {
+			// a started sync is finished
+			drift, err = db.Sync(context.WithoutCancel(ctx), coll, db.Spec(name), *drop)
+		}
+		if err != nil {
+			logger.Error("Index sync failed", "collection", name, "err", err)
+			progress.Fail(err)
+			continue
+		}
+
+		if drift.Empty() {
+			logger.Info("Indexes up to date", "collection", name)
+			progress.Processed(1)
+			continue
+		}
+
+		names := func(indexes []db.Index) []string {
+			res := make([]string, len(indexes))
+			for i, idx := range indexes {
+				res[i] = idx.IndexName()
+			}
+			return res
+		}
+		logger.Warn("Index drift", "collection", name, "missing", names(drift.Missing),
+			"changed", names(drift.Changed), "unknown", drift.Unknown)
+
+		if *mode == "check" {
+			progress.Fail(errDrift)
+			continue
+		}
+		progress.Processed(1)
+		run.Output(*dbName + "." + name)
+	}
+
+	if err := run.Publish(ctx, *reportPath, report.Runs(database, *runsCollectionName)); err != nil {
+		logger.Error("Unable to publish the run report", "err", err)
+	}
+}
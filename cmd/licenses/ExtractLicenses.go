@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+
+	"sbom-processor/internal/catalogue"
+	"sbom-processor/internal/db"
+	"sbom-processor/internal/license"
+	"sbom-processor/internal/logging"
+	"sbom-processor/internal/metrics"
+	"sbom-processor/internal/report"
+	"sbom-processor/internal/sbom"
+	"sbom-processor/internal/shutdown"
+
+	"github.com/janniclas/beehive"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var depsFallback = flag.Bool("depsFallback", true, "query deps.dev for components that don't declare a license")
+var logLevel = flag.Int("logLevel", 0, "Can be 0 for INFO, -4 for DEBUG, 4 for WARN, or 8 for ERROR. Defaults to INFO.")
+var metricsAddr = flag.String("metricsAddr", "", "address to serve Prometheus metrics on, e.g., :9090. metrics aren't served if empty.")
+var reportPath = flag.String("report", "", "file to write the JSON run report to. written to stderr if empty.")
+var runsCollectionName = flag.String("runsCollection", "", "collection name to store the run report in, e.g., runs. the report isn't stored if empty.")
+var dbName = flag.String("db", "sbom_metadata", "database name to connect to")
+var collectionName = flag.String("collection", "sboms", "collection name for SBOMs")
+var componentsCollectionName = flag.String("componentsCollection", "", "collection name of the component catalogue. set it to read SBOMs stored in the normalized layout, they are read as embedded if empty.")
+var licenseCollectionName = flag.String("licenseCollection", "licenses", "collection name to store the licenses in")
+
+func main() {
+
+	flag.Parse()
+
+	ctx, done := shutdown.Context()
+	defer done()
+
+	run := report.New("licenses")
+
+	logger := logging.SetUpLogging(*logLevel)
+
+	if err := metrics.Serve(*metricsAddr); err != nil {
+		log.Fatalf("Unable to serve metrics %s\n", err.Error())
+	}
+
+	// INPUT VALIDATION
+	uri := os.Getenv("MONGO_URI")
+	usr := os.Getenv("MONGO_USERNAME")
+	pwd := os.Getenv("MONGO_PWD")
+
+	if usr == "" || pwd == "" || uri == "" {
+		log.Fatalf("uri, username or password not found. Make sure MONGO_USERNAME, MONGO_PWD, and MONGO_URI are set\n")
+	}
+
+	// DB CONNECTION
+	client, err := mongo.Connect(options.Client().
+		ApplyURI(uri).
+		SetAuth(options.Credential{
+			Username: usr,
+			Password: pwd,
+		}))
+	if err != nil {
+		panic(err)
+	}
+
+	defer func() {
+		if err := client.Disconnect(context.Background()); err != nil {
+			panic(err)
+		}
+	}()
+
+	database := client.Database(*dbName)
+	sbomsColl := database.Collection(*collectionName)
+	licenseColl := database.Collection(*licenseCollectionName)
+
+	// SBOMs in flight are resolved and stored when ctx is canceled
+	drain := context.WithoutCancel(ctx)
+
+	if err := db.Verify(ctx, licenseColl, db.Spec("licenses")); err != nil {
+		logger.Warn("Index verification failed", "collection", licenseColl.Name(), "err", err)
+	}
+
+	var fallback license.Lookup
+	if *depsFallback {
+		fallback = license.NewDepsLookup(drain)
+	}
+
+	logger.Info("Extract licenses called", "db", *dbName, "collection", *collectionName, "licenseCollection", *licenseCollectionName, "depsFallback", *depsFallback)
+
+	progress := metrics.NewProgress("licenses")
+	if total, err := sbomsColl.EstimatedDocumentCount(ctx); err == nil {
+		progress.SetTotal(total)
+	}
+	progress.Start(logger, metrics.LogInterval)
+	run.Track(progress)
+	run.Output(*dbName + "." + *licenseCollectionName)
+
+	sbomStore := catalogue.Store{Sboms: sbomsColl, Components: catalogue.Collection(database, *componentsCollectionName)}
+	if err := sbomStore.Verify(ctx); err != nil {
+		log.Fatalf("Unable to read the SBOMs %s\n", err.Error())
+	}
+	cursor, err := sbomStore.Find(ctx, bson.D{})
+	if err != nil {
+		panic(err)
+	}
+	defer cursor.Close(drain)
+
+	it := db.MongodbIterator[sbom.StoredSbom](ctx, cursor)
+
+	worker := beehive.Worker[sbom.StoredSbom, license.SbomLicenses]{
+		Work: metrics.Track(progress, func(s *sbom.StoredSbom) (*license.SbomLicenses, error) {
+			l := license.Compute(s, fallback)
+			logger.Debug("Extracted licenses", "source", s.Source.Name, "aggregate", l.Aggregate)
+			return l, nil
+		}),
+	}
+
+	buffer := 100
+	writer := beehive.NewBufferedCollector(
+		metrics.TrackWrite(progress, func(l []*license.SbomLicenses) error {
+			return license.Store(drain, licenseColl, l)
+		}),
+		beehive.BufferedCollectorConfig{BufferSize: &buffer})
+
+	dispatcher := beehive.NewDispatcher(worker, it, *writer, beehive.DispatcherConfig{})
+
+	dispatcher.Dispatch()
+	progress.Stop(logger)
+
+	if err := run.Publish(ctx, *reportPath, report.Runs(database, *runsCollectionName)); err != nil {
+		logger.Error("Unable to publish the run report", "err", err)
+	}
+}
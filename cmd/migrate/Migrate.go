@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"log/slog"
+	"os"
+
+	"sbom-processor/internal/logging"
+	"sbom-processor/internal/metrics"
+	"sbom-processor/internal/migrate"
+	"sbom-processor/internal/report"
+	"sbom-processor/internal/shutdown"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var mode = flag.String("mode", "status", "status, up, or down. lists the migrations, applies the pending ones, or reverts the applied ones.")
+var target = flag.Int("target", migrate.Latest, "version to migrate to. -1 migrates up to the latest version or reverts the last applied migration.")
+var dryRun = flag.Bool("dryRun", false, "count the documents each migration would change without writing")
+var logLevel = flag.Int("logLevel", 0, "Can be 0 for INFO, -4 for DEBUG, 4 for WARN, or 8 for ERROR. Defaults to INFO.")
+var metricsAddr = flag.String("metricsAddr", "", "address to serve Prometheus metrics on, e.g., :9090. metrics aren't served if empty.")
+var reportPath = flag.String("report", "", "file to write the JSON run report to. written to stderr if empty.")
+var runsCollectionName = flag.String("runsCollection", "", "collection name to store the run report in, e.g., runs. the report isn't stored if empty.")
+var dbName = flag.String("db", "sbom_metadata", "database name to connect to")
+var historyCollectionName = flag.String("historyCollection", "schema_migrations", "collection name to record the applied migrations in")
+
+func main() {
+
+	flag.Parse()
+
+	ctx, done := shutdown.Context()
+	defer done()
+
+	run := report.New("migrate")
+
+	logger := logging.SetUpLogging(*logLevel)
+
+	if err := metrics.Serve(*metricsAddr); err != nil {
+		log.Fatalf("Unable to serve metrics %s\n", err.Error())
+	}
+
+	if *mode != "status" && *mode != string(migrate.Up) && *mode != string(migrate.Down) {
+		log.Fatalf("Unknown mode %s, choose status, up, or down\n", *mode)
+	}
+
+	// INPUT VALIDATION
+	uri := os.Getenv("MONGO_URI")
+	usr := os.Getenv("MONGO_USERNAME")
+	pwd := os.Getenv("MONGO_PWD")
+
+	if usr == "" || pwd == "" || uri == "" {
+		log.Fatalf("uri, username or password not found. Make sure MONGO_USERNAME, MONGO_PWD, and MONGO_URI are set\n")
+	}
+
+	// DB CONNECTION
+	client, err := mongo.Connect(options.Client().
+		ApplyURI(uri).
+		SetAuth(options.Credential{
+			Username: usr,
+			Password: pwd,
+		}))
+	if err != nil {
+		panic(err)
+	}
+
+	defer func() {
+		if err := client.Disconnect(context.Background()); err != nil {
+			panic(err)
+		}
+	}()
+
+	database := client.Database(*dbName)
+	runner := migrate.Runner{
+		Database:   database,
+		Migrations: migrate.Migrations(),
+		History:    database.Collection(*historyCollectionName),
+	}
+
+	if *mode == "status" {
+		status(ctx, &runner, run, logger)
+	} else {
+		logger.Info("Migrate called", "db", *dbName, "mode", *mode, "target", *target, "dryRun", *dryRun)
+
+		progress := metrics.NewProgress("migrate")
+		run.Track(progress)
+
+		err := runner.Run(ctx, migrate.Direction(*mode), *target, *dryRun, func(r migrate.Result) {
+			progress.Processed(1)
+			if r.DryRun {
+				logger.Info("Dry run", "version", r.Version, "collection", r.Collection, "direction", r.Direction,
+					"description", r.Description, "affected documents", r.Matched)
+				return
+			}
+			logger.Info("Migrated", "version", r.Version, "collection", r.Collection, "direction", r.Direction,
+				"description", r.Description, "matched", r.Matched, "modified", r.Modified)
+			run.Output(*dbName + "." + r.Collection)
+		})
+		if err != nil && !shutdown.Interrupted(ctx) {
+			logger.Error("Migration failed", "err", err)
+			progress.Fail(err)
+			run.Fail(err)
+		}
+	}
+
+	if err := run.Publish(ctx, *reportPath, report.Runs(database, *runsCollectionName)); err != nil {
+		logger.Error("Unable to publish the run report", "err", err)
+	}
+}
+
+// logs every migration and whether it's applied
+func status(ctx context.Context, runner *migrate.Runner, run *report.Report, logger *slog.Logger) {
+	applied, err := runner.Applied(ctx)
+	if err != nil {
+		logger.Error("Reading the migration history failed", "err", err)
+		run.Fail(err)
+		return
+	}
+
+	for _, m := range runner.Migrations {
+		logger.Info("Migration", "version", m.Version, "collection", m.Collection,
+			"description", m.Description, "applied", applied[m.Version])
+	}
+}
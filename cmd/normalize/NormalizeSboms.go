@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+
+	"sbom-processor/internal/catalogue"
+	"sbom-processor/internal/db"
+	"sbom-processor/internal/logging"
+	"sbom-processor/internal/metrics"
+	"sbom-processor/internal/report"
+	"sbom-processor/internal/sbom"
+	"sbom-processor/internal/shutdown"
+
+	"github.com/janniclas/beehive"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var logLevel = flag.Int("logLevel", 0, "Can be 0 for INFO, -4 for DEBUG, 4 for WARN, or 8 for ERROR. Defaults to INFO.")
+var metricsAddr = flag.String("metricsAddr", "", "address to serve Prometheus metrics on, e.g., :9090. metrics aren't served if empty.")
+var reportPath = flag.String("report", "", "file to write the JSON run report to. written to stderr if empty.")
+var runsCollectionName = flag.String("runsCollection", "", "collection name to store the run report in, e.g., runs. the report isn't stored if empty.")
+var dbName = flag.String("db", "sbom_metadata", "database name to connect to")
+var collectionName = flag.String("collection", "sboms", "collection name for SBOMs")
+var componentsCollectionName = flag.String("componentsCollection", "components", "collection name of the component catalogue")
+
+func main() {
+
+	flag.Parse()
+
+	ctx, done := shutdown.Context()
+	defer done()
+
+	run := report.New("normalize")
+
+	logger := logging.SetUpLogging(*logLevel)
+
+	if err := metrics.Serve(*metricsAddr); err != nil {
+		log.Fatalf("Unable to serve metrics %s\n", err.Error())
+	}
+
+	if *componentsCollectionName == "" {
+		log.Fatalf("componentsCollection must not be empty\n")
+	}
+
+	// INPUT VALIDATION
+	uri := os.Getenv("MONGO_URI")
+	usr := os.Getenv("MONGO_USERNAME")
+	pwd := os.Getenv("MONGO_PWD")
+
+	if usr == "" || pwd == "" || uri == "" {
+		log.Fatalf("uri, username or password not found. Make sure MONGO_USERNAME, MONGO_PWD, and MONGO_URI are set\n")
+	}
+
+	// DB CONNECTION
+	client, err := mongo.Connect(options.Client().
+		ApplyURI(uri).
+		SetAuth(options.Credential{
+			Username: usr,
+			Password: pwd,
+		}))
+	if err != nil {
+		panic(err)
+	}
+
+	defer func() {
+		if err := client.Disconnect(context.Background()); err != nil {
+			panic(err)
+		}
+	}()
+
+	database := client.Database(*dbName)
+	store := catalogue.Store{
+		Sboms:      database.Collection(*collectionName),
+		Components: database.Collection(*componentsCollectionName),
+	}
+
+	// SBOMs in flight are converted when ctx is canceled
+	drain := context.WithoutCancel(ctx)
+
+	if err := db.Verify(ctx, store.Sboms, db.Spec("sboms")); err != nil {
+		logger.Warn("Index verification failed", "collection", store.Sboms.Name(), "err", err)
+	}
+
+	logger.Info("Normalize SBOMs called", "db", *dbName, "collection", *collectionName, "componentsCollection", *componentsCollectionName)
+
+	// only SBOMs in the embedded layout, so an interrupted
+	// migration continues where it stopped
+	filter := bson.D{
+		{Key: "refs", Value: bson.D{{Key: "$exists", Value: false}}},
+		{Key: "components", Value: bson.D{{Key: "$exists", Value: true}}},
+	}
+
+	progress := metrics.NewProgress("normalize")
+	if total, err := store.Sboms.CountDocuments(ctx, filter); err == nil {
+		progress.SetTotal(total)
+	}
+	progress.Start(logger, metrics.LogInterval)
+	run.Track(progress)
+	run.Output(*dbName+"."+*collectionName, *dbName+"."+*componentsCollectionName)
+
+	cursor, err := store.Sboms.Find(ctx, filter)
+	if err != nil {
+		panic(err)
+	}
+	defer cursor.Close(drain)
+
+	it := db.MongodbIterator[sbom.StoredSbom](ctx, cursor)
+
+	worker := beehive.Worker[sbom.StoredSbom, catalogue.Normalized]{
+		Work: metrics.Track(progress, func(s *sbom.StoredSbom) (*catalogue.Normalized, error) {
+			n := catalogue.Normalize(&s.CyclonedxSbom)
+			n.Sbom.Id = s.Id
+			logger.Debug("Normalized SBOM", "source", s.Source.Name, "components", len(s.Components), "entries", len(n.Entries))
+			return n, nil
+		}),
+	}
+
+	buffer := 100
+	writer := beehive.NewBufferedCollector(
+		metrics.TrackWrite(progress, func(n []*catalogue.Normalized) error {
+			return store.Replace(drain, n)
+		}),
+		beehive.BufferedCollectorConfig{BufferSize: &buffer})
+
+	dispatcher := beehive.NewDispatcher(worker, it, *writer, beehive.DispatcherConfig{})
+
+	dispatcher.Dispatch()
+	progress.Stop(logger)
+
+	if err := run.Publish(ctx, *reportPath, report.Runs(database, *runsCollectionName)); err != nil {
+		logger.Error("Unable to publish the run report", "err", err)
+	}
+}
@@ -3,15 +3,22 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
+	"io"
 	"log"
-	"math"
 	"os"
-	"path/filepath"
+	"sbom-processor/internal/catalogue"
 	"sbom-processor/internal/db"
 	"sbom-processor/internal/json"
 	"sbom-processor/internal/logging"
+	"sbom-processor/internal/metrics"
+	"sbom-processor/internal/query"
+	"sbom-processor/internal/report"
+	"sbom-processor/internal/segment"
+	"sbom-processor/internal/shutdown"
+	"sbom-processor/internal/table"
 	"sbom-processor/internal/validator"
-	"time"
+	"strings"
 
 	"github.com/janniclas/beehive"
 
@@ -20,24 +27,107 @@ import (
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
 
-type UniqueNames struct {
-	Name  string `bson:"_id" json:"_id"`
-	Count int    `bson:"count" json:"count"`
+// repeatable k=v flag
+type params map[string]string
+
+func (p params) String() string {
+	var res []string
+	for k, v := range p {
+		res = append(res, k+"="+v)
+	}
+	return strings.Join(res, ",")
+}
+
+func (p params) Set(s string) error {
+	k, v, found := strings.Cut(s, "=")
+	if !found || k == "" {
+		return fmt.Errorf("param must be given as key=value, got %s", s)
+	}
+	p[k] = v
+	return nil
 }
 
-var out = flag.String("out", "", "File to write the SBOM to")
+var queryParams = params{}
+
+var queryName = flag.String("query", "product-names", "name of the query from the catalogue, use --list to show all queries")
+var pipelineFile = flag.String("pipeline", "", "JSON file with a user defined aggregation pipeline, replaces --query")
+var list = flag.Bool("list", false, "list the queries of the catalogue and their params")
+var out = flag.String("out", "", "directory to write the query result to, the file is named after the query")
+var maxFileSize = flag.Int64("maxFileSize", 0, "size in MiB after which the output continues in a new file. 0 writes a single file.")
+var format = flag.String("format", "json", "json, csv, ndjson, or parquet. tabular formats use the columns declared by the query or the fields of the first result.")
 var logLevel = flag.Int("logLevel", 0, "Can be 0 for INFO, -4 for DEBUG, 4 for WARN, or 8 for ERROR. Defaults to INFO.")
+var metricsAddr = flag.String("metricsAddr", "", "address to serve Prometheus metrics on, e.g., :9090. metrics aren't served if empty.")
+var reportPath = flag.String("report", "", "file to write the JSON run report to. written to stderr if empty.")
+var runsCollectionName = flag.String("runsCollection", "", "collection name to store the run report in, e.g., runs. the report isn't stored if empty.")
 var dbName = flag.String("db", "sbom_metadata", "database name to connect to")
 var collectionName = flag.String("collection", "sboms", "collection name for SBOMs")
+var componentsCollectionName = flag.String("componentsCollection", "", "collection name of the component catalogue. set it to read SBOMs stored in the normalized layout, they are read as embedded if empty.")
+
+func init() {
+	flag.Var(queryParams, "param", "query param as key=value, can be repeated")
+}
+
+func printCatalogue() {
+	queries, err := query.Catalogue()
+	if err != nil {
+		log.Fatalf("Unable to read the query catalogue %s\n", err.Error())
+	}
+
+	for _, d := range queries {
+		fmt.Printf("%s\n  %s\n", d.Name, d.Description)
+		for _, p := range d.Params {
+			def := "required"
+			if p.Default != nil {
+				def = fmt.Sprintf("default %q", *p.Default)
+			}
+			fmt.Printf("  --param %s=<%s> %s (%s)\n", p.Name, p.Name, p.Description, def)
+		}
+	}
+}
 
 func main() {
 
-	start := time.Now()
 	flag.Parse()
 
+	ctx, done := shutdown.Context()
+	defer done()
+
+	run := report.New("query")
+
 	logger := logging.SetUpLogging(*logLevel)
 
-	validator.ValidateOutPath(out)
+	if err := metrics.Serve(*metricsAddr); err != nil {
+		log.Fatalf("Unable to serve metrics %s\n", err.Error())
+	}
+
+	if *list {
+		printCatalogue()
+		return
+	}
+
+	var definition *query.Definition
+	var err error
+	if *pipelineFile != "" {
+		definition, err = query.Load(*pipelineFile)
+	} else {
+		definition, err = query.Lookup(*queryName)
+	}
+	if err != nil {
+		log.Fatalf("%s\n", err.Error())
+	}
+
+	pipeline, err := definition.Render(queryParams)
+	if err != nil {
+		log.Fatalf("%s\n", err.Error())
+	}
+
+	if _, err := table.ParseFormat(*format); err != nil && *format != "json" {
+		log.Fatalf("Unknown format %s, choose json, csv, ndjson, or parquet\n", *format)
+	}
+
+	if err := validator.ValidateOutPath(out); err != nil {
+		log.Fatalf("Invalid out path %s\n", err.Error())
+	}
 
 	// INPUT VALIDATION
 	uri := os.Getenv("MONGO_URI")
@@ -65,51 +155,96 @@ func main() {
 		}
 	}()
 
-	sboms := client.Database(*dbName).Collection(*collectionName)
-
-	logger.Info("DB Query", "db", *dbName, "collection", *collectionName)
-
-	// prep db query
-	pipeline := mongo.Pipeline{
-		{{Key: "$project", Value: bson.D{
-			{Key: "nameWithoutPostfix", Value: bson.D{
-				{Key: "$arrayElemAt", Value: bson.A{
-					bson.D{{Key: "$split", Value: bson.A{"$source.name", ":"}}},
-					0,
-				}},
-			}},
-		}}},
-		{{Key: "$group", Value: bson.D{
-			{Key: "_id", Value: "$nameWithoutPostfix"},
-			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
-			// Add other aggregations if needed
-		}}},
+	collection := *collectionName
+	if definition.Collection != "" {
+		collection = definition.Collection
+	}
+	database := client.Database(*dbName)
+	// queries on the SBOMs read the components of both layouts
+	coll := &catalogue.Store{Sboms: database.Collection(collection)}
+	if definition.Collection == "" {
+		coll.Components = catalogue.Collection(database, *componentsCollectionName)
+		if err := coll.Verify(ctx); err != nil {
+			log.Fatalf("Unable to read the SBOMs %s\n", err.Error())
+		}
 	}
 
-	cursor, err := sboms.Aggregate(context.TODO(), pipeline, options.Aggregate().SetBatchSize(1000))
+	logger.Info("DB Query", "db", *dbName, "collection", collection, "query", definition.Name, "params", queryParams.String())
+
+	cursor, err := coll.Aggregate(ctx, pipeline, options.Aggregate().SetBatchSize(1000))
 	if err != nil {
 		panic(err)
 	}
-	defer cursor.Close(context.TODO())
+	defer cursor.Close(context.WithoutCancel(ctx))
+
+	it := db.MongodbIterator[bson.D](ctx, cursor)
 
-	it := db.MongodbIterator[UniqueNames](cursor)
+	progress := metrics.NewProgress("query")
+	progress.Start(logger, metrics.LogInterval)
+	run.Track(progress)
 
-	worker := beehive.Worker[UniqueNames, UniqueNames]{
-		Work: beehive.DoNothing[UniqueNames],
+	worker := beehive.Worker[bson.D, bson.D]{
+		Work: metrics.Track(progress, beehive.DoNothing[bson.D]),
 	}
-	DoWrite := func(t []*UniqueNames) error {
-		outPath := filepath.Join(*out, "productNames.json")
-		logger.Info("write output called", "out path", outPath)
-		return json.StoreFile(outPath, t)
+	// columns declared by the query or inferred from the first result
+	schema := definition.Columns
+	output := newOutput(definition.Name, &schema)
+
+	// results are streamed to the output, only the buffer is kept in memory
+	DoWrite := func(t []*bson.D) error {
+		for _, r := range t {
+			if len(schema) == 0 {
+				schema = table.InferSchema(*r)
+			}
+			if err := output.Write(r); err != nil {
+				return err
+			}
+		}
+		return nil
 	}
 
-	buffer := math.MaxInt
+	buffer := 1000
 
-	writer := beehive.NewBufferedCollector(DoWrite, beehive.BufferedCollectorConfig{BufferSize: &buffer})
+	writer := beehive.NewBufferedCollector(metrics.TrackWrite(progress, DoWrite), beehive.BufferedCollectorConfig{BufferSize: &buffer})
 	dispatcher := beehive.NewDispatcher(worker, it, *writer, beehive.DispatcherConfig{})
 
 	dispatcher.Dispatch()
+	progress.Stop(logger)
+
+	if err := output.Close(); err != nil {
+		panic(err)
+	}
+	run.Output(output.Files()...)
+	logger.Info("write output finished", "files", output.Files())
+
+	if err := run.Publish(ctx, *reportPath, report.Runs(database, *runsCollectionName)); err != nil {
+		logger.Error("Unable to publish the run report", "err", err)
+	}
+}
+
+// returns a writer for the selected format rolling over to a new
+// file after maxFileSize. the schema is read when the first file
+// is created.
+func newOutput(name string, schema *table.Schema) *segment.Writer[*bson.D] {
+	ext := ".json"
+	if *format != "json" {
+		ext = table.Format(*format).Ext()
+	}
 
-	elapsed := time.Since(start)
-	logger.Info("Finished syft transform", "time elapsed", elapsed)
+	return segment.NewWriter(segment.Config[*bson.D]{
+		Dir:      *out,
+		Name:     name,
+		Ext:      ext,
+		MaxBytes: *maxFileSize * 1024 * 1024,
+		New: func(w io.Writer) (segment.RecordWriter[*bson.D], error) {
+			if *format == "json" {
+				return json.NewArrayWriter[*bson.D](w)
+			}
+			tw, err := table.NewWriter(w, table.Format(*format), *schema)
+			if err != nil {
+				return nil, err
+			}
+			return table.NewRecordWriter(tw, func(d *bson.D) []any { return table.Row(*schema, *d) }), nil
+		},
+	})
 }
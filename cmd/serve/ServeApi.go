@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"sbom-processor/internal/api"
+	"sbom-processor/internal/catalogue"
+	"sbom-processor/internal/logging"
+	"sbom-processor/internal/metrics"
+	"sbom-processor/internal/report"
+	"sbom-processor/internal/shutdown"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var addr = flag.String("addr", ":8080", "address to listen on")
+var logLevel = flag.Int("logLevel", 0, "Can be 0 for INFO, -4 for DEBUG, 4 for WARN, or 8 for ERROR. Defaults to INFO.")
+var metricsAddr = flag.String("metricsAddr", "", "address to serve Prometheus metrics on, e.g., :9090. metrics aren't served if empty.")
+var reportPath = flag.String("report", "", "file to write the JSON run report to. written to stderr if empty.")
+var runsCollectionName = flag.String("runsCollection", "", "collection name to store the run report in, e.g., runs. the report isn't stored if empty.")
+var dbName = flag.String("db", "sbom_metadata", "database name to connect to")
+var collectionName = flag.String("collection", "sboms", "collection name for SBOMs")
+var componentsCollectionName = flag.String("componentsCollection", "", "collection name of the component catalogue. set it to read SBOMs stored in the normalized layout, they are read as embedded if empty.")
+var lagCollectionName = flag.String("lagCollection", "lag", "collection name of the technical lag")
+var versionsCollectionName = flag.String("versionsCollection", "versions", "collection name of the component versions")
+
+func main() {
+
+	flag.Parse()
+
+	ctx, done := shutdown.Context()
+	defer done()
+
+	run := report.New("serve")
+
+	logger := logging.SetUpLogging(*logLevel)
+
+	if err := metrics.Serve(*metricsAddr); err != nil {
+		log.Fatalf("Unable to serve metrics %s\n", err.Error())
+	}
+
+	// INPUT VALIDATION
+	uri := os.Getenv("MONGO_URI")
+	usr := os.Getenv("MONGO_USERNAME")
+	pwd := os.Getenv("MONGO_PWD")
+
+	if usr == "" || pwd == "" || uri == "" {
+		log.Fatalf("uri, username or password not found. Make sure MONGO_USERNAME, MONGO_PWD, and MONGO_URI are set\n")
+	}
+
+	// DB CONNECTION
+	client, err := mongo.Connect(options.Client().
+		ApplyURI(uri).
+		SetAuth(options.Credential{
+			Username: usr,
+			Password: pwd,
+		}))
+	if err != nil {
+		panic(err)
+	}
+
+	defer func() {
+		if err := client.Disconnect(context.Background()); err != nil {
+			panic(err)
+		}
+	}()
+
+	database := client.Database(*dbName)
+	store := api.MongoStore{
+		SbomStore:         &catalogue.Store{Sboms: database.Collection(*collectionName), Components: catalogue.Collection(database, *componentsCollectionName)},
+		LagCollection:     database.Collection(*lagCollectionName),
+		VersionCollection: database.Collection(*versionsCollectionName),
+	}
+	if err := store.SbomStore.Verify(ctx); err != nil {
+		log.Fatalf("Unable to read the SBOMs %s\n", err.Error())
+	}
+
+	server := http.Server{
+		Addr:              *addr,
+		Handler:           api.NewHandler(&store, logger),
+		ReadHeaderTimeout: 10 * time.Second,
+		// aggregations over all SBOMs can take a while
+		WriteTimeout: 5 * time.Minute,
+	}
+
+	// stop accepting connections once ctx is canceled and
+	// wait for the requests in flight
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Minute)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			logger.Error("Shutdown failed", "err", err)
+		}
+	}()
+
+	logger.Info("Serve API called", "db", *dbName, "collection", *collectionName, "addr", *addr)
+
+	if err := server.ListenAndServe(); errors.Is(err, http.ErrServerClosed) {
+		<-stopped
+	} else if err != nil {
+		logger.Error("Server stopped", "err", err)
+		run.Fail(err)
+	}
+
+	if err := run.Publish(ctx, *reportPath, report.Runs(database, *runsCollectionName)); err != nil {
+		logger.Error("Unable to publish the run report", "err", err)
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"log"
+	"os"
+
+	"sbom-processor/internal/catalogue"
+	"sbom-processor/internal/db"
+	"sbom-processor/internal/logging"
+	"sbom-processor/internal/metrics"
+	"sbom-processor/internal/report"
+	"sbom-processor/internal/sbom"
+	"sbom-processor/internal/shutdown"
+	"sbom-processor/internal/stats"
+
+	"github.com/janniclas/beehive"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var out = flag.String("out", "", "File to write the JSON statistics to. Defaults to stdout.")
+var logLevel = flag.Int("logLevel", 0, "Can be 0 for INFO, -4 for DEBUG, 4 for WARN, or 8 for ERROR. Defaults to INFO.")
+var metricsAddr = flag.String("metricsAddr", "", "address to serve Prometheus metrics on, e.g., :9090. metrics aren't served if empty.")
+var reportPath = flag.String("report", "", "file to write the JSON run report to. written to stderr if empty.")
+var runsCollectionName = flag.String("runsCollection", "", "collection name to store the run report in, e.g., runs. the report isn't stored if empty.")
+var dbName = flag.String("db", "sbom_metadata", "database name to connect to")
+var collectionName = flag.String("collection", "sboms", "collection name for SBOMs")
+var componentsCollectionName = flag.String("componentsCollection", "", "collection name of the component catalogue. set it to read SBOMs stored in the normalized layout, they are read as embedded if empty.")
+var statsCollectionName = flag.String("statsCollection", "", "collection name to store the statistics of each SBOM in, e.g., sbom_stats. they aren't stored if empty.")
+
+// statistics as written to the output
+type result struct {
+	Corpus *stats.Corpus      `json:"corpus"`
+	Sboms  []*stats.SbomStats `json:"sboms"`
+}
+
+func main() {
+
+	flag.Parse()
+
+	ctx, done := shutdown.Context()
+	defer done()
+
+	run := report.New("stats")
+
+	logger := logging.SetUpLogging(*logLevel)
+
+	if err := metrics.Serve(*metricsAddr); err != nil {
+		log.Fatalf("Unable to serve metrics %s\n", err.Error())
+	}
+
+	// INPUT VALIDATION
+	uri := os.Getenv("MONGO_URI")
+	usr := os.Getenv("MONGO_USERNAME")
+	pwd := os.Getenv("MONGO_PWD")
+
+	if usr == "" || pwd == "" || uri == "" {
+		log.Fatalf("uri, username or password not found. Make sure MONGO_USERNAME, MONGO_PWD, and MONGO_URI are set\n")
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		w = f
+		run.Output(*out)
+	}
+
+	// DB CONNECTION
+	client, err := mongo.Connect(options.Client().
+		ApplyURI(uri).
+		SetAuth(options.Credential{
+			Username: usr,
+			Password: pwd,
+		}))
+	if err != nil {
+		panic(err)
+	}
+
+	defer func() {
+		if err := client.Disconnect(context.Background()); err != nil {
+			panic(err)
+		}
+	}()
+
+	database := client.Database(*dbName)
+	sbomsColl := database.Collection(*collectionName)
+
+	// SBOMs in flight are computed and stored when ctx is canceled
+	drain := context.WithoutCancel(ctx)
+
+	var statsColl *mongo.Collection
+	if *statsCollectionName != "" {
+		statsColl = database.Collection(*statsCollectionName)
+		if err := db.Verify(ctx, statsColl, db.Spec("sbom_stats")); err != nil {
+			logger.Warn("Index verification failed", "collection", statsColl.Name(), "err", err)
+		}
+		run.Output(*dbName + "." + *statsCollectionName)
+	}
+
+	logger.Info("Stats called", "db", *dbName, "collection", *collectionName, "statsCollection", *statsCollectionName)
+
+	progress := metrics.NewProgress("stats")
+	if total, err := sbomsColl.EstimatedDocumentCount(ctx); err == nil {
+		progress.SetTotal(total)
+	}
+	progress.Start(logger, metrics.LogInterval)
+	run.Track(progress)
+
+	sbomStore := catalogue.Store{Sboms: sbomsColl, Components: catalogue.Collection(database, *componentsCollectionName)}
+	if err := sbomStore.Verify(ctx); err != nil {
+		log.Fatalf("Unable to read the SBOMs %s\n", err.Error())
+	}
+	cursor, err := sbomStore.Find(ctx, bson.D{})
+	if err != nil {
+		panic(err)
+	}
+	defer cursor.Close(drain)
+
+	it := db.MongodbIterator[sbom.StoredSbom](ctx, cursor)
+
+	worker := beehive.Worker[sbom.StoredSbom, stats.SbomStats]{
+		Work: metrics.Track(progress, func(s *sbom.StoredSbom) (*stats.SbomStats, error) {
+			st := stats.Compute(s)
+			logger.Debug("Computed statistics", "source", s.Source.Name, "components", st.Components, "maxDepth", st.MaxDepth)
+			return st, nil
+		}),
+	}
+
+	// the collector runs in a single goroutine
+	aggregator := stats.NewAggregator()
+	res := result{Sboms: []*stats.SbomStats{}}
+
+	buffer := 100
+	writer := beehive.NewBufferedCollector(
+		metrics.TrackWrite(progress, func(s []*stats.SbomStats) error {
+			for _, st := range s {
+				aggregator.Add(st)
+			}
+			res.Sboms = append(res.Sboms, s...)
+			if statsColl == nil {
+				return nil
+			}
+			return stats.Store(drain, statsColl, s)
+		}),
+		beehive.BufferedCollectorConfig{BufferSize: &buffer})
+
+	dispatcher := beehive.NewDispatcher(worker, it, *writer, beehive.DispatcherConfig{})
+
+	dispatcher.Dispatch()
+	progress.Stop(logger)
+
+	res.Corpus = aggregator.Corpus()
+	logger.Info("Corpus statistics", "sboms", res.Corpus.Sboms, "components", res.Corpus.Components,
+		"ecosystems", len(res.Corpus.Ecosystems))
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(res); err != nil {
+		run.Fail(err)
+		logger.Error("Unable to write the statistics", "err", err)
+	}
+
+	if err := run.Publish(ctx, *reportPath, report.Runs(database, *runsCollectionName)); err != nil {
+		logger.Error("Unable to publish the run report", "err", err)
+	}
+}
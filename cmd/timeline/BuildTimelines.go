@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"slices"
+
+	"sbom-processor/internal/catalogue"
+	"sbom-processor/internal/db"
+	"sbom-processor/internal/logging"
+	"sbom-processor/internal/metrics"
+	"sbom-processor/internal/report"
+	"sbom-processor/internal/sbom"
+	"sbom-processor/internal/shutdown"
+	"sbom-processor/internal/timeline"
+
+	"github.com/janniclas/beehive"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var order = flag.String("order", "tag", "tag or time. defines whether scans are ordered by their tag or by scan time.")
+var matchBy = flag.String("matchBy", "name", "name or purl. defines how components of consecutive scans are matched.")
+var minScans = flag.Int("minScans", 2, "minimum number of scans a repository needs to get a timeline")
+var logLevel = flag.Int("logLevel", 0, "Can be 0 for INFO, -4 for DEBUG, 4 for WARN, or 8 for ERROR. Defaults to INFO.")
+var metricsAddr = flag.String("metricsAddr", "", "address to serve Prometheus metrics on, e.g., :9090. metrics aren't served if empty.")
+var reportPath = flag.String("report", "", "file to write the JSON run report to. written to stderr if empty.")
+var runsCollectionName = flag.String("runsCollection", "", "collection name to store the run report in, e.g., runs. the report isn't stored if empty.")
+var dbName = flag.String("db", "sbom_metadata", "database name to connect to")
+var collectionName = flag.String("collection", "sboms", "collection name for SBOMs")
+var componentsCollectionName = flag.String("componentsCollection", "", "collection name of the component catalogue. set it to read SBOMs stored in the normalized layout, they are read as embedded if empty.")
+var lagCollectionName = flag.String("lagCollection", "lag", "collection name of the technical lag")
+var timelineCollectionName = flag.String("timelineCollection", "timelines", "collection name to store the timelines in")
+
+type repository struct {
+	name    string
+	entries []timeline.Entry
+}
+
+func main() {
+
+	flag.Parse()
+
+	ctx, done := shutdown.Context()
+	defer done()
+
+	run := report.New("timelines")
+
+	logger := logging.SetUpLogging(*logLevel)
+
+	if err := metrics.Serve(*metricsAddr); err != nil {
+		log.Fatalf("Unable to serve metrics %s\n", err.Error())
+	}
+
+	if *order != string(timeline.OrderByTag) && *order != string(timeline.OrderByTime) {
+		log.Fatalf("Unknown order %s, choose tag or time\n", *order)
+	}
+
+	if *matchBy != string(sbom.MatchByName) && *matchBy != string(sbom.MatchByPurl) {
+		log.Fatalf("Unknown match %s, choose name or purl\n", *matchBy)
+	}
+
+	// INPUT VALIDATION
+	uri := os.Getenv("MONGO_URI")
+	usr := os.Getenv("MONGO_USERNAME")
+	pwd := os.Getenv("MONGO_PWD")
+
+	if usr == "" || pwd == "" || uri == "" {
+		log.Fatalf("uri, username or password not found. Make sure MONGO_USERNAME, MONGO_PWD, and MONGO_URI are set\n")
+	}
+
+	// DB CONNECTION
+	client, err := mongo.Connect(options.Client().
+		ApplyURI(uri).
+		SetAuth(options.Credential{
+			Username: usr,
+			Password: pwd,
+		}))
+	if err != nil {
+		panic(err)
+	}
+
+	defer func() {
+		if err := client.Disconnect(context.Background()); err != nil {
+			panic(err)
+		}
+	}()
+
+	database := client.Database(*dbName)
+	sboms := database.Collection(*collectionName)
+	timelines := database.Collection(*timelineCollectionName)
+
+	// timelines in flight are built and stored when ctx is canceled
+	drain := context.WithoutCancel(ctx)
+
+	loader := timeline.MongoLoader{
+		Sboms: &catalogue.Store{Sboms: sboms, Components: catalogue.Collection(database, *componentsCollectionName)},
+		Lags:  database.Collection(*lagCollectionName),
+		Ctx:   drain,
+	}
+
+	if err := loader.Sboms.Verify(ctx); err != nil {
+		log.Fatalf("Unable to read the SBOMs %s\n", err.Error())
+	}
+
+	if err := db.Verify(ctx, timelines, db.Spec("timelines")); err != nil {
+		logger.Warn("Index verification failed", "collection", timelines.Name(), "err", err)
+	}
+
+	logger.Info("Build timelines called", "db", *dbName, "collection", *collectionName, "order", *order)
+
+	// only the source is needed to group the SBOMs
+	cursor, err := sboms.Find(ctx, bson.D{}, options.Find().SetProjection(bson.D{{Key: "source", Value: 1}}))
+	if err != nil {
+		panic(err)
+	}
+	defer cursor.Close(drain)
+
+	var entries []timeline.Entry
+	for s := range db.MongodbIterator[sbom.StoredSbom](ctx, cursor) {
+		entries = append(entries, timeline.NewEntry(s.Id, s.Source))
+	}
+
+	// timelines of incomplete groups would replace the stored ones
+	if ctx.Err() != nil {
+		if err := run.Publish(ctx, *reportPath, report.Runs(database, *runsCollectionName)); err != nil {
+			logger.Error("Unable to publish the run report", "err", err)
+		}
+		return
+	}
+
+	groups, names := timeline.Group(entries)
+	logger.Info("Grouped SBOMs by repository", "sboms", len(entries), "repositories", len(names))
+
+	var repos []repository
+	for _, name := range names {
+		if len(groups[name]) >= *minScans {
+			repos = append(repos, repository{name: name, entries: groups[name]})
+		}
+	}
+	logger.Info("Repositories with enough scans", "repositories", len(repos), "min scans", *minScans)
+
+	progress := metrics.NewProgress("timelines")
+	progress.SetTotal(int64(len(repos)))
+	progress.Start(logger, metrics.LogInterval)
+	run.Track(progress)
+	run.Output(*dbName + "." + *timelineCollectionName)
+
+	worker := beehive.Worker[repository, timeline.Timeline]{
+		Work: metrics.Track(progress, func(r *repository) (*timeline.Timeline, error) {
+			return timeline.Build(r.name, r.entries, timeline.OrderBy(*order), sbom.MatchBy(*matchBy), &loader)
+		}),
+	}
+
+	buffer := 50
+	writer := beehive.NewBufferedCollector(
+		metrics.TrackWrite(progress, func(t []*timeline.Timeline) error {
+			// replace timelines of previous runs
+			models := make([]mongo.WriteModel, len(t))
+			for i, tl := range t {
+				models[i] = mongo.NewReplaceOneModel().
+					SetFilter(bson.D{{Key: "repository", Value: tl.Repository}}).
+					SetReplacement(tl).
+					SetUpsert(true)
+			}
+			_, err := timelines.BulkWrite(drain, models)
+			return err
+		}),
+		beehive.BufferedCollectorConfig{BufferSize: &buffer})
+
+	dispatcher := beehive.NewDispatcher(worker, shutdown.Seq(ctx, slices.Values(repos)), *writer, beehive.DispatcherConfig{})
+
+	dispatcher.Dispatch()
+	progress.Stop(logger)
+
+	if err := run.Publish(ctx, *reportPath, report.Runs(database, *runsCollectionName)); err != nil {
+		logger.Error("Unable to publish the run report", "err", err)
+	}
+}
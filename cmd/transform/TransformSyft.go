@@ -9,11 +9,15 @@ import (
 	"path/filepath"
 	"runtime"
 	"slices"
-	"time"
 
+	"sbom-processor/internal/catalogue"
+	"sbom-processor/internal/db"
 	"sbom-processor/internal/json"
 	"sbom-processor/internal/logging"
+	"sbom-processor/internal/metrics"
+	"sbom-processor/internal/report"
 	"sbom-processor/internal/sbom"
+	"sbom-processor/internal/shutdown"
 	"sbom-processor/internal/validator"
 
 	"github.com/janniclas/beehive"
@@ -24,18 +28,37 @@ import (
 var mode = flag.String("mode", "file", "file or db. defines whether to store results in db or file.")
 var dbName = flag.String("db", "sbom_metadata", "database name to connect to")
 var collectionName = flag.String("collection", "sboms", "collection name for SBOMs")
+var componentsCollectionName = flag.String("componentsCollection", "", "collection name of the component catalogue. SBOMs are stored in the normalized layout if set, embedded otherwise. only used in db mode.")
 var in = flag.String("in", "", "Path to SBOM")
 var out = flag.String("out", "", "File to write the SBOM to")
+var relationships = flag.String("relationships", "", "relationship modes as type=mode pairs, e.g., contains=collapse,evident-by=drop. modes are keep, drop, or collapse. all relationships are kept if empty.")
+var validateRefs = flag.Bool("validateRefs", false, "fail SBOMs with relationships that refer to ids which aren't components")
+var imageRoot = flag.Bool("imageRoot", false, "add the image as root component that contains all top-level components")
+var packagesOnly = flag.Bool("packagesOnly", false, "collapse the file relationships, validate the refs, and add the image root. overrides the other relationship flags.")
 var logLevel = flag.Int("logLevel", 0, "Can be 0 for INFO, -4 for DEBUG, 4 for WARN, or 8 for ERROR. Defaults to INFO.")
+var metricsAddr = flag.String("metricsAddr", "", "address to serve Prometheus metrics on, e.g., :9090. metrics aren't served if empty.")
+var reportPath = flag.String("report", "", "file to write the JSON run report to. written to stderr if empty.")
+var runsCollectionName = flag.String("runsCollection", "", "collection name to store the run report in, e.g., runs. the report isn't stored if empty.")
+
+// applied to every SBOM, set from the flags
+var transformOptions sbom.TransformOptions
 
 func main() {
 
-	start := time.Now()
 	// get input path and check for correctness
 	flag.Parse()
 
+	ctx, done := shutdown.Context()
+	defer done()
+
+	run := report.New("transform")
+
 	logger := logging.SetUpLogging(*logLevel)
 
+	if err := metrics.Serve(*metricsAddr); err != nil {
+		log.Fatalf("Unable to serve metrics %s\n", err.Error())
+	}
+
 	if *mode != "file" && *mode != "db" {
 		panic("Unkown operation mode, choose file or db")
 	}
@@ -61,20 +84,43 @@ func main() {
 		}
 	}
 
+	if *packagesOnly {
+		transformOptions = sbom.PackageOptions()
+	} else {
+		modes, err := sbom.ParseRelationships(*relationships)
+		if err != nil {
+			log.Fatal(err)
+		}
+		transformOptions = sbom.TransformOptions{
+			Relationships: modes,
+			ValidateRefs:  *validateRefs,
+			ImageRoot:     *imageRoot,
+		}
+	}
+
 	paths, err := json.CollectJsonFiles(*in)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	logger.Info("Starting syft to cyclonedx transformation", "path", *in, "mode", *mode)
+	logger.Info("Starting syft to cyclonedx transformation", "path", *in, "mode", *mode, "componentsCollection", *componentsCollectionName, "options", transformOptions)
+
+	progress := metrics.NewProgress("transform")
+	progress.SetTotal(int64(len(paths)))
+	run.Track(progress)
 
 	var writer *beehive.BufferedCollector[sbom.CyclonedxSbom]
+	// only set in db mode
+	var database *mongo.Database
 
 	if *mode == "file" {
 		buffer := 100
 		writer = beehive.NewBufferedCollector(
-			writeToFile,
+			func(t []*sbom.CyclonedxSbom) error {
+				return writeToFile(t, progress)
+			},
 			beehive.BufferedCollectorConfig{BufferSize: &buffer})
+		run.Output(*out)
 	} else {
 
 		// DB CONNECTION
@@ -94,42 +140,71 @@ func main() {
 			}
 		}()
 
-		coll := client.Database(*dbName).Collection(*collectionName)
+		database = client.Database(*dbName)
+		coll := database.Collection(*collectionName)
+
+		store := catalogue.Store{Sboms: coll, Components: catalogue.Collection(database, *componentsCollectionName)}
+		if err := db.Verify(ctx, coll, db.Spec("sboms")); err != nil {
+			logger.Warn("Index verification failed", "collection", coll.Name(), "err", err)
+		}
 
 		buffer := 200
 		writer = beehive.NewBufferedCollector(
-			func(t []*sbom.CyclonedxSbom) error {
-				_, err := coll.InsertMany(context.Background(), t)
-
-				return err
-			},
+			metrics.TrackWrite(progress, func(t []*sbom.CyclonedxSbom) error {
+				// SBOMs in flight are stored when ctx is canceled
+				drain := context.WithoutCancel(ctx)
+
+				if store.Components == nil {
+					_, err := coll.InsertMany(drain, t)
+					return err
+				}
+
+				normalized := make([]*catalogue.Normalized, len(t))
+				for i, s := range t {
+					normalized[i] = catalogue.Normalize(s)
+				}
+				return store.Insert(drain, normalized)
+			}),
 			beehive.BufferedCollectorConfig{BufferSize: &buffer})
+		run.Output(*dbName + "." + *collectionName)
+		if store.Components != nil {
+			run.Output(*dbName + "." + *componentsCollectionName)
+		}
 	}
 
+	progress.Start(logger, metrics.LogInterval)
+
 	worker := beehive.Worker[string, sbom.CyclonedxSbom]{
-		Work: transformSbom,
+		Work: metrics.Track(progress, transformSbom),
 	}
 	noWorker := runtime.NumCPU()
 
-	d := beehive.NewDispatcher(worker, slices.Values(paths), *writer,
+	d := beehive.NewDispatcher(worker, shutdown.Seq(ctx, slices.Values(paths)), *writer,
 		beehive.DispatcherConfig{NumWorker: &noWorker})
 
 	logger.Debug("Initialized dispatcher", "dispatcher", d)
 
 	d.Dispatch()
+	progress.Stop(logger)
 
-	elapsed := time.Since(start)
-	logger.Info("Finished syft transform", "time elapsed", elapsed)
+	var runs *mongo.Collection
+	if database != nil {
+		runs = report.Runs(database, *runsCollectionName)
+	}
+	if err := run.Publish(ctx, *reportPath, runs); err != nil {
+		logger.Error("Unable to publish the run report", "err", err)
+	}
 }
 
-func writeToFile(t []*sbom.CyclonedxSbom) error {
+func writeToFile(t []*sbom.CyclonedxSbom, progress *metrics.Progress) error {
 	var err error
 	for _, s := range t {
-		ts := time.Now().Format("20060102150405") // Format: YYYYMMDDHHMMSS
-		outPath := filepath.Join(*out, s.Source.Id+"-"+ts+".json")
+		// content-addressed, equal SBOMs overwrite each other with the same content
+		outPath := filepath.Join(*out, s.FileName())
 		err = json.StoreFile(outPath, s)
 		if err != nil {
 			slog.Default().Error("err during file storage", "file", outPath, "error", err)
+			progress.WriteFailed(1, err)
 		}
 	}
 
@@ -142,5 +217,11 @@ func transformSbom(p *string) (*sbom.CyclonedxSbom, error) {
 		return nil, err
 	}
 
-	return syft.Transform()
+	c, err := syft.TransformWithOptions(transformOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	// identical inputs result in identical outputs
+	return c, c.Canonicalize()
 }
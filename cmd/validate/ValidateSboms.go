@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"sbom-processor/internal/json"
+	"sbom-processor/internal/logging"
+	"sbom-processor/internal/metrics"
+	"sbom-processor/internal/report"
+	"sbom-processor/internal/shutdown"
+	"sbom-processor/internal/validator"
+)
+
+var in = flag.String("in", "", "Path to a SBOM file or a directory of SBOMs")
+var format = flag.String("format", "auto", "auto, syft, or cyclonedx. auto detects the format of each file.")
+var rules = flag.String("rules", "", "severity overrides as rule=severity pairs, e.g., missing-version=error,missing-distro=off. severities are off, info, warning, or error.")
+var failOn = flag.String("failOn", "error", "info, warning, or error. files with findings of this or a higher severity fail.")
+var out = flag.String("out", "", "File to write the JSON report to. Defaults to stdout.")
+var listRules = flag.Bool("listRules", false, "print the rules with their default severity and exit")
+var logLevel = flag.Int("logLevel", 0, "Can be 0 for INFO, -4 for DEBUG, 4 for WARN, or 8 for ERROR. Defaults to INFO.")
+var metricsAddr = flag.String("metricsAddr", "", "address to serve Prometheus metrics on, e.g., :9090. metrics aren't served if empty.")
+var reportPath = flag.String("report", "", "file to write the JSON run report to. written to stderr if empty.")
+
+// exit status if at least one file fails
+const exitFailed = 1
+
+var errFailed = errors.New("validation failed")
+
+func main() {
+
+	flag.Parse()
+
+	if *listRules {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, r := range validator.Rules() {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", r.Name, r.Severity, r.Description)
+		}
+		w.Flush()
+		return
+	}
+
+	ctx, done := shutdown.Context()
+	defer done()
+
+	run := report.New("validate")
+
+	logger := logging.SetUpLogging(*logLevel)
+
+	if err := metrics.Serve(*metricsAddr); err != nil {
+		log.Fatalf("Unable to serve metrics %s\n", err.Error())
+	}
+
+	inputFormat := validator.Format(*format)
+	switch inputFormat {
+	case "auto":
+		inputFormat = ""
+	case validator.Syft, validator.Cyclonedx:
+	default:
+		log.Fatalf("Unknown format %s, choose auto, syft, or cyclonedx\n", *format)
+	}
+
+	overrides, err := validator.ParseSeverities(*rules)
+	if err != nil {
+		log.Fatal(err)
+	}
+	threshold, err := validator.ParseSeverity(*failOn)
+	if err != nil || threshold == validator.Off {
+		log.Fatalf("Unknown failOn %s, choose info, warning, or error\n", *failOn)
+	}
+
+	if *in == "" {
+		log.Fatalf("--in is required\n")
+	}
+	files, err := json.CollectJsonFiles(*in)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		w = f
+		run.Output(*out)
+	}
+	buffered := bufio.NewWriter(w)
+	reports, err := json.NewArrayWriter[*validator.FileReport](buffered)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	logger.Info("Validate called", "in", *in, "files", len(files), "failOn", threshold.String())
+
+	progress := metrics.NewProgress("validate")
+	progress.SetTotal(int64(len(files)))
+	run.Track(progress)
+
+	failed := 0
+	for _, p := range files {
+		if ctx.Err() != nil {
+			break
+		}
+
+		r := validator.ValidateFile(p, inputFormat, overrides, threshold)
+		if err := reports.Write(r); err != nil {
+			log.Fatal(err)
+		}
+
+		switch {
+		case r.Error != "":
+			logger.Error("Unable to read SBOM", "file", p, "err", r.Error)
+			progress.Fail(errors.New(r.Error))
+			failed++
+		case !r.Passed:
+			logger.Warn("SBOM failed the validation", "file", p, "findings", len(r.Findings),
+				"highest", validator.Highest(r.Findings).String())
+			progress.Fail(errFailed)
+			failed++
+		default:
+			logger.Debug("SBOM passed the validation", "file", p, "findings", len(r.Findings))
+			progress.Processed(1)
+		}
+	}
+
+	if err := reports.Close(); err != nil {
+		log.Fatal(err)
+	}
+	if err := buffered.Flush(); err != nil {
+		log.Fatal(err)
+	}
+
+	logger.Info("Validation done", "files", len(files), "failed", failed)
+
+	if err := run.Publish(ctx, *reportPath, nil); err != nil {
+		logger.Error("Unable to publish the run report", "err", err)
+	}
+
+	// interrupted runs exit with the status of shutdown
+	if failed > 0 && !shutdown.Interrupted(ctx) {
+		os.Exit(exitFailed)
+	}
+}
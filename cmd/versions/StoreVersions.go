@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"strings"
+
+	"sbom-processor/internal/logging"
+	"sbom-processor/internal/metrics"
+	"sbom-processor/internal/report"
+	"sbom-processor/internal/shutdown"
+	"sbom-processor/internal/store"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var logLevel = flag.Int("logLevel", 0, "Can be 0 for INFO, -4 for DEBUG, 4 for WARN, or 8 for ERROR. Defaults to INFO.")
+var metricsAddr = flag.String("metricsAddr", "", "address to serve Prometheus metrics on, e.g., :9090. metrics aren't served if empty.")
+var reportPath = flag.String("report", "", "file to write the JSON run report to. written to stderr if empty.")
+var runsCollectionName = flag.String("runsCollection", "", "collection name to store the run report in, e.g., runs. the report isn't stored if empty.")
+var dbName = flag.String("db", "sbom_metadata", "database name to connect to")
+var collectionName = flag.String("collection", "sboms", "collection name for SBOMs")
+var componentsCollectionName = flag.String("componentsCollection", "", "collection name of the component catalogue. set it to read SBOMs stored in the normalized layout, they are read as embedded if empty.")
+var versionsCollectionName = flag.String("versionsCollection", "versions", "collection name to store the versions in")
+var blacklistCollectionName = flag.String("blacklistCollection", "blacklist", "collection name to store failed lookups in")
+var componentTypes = flag.String("componentType", "deb", "comma separated list of component types to query versions for")
+var batchSize = flag.Int("batchSize", 200, "max number of documents per insert and cache check")
+
+func main() {
+
+	flag.Parse()
+
+	ctx, done := shutdown.Context()
+	defer done()
+
+	run := report.New("versions")
+
+	logger := logging.SetUpLogging(*logLevel)
+
+	if err := metrics.Serve(*metricsAddr); err != nil {
+		log.Fatalf("Unable to serve metrics %s\n", err.Error())
+	}
+
+	if *batchSize < 1 {
+		log.Fatalf("batch size must be positive\n")
+	}
+
+	var types []string
+	for _, t := range strings.Split(*componentTypes, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			types = append(types, t)
+		}
+	}
+	if len(types) == 0 {
+		log.Fatalf("at least one component type is required\n")
+	}
+
+	// INPUT VALIDATION
+	uri := os.Getenv("MONGO_URI")
+	usr := os.Getenv("MONGO_USERNAME")
+	pwd := os.Getenv("MONGO_PWD")
+
+	if usr == "" || pwd == "" || uri == "" {
+		log.Fatalf("uri, username or password not found. Make sure MONGO_USERNAME, MONGO_PWD, and MONGO_URI are set\n")
+	}
+
+	// DB CONNECTION
+	client, err := mongo.Connect(options.Client().
+		ApplyURI(uri).
+		SetAuth(options.Credential{
+			Username: usr,
+			Password: pwd,
+		}))
+	if err != nil {
+		panic(err)
+	}
+
+	defer func() {
+		if err := client.Disconnect(context.Background()); err != nil {
+			panic(err)
+		}
+	}()
+
+	logger.Info("Store version information called", "db", *dbName, "collection", *collectionName, "componentTypes", types)
+
+	progress := metrics.NewProgress("versions")
+	run.Track(progress)
+	run.Output(*dbName+"."+*versionsCollectionName, *dbName+"."+*blacklistCollectionName)
+
+	totals, err := store.StoreVersionInformation(ctx, client, store.Config{
+		Db:                   *dbName,
+		Collection:           *collectionName,
+		ComponentsCollection: *componentsCollectionName,
+		VersionsCollection:   *versionsCollectionName,
+		BlacklistCollection:  *blacklistCollectionName,
+		ComponentTypes:       types,
+		BatchSize:            *batchSize,
+		Progress:             progress,
+	})
+	if err != nil && ctx.Err() == nil {
+		logger.Error("Version harvest failed", "err", err)
+		run.Fail(err)
+	}
+
+	logger.Info("Version harvest totals", "totals", totals)
+
+	if err := run.Publish(ctx, *reportPath, report.Runs(client.Database(*dbName), *runsCollectionName)); err != nil {
+		logger.Error("Unable to publish the run report", "err", err)
+	}
+}
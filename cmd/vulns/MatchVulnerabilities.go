@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"sbom-processor/internal/catalogue"
+	"sbom-processor/internal/db"
+	"sbom-processor/internal/logging"
+	"sbom-processor/internal/metrics"
+	"sbom-processor/internal/osv"
+	"sbom-processor/internal/report"
+	"sbom-processor/internal/sbom"
+	"sbom-processor/internal/shutdown"
+
+	"github.com/janniclas/beehive"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var mode = flag.String("mode", "match", "import or match. import loads OSV advisories from disk, match evaluates them for all stored SBOMs.")
+var in = flag.String("in", "", "OSV bulk data zip file or directory containing zip files, e.g., Debian/all.zip. Required for import.")
+var logLevel = flag.Int("logLevel", 0, "Can be 0 for INFO, -4 for DEBUG, 4 for WARN, or 8 for ERROR. Defaults to INFO.")
+var metricsAddr = flag.String("metricsAddr", "", "address to serve Prometheus metrics on, e.g., :9090. metrics aren't served if empty.")
+var reportPath = flag.String("report", "", "file to write the JSON run report to. written to stderr if empty.")
+var runsCollectionName = flag.String("runsCollection", "", "collection name to store the run report in, e.g., runs. the report isn't stored if empty.")
+var dbName = flag.String("db", "sbom_metadata", "database name to connect to")
+var collectionName = flag.String("collection", "sboms", "collection name for SBOMs")
+var componentsCollectionName = flag.String("componentsCollection", "", "collection name of the component catalogue. set it to read SBOMs stored in the normalized layout, they are read as embedded if empty.")
+var osvCollectionName = flag.String("osvCollection", "osv", "collection name of the imported OSV advisories")
+var findingsCollectionName = flag.String("findingsCollection", "findings", "collection name to store the findings in")
+
+func main() {
+
+	flag.Parse()
+
+	ctx, done := shutdown.Context()
+	defer done()
+
+	run := report.New("vulns")
+
+	logger := logging.SetUpLogging(*logLevel)
+
+	if err := metrics.Serve(*metricsAddr); err != nil {
+		log.Fatalf("Unable to serve metrics %s\n", err.Error())
+	}
+
+	if *mode != "import" && *mode != "match" {
+		log.Fatalf("Unknown mode %s, choose import or match\n", *mode)
+	}
+
+	if *mode == "import" && *in == "" {
+		log.Fatalf("Import requires the OSV data to be provided with -in\n")
+	}
+
+	// INPUT VALIDATION
+	uri := os.Getenv("MONGO_URI")
+	usr := os.Getenv("MONGO_USERNAME")
+	pwd := os.Getenv("MONGO_PWD")
+
+	if usr == "" || pwd == "" || uri == "" {
+		log.Fatalf("uri, username or password not found. Make sure MONGO_USERNAME, MONGO_PWD, and MONGO_URI are set\n")
+	}
+
+	// DB CONNECTION
+	client, err := mongo.Connect(options.Client().
+		ApplyURI(uri).
+		SetAuth(options.Credential{
+			Username: usr,
+			Password: pwd,
+		}))
+	if err != nil {
+		panic(err)
+	}
+
+	defer func() {
+		if err := client.Disconnect(context.Background()); err != nil {
+			panic(err)
+		}
+	}()
+
+	database := client.Database(*dbName)
+	osvColl := database.Collection(*osvCollectionName)
+
+	if err := db.Verify(ctx, osvColl, db.Spec("osv")); err != nil {
+		logger.Warn("Index verification failed", "collection", osvColl.Name(), "err", err)
+	}
+
+	if *mode == "import" {
+		importAdvisories(ctx, osvColl, run, logger)
+	} else {
+		matchSboms(ctx, database, osvColl, run, logger)
+	}
+
+	if err := run.Publish(ctx, *reportPath, report.Runs(database, *runsCollectionName)); err != nil {
+		logger.Error("Unable to publish the run report", "err", err)
+	}
+}
+
+// returns the zip files to import. in is either a zip file
+// or a directory that is searched recursively.
+func zipFiles(in string) ([]string, error) {
+	info, err := os.Stat(in)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{in}, nil
+	}
+
+	var files []string
+	err = filepath.WalkDir(in, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.HasSuffix(d.Name(), ".zip") {
+			files = append(files, p)
+		}
+		return nil
+	})
+	return files, err
+}
+
+func importAdvisories(ctx context.Context, osvColl *mongo.Collection, run *report.Report, logger *slog.Logger) {
+	files, err := zipFiles(*in)
+	if err != nil {
+		log.Fatalf("Unable to read OSV data %s\n", err.Error())
+	}
+
+	logger.Info("Import OSV advisories called", "files", len(files), "collection", *osvCollectionName)
+
+	progress := metrics.NewProgress("osv_import")
+	progress.Start(logger, metrics.LogInterval)
+	run.Track(progress)
+	run.Output(*dbName + "." + *osvCollectionName)
+
+	buffer := 500
+	writer := beehive.NewBufferedCollector(
+		metrics.TrackWrite(progress, func(a []*osv.Advisory) error {
+			// advisories are updated upstream, replace older imports
+			models := make([]mongo.WriteModel, len(a))
+			for i, adv := range a {
+				models[i] = mongo.NewReplaceOneModel().
+					SetFilter(bson.D{{Key: "id", Value: adv.Id}}).
+					SetReplacement(adv).
+					SetUpsert(true)
+			}
+			_, err := osvColl.BulkWrite(context.WithoutCancel(ctx), models)
+			return err
+		}),
+		beehive.BufferedCollectorConfig{BufferSize: &buffer})
+
+	worker := beehive.Worker[osv.Advisory, osv.Advisory]{
+		Work: metrics.Track(progress, func(a *osv.Advisory) (*osv.Advisory, error) {
+			return a, nil
+		}),
+	}
+
+	// advisories of all files, broken entries are skipped.
+	// the import stops after the current advisory if ctx is canceled.
+	advisories := func(yield func(osv.Advisory) bool) {
+		for _, f := range files {
+			logger.Info("Importing advisories", "file", f)
+			for a, err := range osv.ReadZip(f) {
+				if ctx.Err() != nil {
+					return
+				}
+				if err != nil {
+					logger.Warn("Skipping advisory", "file", f, "err", err)
+					progress.Fail(err)
+					continue
+				}
+				if !yield(*a) {
+					return
+				}
+			}
+		}
+	}
+
+	dispatcher := beehive.NewDispatcher(worker, advisories, *writer, beehive.DispatcherConfig{})
+
+	dispatcher.Dispatch()
+	progress.Stop(logger)
+}
+
+func matchSboms(ctx context.Context, database *mongo.Database, osvColl *mongo.Collection, run *report.Report, logger *slog.Logger) {
+	sbomsColl := database.Collection(*collectionName)
+	findings := database.Collection(*findingsCollectionName)
+
+	if err := db.Verify(ctx, findings, db.Spec("findings")); err != nil {
+		logger.Warn("Index verification failed", "collection", findings.Name(), "err", err)
+	}
+
+	// SBOMs in flight are matched and stored when ctx is canceled
+	drain := context.WithoutCancel(ctx)
+
+	lookup := osv.MongoLookup{
+		Collection: osvColl,
+		Ctx:        drain,
+	}
+
+	logger.Info("Match vulnerabilities called", "db", *dbName, "collection", *collectionName, "findingsCollection", *findingsCollectionName)
+
+	progress := metrics.NewProgress("vulns")
+	if total, err := sbomsColl.EstimatedDocumentCount(ctx); err == nil {
+		progress.SetTotal(total)
+	}
+	progress.Start(logger, metrics.LogInterval)
+	run.Track(progress)
+	run.Output(*dbName + "." + *findingsCollectionName)
+
+	sbomStore := catalogue.Store{Sboms: sbomsColl, Components: catalogue.Collection(database, *componentsCollectionName)}
+	if err := sbomStore.Verify(ctx); err != nil {
+		log.Fatalf("Unable to read the SBOMs %s\n", err.Error())
+	}
+	cursor, err := sbomStore.Find(ctx, bson.D{})
+	if err != nil {
+		panic(err)
+	}
+	defer cursor.Close(drain)
+
+	it := db.MongodbIterator[sbom.StoredSbom](ctx, cursor)
+
+	worker := beehive.Worker[sbom.StoredSbom, osv.SbomFindings]{
+		Work: metrics.Track(progress, func(s *sbom.StoredSbom) (*osv.SbomFindings, error) {
+			f, err := osv.MatchSbom(s, &lookup)
+			if err != nil {
+				return nil, err
+			}
+			logger.Debug("Matched vulnerabilities", "source", s.Source.Name, "queried", f.Queried, "vulnerable", f.Vulnerable)
+			return f, nil
+		}),
+	}
+
+	buffer := 100
+	writer := beehive.NewBufferedCollector(
+		metrics.TrackWrite(progress, func(f []*osv.SbomFindings) error {
+			_, err := findings.InsertMany(drain, f)
+			return err
+		}),
+		beehive.BufferedCollectorConfig{BufferSize: &buffer})
+
+	dispatcher := beehive.NewDispatcher(worker, it, *writer, beehive.DispatcherConfig{})
+
+	dispatcher.Dispatch()
+	progress.Stop(logger)
+}
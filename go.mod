@@ -4,18 +4,23 @@ go 1.24.5
 
 require (
 	github.com/hashicorp/go-version v1.7.0
+	github.com/janniclas/beehive v0.0.2
+	github.com/parquet-go/parquet-go v0.25.1
 	go.mongodb.org/mongo-driver/v2 v2.2.2
 	golang.org/x/sync v0.16.0
-	github.com/janniclas/beehive v0.0.2
 )
 
 require (
+	github.com/andybalholm/brotli v1.1.0 // indirect
 	github.com/golang/snappy v1.0.0 // indirect
+	github.com/google/uuid v1.6.0 // indirect
 	github.com/klauspost/compress v1.18.0 // indirect
+	github.com/pierrec/lz4/v4 v4.1.21 // indirect
 	github.com/xdg-go/pbkdf2 v1.0.0 // indirect
 	github.com/xdg-go/scram v1.1.2 // indirect
 	github.com/xdg-go/stringprep v1.0.4 // indirect
 	github.com/youmark/pkcs8 v0.0.0-20240726163527-a2c0da244d78 // indirect
 	golang.org/x/crypto v0.40.0 // indirect
+	golang.org/x/sys v0.34.0 // indirect
 	golang.org/x/text v0.27.0 // indirect
 )
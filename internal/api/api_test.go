@@ -0,0 +1,296 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"sbom-processor/internal/lag"
+	"sbom-processor/internal/sbom"
+	"sbom-processor/internal/semver"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func storedSbom(name string, components ...sbom.Component) sbom.StoredSbom {
+	return sbom.StoredSbom{
+		Id: bson.NewObjectID(),
+		CyclonedxSbom: sbom.CyclonedxSbom{
+			Source:     sbom.Source{Id: name + "-id", Name: name, Version: "sha256:" + name},
+			Distro:     sbom.Distro{Id: "debian", Version: "12"},
+			Components: components,
+		},
+	}
+}
+
+func newTestServer(t *testing.T) (*httptest.Server, *MemoryStore) {
+	store := &MemoryStore{
+		StoredSboms: []sbom.StoredSbom{
+			storedSbom("nginx:1.25",
+				sbom.Component{Id: "a1", Name: "openssl", Type: "deb", Version: "3.0.11-1"},
+				sbom.Component{Id: "b1", Name: "zlib", Type: "deb", Version: "1.2.13"},
+			),
+			storedSbom("nginx:1.24",
+				sbom.Component{Id: "a0", Name: "openssl", Type: "deb", Version: "1.1.1w-0"},
+				sbom.Component{Id: "b1", Name: "zlib", Type: "deb", Version: "1.2.13"},
+			),
+			storedSbom("redis:7",
+				sbom.Component{Id: "a1", Name: "openssl", Type: "deb", Version: "3.0.11-1"},
+				sbom.Component{Id: "c1", Name: "openssl", Type: "python", Version: "23.2.0"},
+			),
+		},
+		Lags: []lag.SbomLag{
+			{ComputedAt: time.Now(), Components: []lag.ComponentLag{
+				{ComponentId: "a1", Name: "openssl", Type: "deb", Version: "3.0.11-1", Status: lag.StatusOk, Distance: &semver.VersionDistance{MissedReleases: 2}},
+				{ComponentId: "b1", Name: "zlib", Type: "deb", Version: "1.2.13", Status: lag.StatusOk},
+			}},
+			{ComputedAt: time.Now(), Components: []lag.ComponentLag{
+				{ComponentId: "a0", Name: "openssl", Type: "deb", Version: "1.1.1w-0", Status: lag.StatusInvalidVersion},
+				{ComponentId: "b1", Name: "zlib", Type: "deb", Version: "1.2.13", Status: lag.StatusOk},
+			}},
+			{ComputedAt: time.Now(), Components: []lag.ComponentLag{
+				{ComponentId: "a1", Name: "openssl", Type: "deb", Version: "3.0.11-1", Status: lag.StatusOk, Distance: &semver.VersionDistance{MissedReleases: 2}},
+				{ComponentId: "c1", Name: "openssl", Type: "python", Version: "23.2.0", Status: lag.StatusNoVersionData},
+			}},
+		},
+		ComponentVersions: []semver.ComponentVersions{
+			{ComponentId: "a1", Versions: []semver.ComponentVersion{{Version: "3.0.11-1"}, {Version: "3.0.13-1"}}},
+		},
+	}
+
+	srv := httptest.NewServer(NewHandler(store, slog.New(slog.NewTextHandler(io.Discard, nil))))
+	t.Cleanup(srv.Close)
+	return srv, store
+}
+
+// GETs path and decodes the response into v
+func get(t *testing.T, srv *httptest.Server, path string, status int, v any) {
+	t.Helper()
+
+	resp, err := http.Get(srv.URL + path)
+	if err != nil {
+		t.Fatalf("GET %s failed: %s", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != status {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("GET %s returned %d, expected %d: %s", path, resp.StatusCode, status, body)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("invalid response of %s: %s", path, err)
+	}
+}
+
+func TestSboms(t *testing.T) {
+	srv, store := newTestServer(t)
+
+	var all ListResponse[SbomSummary]
+	get(t, srv, "/api/v1/sboms", http.StatusOK, &all)
+	if all.Total != 3 || len(all.Items) != 3 || all.Limit != DefaultLimit {
+		t.Fatalf("unexpected list %+v", all)
+	}
+
+	var bySource ListResponse[SbomSummary]
+	get(t, srv, "/api/v1/sboms?source=sha256:redis:7", http.StatusOK, &bySource)
+	if bySource.Total != 1 || bySource.Items[0].Source.Name != "redis:7" || bySource.Items[0].Components != 2 {
+		t.Fatalf("unexpected SBOMs for source %+v", bySource)
+	}
+
+	id := store.StoredSboms[1].Id
+	var s sbom.StoredSbom
+	get(t, srv, "/api/v1/sboms/"+id.Hex(), http.StatusOK, &s)
+	if s.Id != id || s.Source.Name != "nginx:1.24" || len(s.Components) != 2 {
+		t.Fatalf("unexpected SBOM %+v", s)
+	}
+
+	var e ErrorResponse
+	get(t, srv, "/api/v1/sboms/"+bson.NewObjectID().Hex(), http.StatusNotFound, &e)
+	get(t, srv, "/api/v1/sboms/nope", http.StatusBadRequest, &e)
+	if !strings.Contains(e.Error, "nope") {
+		t.Fatalf("unexpected error %s", e.Error)
+	}
+}
+
+func TestPagination(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	var ids []bson.ObjectID
+	for offset := 0; offset < 3; offset++ {
+		var page ListResponse[SbomSummary]
+		get(t, srv, "/api/v1/sboms?limit=1&offset="+strconv.Itoa(offset), http.StatusOK, &page)
+		if page.Total != 3 || len(page.Items) != 1 || page.Offset != offset {
+			t.Fatalf("unexpected page %+v", page)
+		}
+		ids = append(ids, page.Items[0].Id)
+	}
+	if !slices.IsSortedFunc(ids, func(a, b bson.ObjectID) int { return strings.Compare(a.Hex(), b.Hex()) }) {
+		t.Fatalf("pages aren't ordered by id")
+	}
+
+	var past ListResponse[SbomSummary]
+	get(t, srv, "/api/v1/sboms?offset=10", http.StatusOK, &past)
+	if past.Total != 3 || past.Items == nil || len(past.Items) != 0 {
+		t.Fatalf("unexpected page past the end %+v", past)
+	}
+
+	var e ErrorResponse
+	get(t, srv, "/api/v1/sboms?limit=0", http.StatusBadRequest, &e)
+	get(t, srv, "/api/v1/sboms?limit=5000", http.StatusBadRequest, &e)
+	get(t, srv, "/api/v1/sboms?offset=-1", http.StatusBadRequest, &e)
+}
+
+func TestComponents(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	var byName ListResponse[ComponentSummary]
+	get(t, srv, "/api/v1/components?name=openssl", http.StatusOK, &byName)
+	if byName.Total != 3 {
+		t.Fatalf("unexpected components %+v", byName)
+	}
+	// ordered by name, type, and version
+	first := byName.Items[0]
+	if first.Type != "deb" || first.Version != "1.1.1w-0" || first.Sboms != 1 {
+		t.Fatalf("unexpected first component %+v", first)
+	}
+	if second := byName.Items[1]; second.Version != "3.0.11-1" || second.Sboms != 2 {
+		t.Fatalf("unexpected second component %+v", second)
+	}
+
+	var inRange ListResponse[ComponentSummary]
+	get(t, srv, "/api/v1/components?name=openssl&type=deb&minVersion=3.0.0", http.StatusOK, &inRange)
+	if inRange.Total != 1 || inRange.Items[0].Version != "3.0.11-1" {
+		t.Fatalf("unexpected components in range %+v", inRange)
+	}
+
+	var upper ListResponse[ComponentSummary]
+	get(t, srv, "/api/v1/components?type=deb&maxVersion=2", http.StatusOK, &upper)
+	if upper.Total != 2 {
+		t.Fatalf("unexpected components below 2 %+v", upper)
+	}
+
+	var e ErrorResponse
+	get(t, srv, "/api/v1/components?name=openssl&minVersion=not-a-version", http.StatusBadRequest, &e)
+	get(t, srv, "/api/v1/components", http.StatusBadRequest, &e)
+}
+
+func TestComponentsOsRange(t *testing.T) {
+	store := &MemoryStore{StoredSboms: []sbom.StoredSbom{
+		storedSbom("debian",
+			sbom.Component{Id: "a", Name: "openssl", Type: "deb", Version: "1:3.0.11-1"},
+			sbom.Component{Id: "b", Name: "openssl", Type: "deb", Version: "3.0.13-1~deb12u1"},
+		),
+		storedSbom("alpine",
+			sbom.Component{Id: "c", Name: "openssl", Type: "apk", Version: "3.1.4-r5"},
+			sbom.Component{Id: "d", Name: "openssl", Type: "apk", Version: "3.1.4_rc1-r0"},
+		),
+	}}
+	srv := httptest.NewServer(NewHandler(store, slog.New(slog.NewTextHandler(io.Discard, nil))))
+	t.Cleanup(srv.Close)
+
+	versions := func(path string) []string {
+		var res ListResponse[ComponentSummary]
+		get(t, srv, path, http.StatusOK, &res)
+		var v []string
+		for _, c := range res.Items {
+			v = append(v, c.Version)
+		}
+		return v
+	}
+
+	// the epoch orders 1:3.0.11-1 after all versions without epoch
+	if v := versions("/api/v1/components?type=deb&minVersion=1:3.0.0"); !slices.Equal(v, []string{"1:3.0.11-1"}) {
+		t.Fatalf("unexpected deb versions with epoch %v", v)
+	}
+	// the release candidate precedes the release in the apk order
+	if v := versions("/api/v1/components?type=apk&minVersion=3.1.4-r0"); !slices.Equal(v, []string{"3.1.4-r5"}) {
+		t.Fatalf("unexpected apk versions %v", v)
+	}
+	// bounds are accepted if any version order can parse them
+	if v := versions("/api/v1/components?name=openssl&maxVersion=3.1.4-r1"); len(v) != 2 {
+		t.Fatalf("unexpected versions below 3.1.4-r1 %v", v)
+	}
+
+	var e ErrorResponse
+	get(t, srv, "/api/v1/components?type=deb&minVersion=v3", http.StatusBadRequest, &e)
+}
+
+func TestImages(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	var withOpenssl ListResponse[SbomSummary]
+	get(t, srv, "/api/v1/images?name=openssl&type=deb&version=3.0.11-1", http.StatusOK, &withOpenssl)
+	names := []string{}
+	for _, s := range withOpenssl.Items {
+		names = append(names, s.Source.Name)
+	}
+	slices.Sort(names)
+	if !slices.Equal(names, []string{"nginx:1.25", "redis:7"}) {
+		t.Fatalf("unexpected images %v", names)
+	}
+
+	var old ListResponse[SbomSummary]
+	get(t, srv, "/api/v1/images?name=openssl&maxVersion=2.0", http.StatusOK, &old)
+	if old.Total != 1 || old.Items[0].Source.Name != "nginx:1.24" {
+		t.Fatalf("unexpected images with old openssl %+v", old)
+	}
+
+	var e ErrorResponse
+	get(t, srv, "/api/v1/images", http.StatusBadRequest, &e)
+}
+
+func TestLagAndVersions(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	var lags []lag.ComponentLag
+	get(t, srv, "/api/v1/lag?name=openssl&type=deb", http.StatusOK, &lags)
+	if len(lags) != 2 {
+		t.Fatalf("unexpected lag %+v", lags)
+	}
+	// a1 is listed once for both SBOMs
+	if lags[1].ComponentId != "a1" || lags[1].Status != lag.StatusOk || lags[1].Distance.MissedReleases != 2 {
+		t.Fatalf("unexpected lag of a1 %+v", lags[1])
+	}
+
+	var none []lag.ComponentLag
+	get(t, srv, "/api/v1/lag?name=unknown", http.StatusOK, &none)
+	if none == nil || len(none) != 0 {
+		t.Fatalf("expected an empty list, got %+v", none)
+	}
+
+	var versions semver.ComponentVersions
+	get(t, srv, "/api/v1/versions/a1", http.StatusOK, &versions)
+	if versions.ComponentId != "a1" || len(versions.Versions) != 2 {
+		t.Fatalf("unexpected versions %+v", versions)
+	}
+
+	var e ErrorResponse
+	get(t, srv, "/api/v1/versions/zz", http.StatusNotFound, &e)
+	get(t, srv, "/api/v1/lag", http.StatusBadRequest, &e)
+}
+
+func TestOpenapiDescribesRoutes(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	var doc struct {
+		Paths map[string]map[string]any `json:"paths"`
+	}
+	get(t, srv, "/openapi.json", http.StatusOK, &doc)
+
+	for _, r := range routes {
+		method, path, _ := strings.Cut(r.pattern, " ")
+		if _, ok := doc.Paths[path][strings.ToLower(method)]; !ok {
+			t.Fatalf("route %s is missing in openapi.json", r.pattern)
+		}
+	}
+	if len(doc.Paths) != len(routes) {
+		t.Fatalf("openapi.json describes %d paths, %d routes are served", len(doc.Paths), len(routes))
+	}
+}
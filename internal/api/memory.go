@@ -0,0 +1,131 @@
+package api
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"strings"
+
+	"sbom-processor/internal/lag"
+	"sbom-processor/internal/sbom"
+	"sbom-processor/internal/semver"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// MemoryStore serves SBOMs held in memory, e.g., for tests or
+// to serve a directory of SBOM files without a database.
+type MemoryStore struct {
+	StoredSboms       []sbom.StoredSbom
+	Lags              []lag.SbomLag
+	ComponentVersions []semver.ComponentVersions
+}
+
+// SBOMs ordered by their id like in the database
+func (m *MemoryStore) sorted() []*sbom.StoredSbom {
+	res := make([]*sbom.StoredSbom, len(m.StoredSboms))
+	for i := range m.StoredSboms {
+		res[i] = &m.StoredSboms[i]
+	}
+	slices.SortFunc(res, func(a, b *sbom.StoredSbom) int {
+		return strings.Compare(a.Id.Hex(), b.Id.Hex())
+	})
+	return res
+}
+
+func (m *MemoryStore) Sboms(ctx context.Context, filter SbomFilter, page Page) ([]SbomSummary, int, error) {
+	var res []SbomSummary
+	for _, s := range m.sorted() {
+		if matchesSource(s.Source, filter.Source) {
+			res = append(res, summarize(s))
+		}
+	}
+	return paginate(res, page), len(res), nil
+}
+
+func (m *MemoryStore) Sbom(ctx context.Context, id bson.ObjectID) (*sbom.StoredSbom, error) {
+	for i := range m.StoredSboms {
+		if m.StoredSboms[i].Id == id {
+			return &m.StoredSboms[i], nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *MemoryStore) Components(ctx context.Context, filter ComponentFilter, page Page) ([]ComponentSummary, int, error) {
+	type key struct{ name, typ, version string }
+	counts := make(map[key]*ComponentSummary)
+
+	for _, s := range m.StoredSboms {
+		// count every SBOM once per component version
+		seen := make(map[key]bool)
+		for i := range s.Components {
+			c := &s.Components[i]
+			k := key{c.Name, c.Type, c.Version}
+			if seen[k] || !filter.matches(c) {
+				continue
+			}
+			seen[k] = true
+
+			if counts[k] == nil {
+				counts[k] = &ComponentSummary{Name: c.Name, Type: c.Type, Version: c.Version, Purl: c.Purl}
+			}
+			counts[k].Sboms += 1
+		}
+	}
+
+	res := make([]ComponentSummary, 0, len(counts))
+	for _, c := range counts {
+		res = append(res, *c)
+	}
+	slices.SortFunc(res, compareComponents)
+
+	return paginate(res, page), len(res), nil
+}
+
+func (m *MemoryStore) Images(ctx context.Context, filter ComponentFilter, page Page) ([]SbomSummary, int, error) {
+	var res []SbomSummary
+	for _, s := range m.sorted() {
+		if slices.ContainsFunc(s.Components, func(c sbom.Component) bool { return filter.matches(&c) }) {
+			res = append(res, summarize(s))
+		}
+	}
+	return paginate(res, page), len(res), nil
+}
+
+func (m *MemoryStore) Lag(ctx context.Context, name string, componentType string) ([]lag.ComponentLag, error) {
+	// components shared by several SBOMs are listed once
+	var res []lag.ComponentLag
+	for _, l := range m.Lags {
+		for _, c := range l.Components {
+			if c.Name != name || (componentType != "" && c.Type != componentType) ||
+				slices.ContainsFunc(res, func(r lag.ComponentLag) bool { return reflect.DeepEqual(r, c) }) {
+				continue
+			}
+			res = append(res, c)
+		}
+	}
+
+	slices.SortFunc(res, compareLag)
+	return res, nil
+}
+
+func (m *MemoryStore) Versions(ctx context.Context, componentId string) (*semver.ComponentVersions, error) {
+	for i := range m.ComponentVersions {
+		if m.ComponentVersions[i].ComponentId == componentId {
+			return &m.ComponentVersions[i], nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// lags are ordered by type, version, and component id
+func compareLag(a, b lag.ComponentLag) int {
+	if c := strings.Compare(a.Type, b.Type); c != 0 {
+		return c
+	}
+	if c := strings.Compare(a.Version, b.Version); c != 0 {
+		return c
+	}
+	return strings.Compare(a.ComponentId, b.ComponentId)
+}
@@ -0,0 +1,327 @@
+package api
+
+import (
+	"context"
+	"slices"
+
+	"sbom-processor/internal/catalogue"
+	"sbom-processor/internal/lag"
+	"sbom-processor/internal/sbom"
+	"sbom-processor/internal/semver"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// MongoStore reads from the collections written by the other commands
+type MongoStore struct {
+	// sbom.StoredSbom in the embedded or the normalized layout
+	SbomStore *catalogue.Store
+	// lag.SbomLag
+	LagCollection *mongo.Collection
+	// semver.ComponentVersions keyed by the component id
+	VersionCollection *mongo.Collection
+}
+
+// projection of an SBOM to its summary, normalized SBOMs that
+// aren't rehydrated are counted by their refs
+var summaryProjection = bson.D{
+	{Key: "source", Value: 1},
+	{Key: "distro", Value: 1},
+	{Key: "components", Value: bson.D{{Key: "$size", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$components", "$refs", bson.A{}}}}}}},
+}
+
+func sourceFilter(source string) bson.D {
+	if source == "" {
+		return bson.D{}
+	}
+	return bson.D{{Key: "$or", Value: bson.A{
+		bson.D{{Key: "source.id", Value: source}},
+		bson.D{{Key: "source.name", Value: source}},
+		bson.D{{Key: "source.version", Value: source}},
+	}}}
+}
+
+// name, type, and version are matched in the database, the
+// version range is applied afterwards
+func componentMatch(f ComponentFilter) bson.D {
+	match := bson.D{}
+	if f.Name != "" {
+		match = append(match, bson.E{Key: "name", Value: f.Name})
+	}
+	if f.Type != "" {
+		match = append(match, bson.E{Key: "type", Value: f.Type})
+	}
+	if f.Version != "" {
+		match = append(match, bson.E{Key: "version", Value: f.Version})
+	}
+	return match
+}
+
+func (f *ComponentFilter) hasRange() bool {
+	return f.MinVersion != "" || f.MaxVersion != ""
+}
+
+// runs pipeline on the SBOMs. normalized SBOMs are rehydrated
+// first if the stages read the components.
+func (m *MongoStore) aggregate(ctx context.Context, pipeline mongo.Pipeline, components bool) (*mongo.Cursor, error) {
+	opts := options.Aggregate().SetAllowDiskUse(true)
+	if components {
+		return m.SbomStore.Aggregate(ctx, pipeline, opts)
+	}
+	return m.SbomStore.Sboms.Aggregate(ctx, pipeline, opts)
+}
+
+// stage returning the page of the sorted results together with the
+// total number of results, so that a list is read in a single pass.
+// stages are applied to the results of the page.
+func paged(sort bson.D, page Page, stages ...bson.D) bson.D {
+	items := bson.A{
+		bson.D{{Key: "$sort", Value: sort}},
+		bson.D{{Key: "$skip", Value: page.Offset}},
+		bson.D{{Key: "$limit", Value: page.Limit}},
+	}
+	for _, s := range stages {
+		items = append(items, s)
+	}
+
+	return bson.D{{Key: "$facet", Value: bson.D{
+		{Key: "items", Value: items},
+		{Key: "total", Value: bson.A{bson.D{{Key: "$count", Value: "total"}}}},
+	}}}
+}
+
+// runs a pipeline ending with the paged stage
+func aggregatePage[T any](ctx context.Context, m *MongoStore, pipeline mongo.Pipeline, components bool) ([]T, int, error) {
+	cursor, err := m.aggregate(ctx, pipeline, components)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var res []struct {
+		Items []T `bson:"items"`
+		Total []struct {
+			Total int `bson:"total"`
+		} `bson:"total"`
+	}
+	if err := cursor.All(ctx, &res); err != nil {
+		return nil, 0, err
+	}
+
+	items := []T{}
+	if len(res) == 0 || len(res[0].Total) == 0 {
+		return items, 0, nil
+	}
+	return append(items, res[0].Items...), res[0].Total[0].Total, nil
+}
+
+// summaries of the SBOMs matching filter, components tells whether
+// filter reads the components
+func (m *MongoStore) summaries(ctx context.Context, filter bson.D, components bool, page Page) ([]SbomSummary, int, error) {
+	return aggregatePage[SbomSummary](ctx, m, summariesPipeline(filter, page), components)
+}
+
+func summariesPipeline(filter bson.D, page Page) mongo.Pipeline {
+	return mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		paged(bson.D{{Key: "_id", Value: 1}}, page, bson.D{{Key: "$project", Value: summaryProjection}}),
+	}
+}
+
+func (m *MongoStore) Sboms(ctx context.Context, filter SbomFilter, page Page) ([]SbomSummary, int, error) {
+	return m.summaries(ctx, sourceFilter(filter.Source), false, page)
+}
+
+func (m *MongoStore) Sbom(ctx context.Context, id bson.ObjectID) (*sbom.StoredSbom, error) {
+	res, err := m.SbomStore.Sbom(ctx, id)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (m *MongoStore) Components(ctx context.Context, filter ComponentFilter, page Page) ([]ComponentSummary, int, error) {
+	pipeline := componentsPipeline(filter, page)
+	if !filter.hasRange() {
+		return aggregatePage[ComponentSummary](ctx, m, pipeline, true)
+	}
+
+	cursor, err := m.aggregate(ctx, pipeline, true)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	res := []ComponentSummary{}
+	if err := cursor.All(ctx, &res); err != nil {
+		return nil, 0, err
+	}
+	if len(res) > MaxRangeResults {
+		return nil, 0, ErrTooManyResults
+	}
+
+	res = slices.DeleteFunc(res, func(c ComponentSummary) bool { return !filter.inRange(c.Type, c.Version) })
+	slices.SortFunc(res, compareComponents)
+	return paginate(res, page), len(res), nil
+}
+
+// distinct component versions matching the filter. only SBOMs that
+// contain a matching component are unwound. without a version range
+// the page is selected in the database, otherwise at most one
+// component version more than MaxRangeResults is loaded.
+func componentsPipeline(f ComponentFilter, page Page) mongo.Pipeline {
+	match := componentMatch(f)
+
+	pipeline := mongo.Pipeline{}
+	if len(match) > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: bson.D{{Key: "components", Value: bson.D{{Key: "$elemMatch", Value: match}}}}}})
+	}
+	pipeline = append(pipeline,
+		bson.D{{Key: "$unwind", Value: "$components"}},
+		bson.D{{Key: "$replaceRoot", Value: bson.D{{Key: "newRoot", Value: bson.D{
+			{Key: "sbom", Value: "$_id"},
+			{Key: "name", Value: "$components.name"},
+			{Key: "type", Value: "$components.type"},
+			{Key: "version", Value: "$components.version"},
+			{Key: "purl", Value: "$components.purl"},
+		}}}}},
+	)
+	if len(match) > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: match}})
+	}
+	pipeline = append(pipeline,
+		bson.D{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: bson.D{{Key: "name", Value: "$name"}, {Key: "type", Value: "$type"}, {Key: "version", Value: "$version"}}},
+			{Key: "purl", Value: bson.D{{Key: "$first", Value: "$purl"}}},
+			{Key: "sboms", Value: bson.D{{Key: "$addToSet", Value: "$sbom"}}},
+		}}},
+		bson.D{{Key: "$project", Value: bson.D{
+			{Key: "_id", Value: 0},
+			{Key: "name", Value: "$_id.name"},
+			{Key: "type", Value: "$_id.type"},
+			{Key: "version", Value: "$_id.version"},
+			{Key: "purl", Value: 1},
+			{Key: "sboms", Value: bson.D{{Key: "$size", Value: "$sboms"}}},
+		}}},
+	)
+
+	if f.hasRange() {
+		return append(pipeline, bson.D{{Key: "$limit", Value: MaxRangeResults + 1}})
+	}
+	return append(pipeline, paged(bson.D{{Key: "name", Value: 1}, {Key: "type", Value: 1}, {Key: "version", Value: 1}}, page))
+}
+
+func (m *MongoStore) Images(ctx context.Context, filter ComponentFilter, page Page) ([]SbomSummary, int, error) {
+	if !filter.hasRange() {
+		return m.summaries(ctx, imagesMatch(filter), true, page)
+	}
+
+	cursor, err := m.aggregate(ctx, imagesRangePipeline(filter), true)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	res := []SbomSummary{}
+	for cursor.Next(ctx) {
+		var s struct {
+			SbomSummary `bson:",inline"`
+			Versions    []struct {
+				Type    string `bson:"type"`
+				Version string `bson:"version"`
+			} `bson:"versions"`
+		}
+		if err := cursor.Decode(&s); err != nil {
+			return nil, 0, err
+		}
+		for _, v := range s.Versions {
+			if filter.inRange(v.Type, v.Version) {
+				res = append(res, s.SbomSummary)
+				break
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return paginate(res, page), len(res), nil
+}
+
+func imagesMatch(f ComponentFilter) bson.D {
+	return bson.D{{Key: "components", Value: bson.D{{Key: "$elemMatch", Value: componentMatch(f)}}}}
+}
+
+// the type and version of the matching components decide whether
+// an SBOM is in range, only they are loaded
+func imagesRangePipeline(f ComponentFilter) mongo.Pipeline {
+	projection := append(slices.Clone(summaryProjection), bson.E{Key: "versions", Value: bson.D{{Key: "$map", Value: bson.D{
+		{Key: "input", Value: bson.D{{Key: "$filter", Value: bson.D{
+			{Key: "input", Value: "$components"},
+			{Key: "cond", Value: elemConditions(componentMatch(f))},
+		}}}},
+		{Key: "in", Value: bson.D{{Key: "type", Value: "$$this.type"}, {Key: "version", Value: "$$this.version"}}},
+	}}}})
+
+	return mongo.Pipeline{
+		{{Key: "$match", Value: imagesMatch(f)}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+		{{Key: "$project", Value: projection}},
+	}
+}
+
+// the match of componentMatch as aggregation expression on $$this
+func elemConditions(match bson.D) bson.D {
+	conditions := bson.A{}
+	for _, e := range match {
+		conditions = append(conditions, bson.D{{Key: "$eq", Value: bson.A{"$$this." + e.Key, e.Value}}})
+	}
+	return bson.D{{Key: "$and", Value: conditions}}
+}
+
+func (m *MongoStore) Lag(ctx context.Context, name string, componentType string) ([]lag.ComponentLag, error) {
+	cursor, err := m.LagCollection.Aggregate(ctx, lagPipeline(name, componentType), options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return nil, err
+	}
+
+	res := []lag.ComponentLag{}
+	if err := cursor.All(ctx, &res); err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(res, compareLag)
+	return res, nil
+}
+
+func lagPipeline(name string, componentType string) mongo.Pipeline {
+	match := componentMatch(ComponentFilter{Name: name, Type: componentType})
+	elemMatch := bson.D{}
+	for _, e := range match {
+		elemMatch = append(elemMatch, bson.E{Key: "components." + e.Key, Value: e.Value})
+	}
+
+	return mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{{Key: "components", Value: bson.D{{Key: "$elemMatch", Value: match}}}}}},
+		{{Key: "$unwind", Value: "$components"}},
+		{{Key: "$match", Value: elemMatch}},
+		// components shared by several SBOMs are listed once
+		{{Key: "$group", Value: bson.D{{Key: "_id", Value: "$components"}}}},
+		{{Key: "$replaceRoot", Value: bson.D{{Key: "newRoot", Value: "$_id"}}}},
+	}
+}
+
+func (m *MongoStore) Versions(ctx context.Context, componentId string) (*semver.ComponentVersions, error) {
+	var res semver.ComponentVersions
+	err := m.VersionCollection.FindOne(ctx, bson.D{{Key: "component_id", Value: componentId}}).Decode(&res)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"slices"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// names of the stages of the pipeline
+func stages(p mongo.Pipeline) []string {
+	res := make([]string, len(p))
+	for i, s := range p {
+		res[i] = s[0].Key
+	}
+	return res
+}
+
+// value of the key in d, nil if missing
+func field(d bson.D, key string) any {
+	for _, e := range d {
+		if e.Key == key {
+			return e.Value
+		}
+	}
+	return nil
+}
+
+func TestComponentsPipeline(t *testing.T) {
+	page := Page{Limit: 10, Offset: 20}
+	p := componentsPipeline(ComponentFilter{Name: "openssl"}, page)
+
+	// only SBOMs containing the component are unwound
+	expected := []string{"$match", "$unwind", "$replaceRoot", "$match", "$group", "$project", "$facet"}
+	if !slices.Equal(stages(p), expected) {
+		t.Fatalf("unexpected stages %v", stages(p))
+	}
+	if field(p[0][0].Value.(bson.D), "components") == nil {
+		t.Fatalf("the first stage must match the components %v", p[0])
+	}
+
+	// count and page are read in a single pass
+	facet := p[len(p)-1][0].Value.(bson.D)
+	items := field(facet, "items").(bson.A)
+	if field(items[1].(bson.D), "$skip") != 20 || field(items[2].(bson.D), "$limit") != 10 {
+		t.Fatalf("unexpected page %v", items)
+	}
+	if total := field(facet, "total").(bson.A); field(total[0].(bson.D), "$count") != "total" {
+		t.Fatalf("unexpected total %v", total)
+	}
+
+	// the range is applied to at most one result more than the cap
+	ranged := componentsPipeline(ComponentFilter{Type: "deb", MinVersion: "1:2.0"}, page)
+	last := ranged[len(ranged)-1]
+	if last[0].Key != "$limit" || last[0].Value != MaxRangeResults+1 {
+		t.Fatalf("range searches must be capped, got %v", last)
+	}
+	if slices.Contains(stages(ranged), "$facet") {
+		t.Fatalf("the page of range searches is selected after the range")
+	}
+}
+
+func TestImagesPipeline(t *testing.T) {
+	p := summariesPipeline(imagesMatch(ComponentFilter{Name: "openssl", Version: "3.0.11-1"}), Page{Limit: 5})
+	if !slices.Equal(stages(p), []string{"$match", "$facet"}) {
+		t.Fatalf("unexpected stages %v", stages(p))
+	}
+	items := field(p[1][0].Value.(bson.D), "items").(bson.A)
+	if last := items[len(items)-1].(bson.D); last[0].Key != "$project" {
+		t.Fatalf("only the page must be projected, got %v", items)
+	}
+
+	ranged := imagesRangePipeline(ComponentFilter{Name: "openssl", MaxVersion: "3.0"})
+	if !slices.Equal(stages(ranged), []string{"$match", "$sort", "$project"}) {
+		t.Fatalf("unexpected stages %v", stages(ranged))
+	}
+	// the type selects the version order of the range
+	versions := field(ranged[2][0].Value.(bson.D), "versions").(bson.D)
+	in := field(versions[0].Value.(bson.D), "in").(bson.D)
+	if field(in, "type") != "$$this.type" || field(in, "version") != "$$this.version" {
+		t.Fatalf("type and version of the components expected, got %v", in)
+	}
+}
+
+func TestLagPipeline(t *testing.T) {
+	p := lagPipeline("openssl", "deb")
+
+	// one document per SBOM, there is nothing to order by computation
+	if !slices.Equal(stages(p), []string{"$match", "$unwind", "$match", "$group", "$replaceRoot"}) {
+		t.Fatalf("unexpected stages %v", stages(p))
+	}
+	elemMatch := p[2][0].Value.(bson.D)
+	if field(elemMatch, "components.name") != "openssl" || field(elemMatch, "components.type") != "deb" {
+		t.Fatalf("unexpected component match %v", elemMatch)
+	}
+}
@@ -0,0 +1,266 @@
+package api
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"sbom-processor/internal/lag"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const (
+	DefaultLimit = 50
+	MaxLimit     = 1000
+)
+
+//go:embed openapi.json
+var openapi []byte
+
+// ListResponse is a page of a list endpoint
+type ListResponse[T any] struct {
+	Items  []T `json:"items"`
+	Total  int `json:"total"`
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+}
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+type route struct {
+	pattern string
+	handler func(s *server, w http.ResponseWriter, r *http.Request) error
+}
+
+// all routes, every path is described in openapi.json
+var routes = []route{
+	{"GET /api/v1/sboms", (*server).sboms},
+	{"GET /api/v1/sboms/{id}", (*server).sbom},
+	{"GET /api/v1/components", (*server).components},
+	{"GET /api/v1/images", (*server).images},
+	{"GET /api/v1/lag", (*server).lag},
+	{"GET /api/v1/versions/{componentId}", (*server).versions},
+}
+
+type server struct {
+	store  Store
+	logger *slog.Logger
+}
+
+// badRequest is returned by handlers for invalid parameters
+type badRequest struct {
+	msg string
+}
+
+func (e *badRequest) Error() string {
+	return e.msg
+}
+
+func invalid(format string, args ...any) error {
+	return &badRequest{msg: fmt.Sprintf(format, args...)}
+}
+
+// NewHandler serves the read-only API on top of store
+func NewHandler(store Store, logger *slog.Logger) http.Handler {
+	s := &server{store: store, logger: logger}
+
+	mux := http.NewServeMux()
+	for _, r := range routes {
+		handler := r.handler
+		mux.HandleFunc(r.pattern, func(w http.ResponseWriter, req *http.Request) {
+			if err := handler(s, w, req); err != nil {
+				s.writeError(w, req, err)
+			}
+		})
+	}
+	mux.HandleFunc("GET /openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(openapi)
+	})
+
+	return mux
+}
+
+func (s *server) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var bad *badRequest
+	status := http.StatusInternalServerError
+	msg := "internal error"
+	switch {
+	case errors.As(err, &bad):
+		status = http.StatusBadRequest
+		msg = bad.msg
+	case errors.Is(err, ErrNotFound):
+		status = http.StatusNotFound
+		msg = err.Error()
+	case errors.Is(err, ErrTooManyResults):
+		status = http.StatusBadRequest
+		msg = err.Error()
+	default:
+		s.logger.Error("Request failed", "path", r.URL.Path, "err", err)
+	}
+
+	writeJson(w, status, ErrorResponse{Error: msg})
+}
+
+// the status is already sent when encoding fails, the error
+// can't be reported to the client anymore
+func writeJson(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// reads limit and offset, the limit defaults to DefaultLimit
+func page(r *http.Request) (Page, error) {
+	p := Page{Limit: DefaultLimit}
+	q := r.URL.Query()
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > MaxLimit {
+			return p, invalid("limit must be between 1 and %d", MaxLimit)
+		}
+		p.Limit = limit
+	}
+	if v := q.Get("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return p, invalid("offset must be a non-negative number")
+		}
+		p.Offset = offset
+	}
+
+	return p, nil
+}
+
+func list[T any](w http.ResponseWriter, items []T, total int, p Page) {
+	if items == nil {
+		items = []T{}
+	}
+	writeJson(w, http.StatusOK, ListResponse[T]{Items: items, Total: total, Limit: p.Limit, Offset: p.Offset})
+}
+
+func componentFilter(r *http.Request) (ComponentFilter, error) {
+	q := r.URL.Query()
+	f := ComponentFilter{
+		Name:       q.Get("name"),
+		Type:       q.Get("type"),
+		Version:    q.Get("version"),
+		MinVersion: q.Get("minVersion"),
+		MaxVersion: q.Get("maxVersion"),
+	}
+
+	for _, bound := range []string{f.MinVersion, f.MaxVersion} {
+		if bound != "" && !validBound(bound, f.Type) {
+			return f, invalid("invalid version bound %s", bound)
+		}
+	}
+	return f, nil
+}
+
+func (s *server) sboms(w http.ResponseWriter, r *http.Request) error {
+	p, err := page(r)
+	if err != nil {
+		return err
+	}
+
+	items, total, err := s.store.Sboms(r.Context(), SbomFilter{Source: r.URL.Query().Get("source")}, p)
+	if err != nil {
+		return err
+	}
+	list(w, items, total, p)
+	return nil
+}
+
+func (s *server) sbom(w http.ResponseWriter, r *http.Request) error {
+	id, err := bson.ObjectIDFromHex(r.PathValue("id"))
+	if err != nil {
+		return invalid("invalid SBOM id %s", r.PathValue("id"))
+	}
+
+	res, err := s.store.Sbom(r.Context(), id)
+	if err != nil {
+		return err
+	}
+	writeJson(w, http.StatusOK, res)
+	return nil
+}
+
+func (s *server) components(w http.ResponseWriter, r *http.Request) error {
+	p, err := page(r)
+	if err != nil {
+		return err
+	}
+
+	filter, err := componentFilter(r)
+	if err != nil {
+		return err
+	}
+	// searching all components unwinds the whole corpus
+	if filter.Name == "" && filter.Type == "" {
+		return invalid("name or type is required")
+	}
+
+	items, total, err := s.store.Components(r.Context(), filter, p)
+	if err != nil {
+		return err
+	}
+	list(w, items, total, p)
+	return nil
+}
+
+// reverse lookup of the SBOMs containing a component
+func (s *server) images(w http.ResponseWriter, r *http.Request) error {
+	p, err := page(r)
+	if err != nil {
+		return err
+	}
+
+	filter, err := componentFilter(r)
+	if err != nil {
+		return err
+	}
+	if filter.Name == "" {
+		return invalid("name is required")
+	}
+
+	items, total, err := s.store.Images(r.Context(), filter, p)
+	if err != nil {
+		return err
+	}
+	list(w, items, total, p)
+	return nil
+}
+
+func (s *server) lag(w http.ResponseWriter, r *http.Request) error {
+	q := r.URL.Query()
+	if q.Get("name") == "" {
+		return invalid("name is required")
+	}
+
+	res, err := s.store.Lag(r.Context(), q.Get("name"), q.Get("type"))
+	if err != nil {
+		return err
+	}
+	if res == nil {
+		res = []lag.ComponentLag{}
+	}
+	writeJson(w, http.StatusOK, res)
+	return nil
+}
+
+func (s *server) versions(w http.ResponseWriter, r *http.Request) error {
+	res, err := s.store.Versions(r.Context(), r.PathValue("componentId"))
+	if err != nil {
+		return err
+	}
+	writeJson(w, http.StatusOK, res)
+	return nil
+}
@@ -0,0 +1,160 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"sbom-processor/internal/lag"
+	"sbom-processor/internal/sbom"
+	"sbom-processor/internal/semver"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+var ErrNotFound = errors.New("not found")
+
+// MaxRangeResults caps the component versions a version range search
+// loads, the range is applied after loading them
+const MaxRangeResults = 10000
+
+var ErrTooManyResults = fmt.Errorf("more than %d component versions match, narrow the search by name or type", MaxRangeResults)
+
+// Page selects a window of a result, results have a stable order
+type Page struct {
+	Limit  int
+	Offset int
+}
+
+// SbomSummary describes a stored SBOM without its components
+type SbomSummary struct {
+	Id         bson.ObjectID `bson:"_id" json:"id"`
+	Source     sbom.Source   `bson:"source" json:"source"`
+	Distro     sbom.Distro   `bson:"distro" json:"distro"`
+	Components int           `bson:"components" json:"components"`
+}
+
+type SbomFilter struct {
+	// matches the source id, name, or version. all SBOMs if empty.
+	Source string
+}
+
+// ComponentSummary is a distinct component version and the number
+// of SBOMs containing it
+type ComponentSummary struct {
+	Name    string `bson:"name" json:"name"`
+	Type    string `bson:"type" json:"type"`
+	Version string `bson:"version" json:"version"`
+	Purl    string `bson:"purl,omitempty" json:"purl,omitempty"`
+	Sboms   int    `bson:"sboms" json:"sboms"`
+}
+
+type ComponentFilter struct {
+	Name    string
+	Type    string
+	Version string
+	// inclusive version range in the version order of the component
+	// type, versions that can't be compared are excluded if a bound
+	// is set
+	MinVersion string
+	MaxVersion string
+}
+
+// Store provides read access to the SBOM database. List methods
+// return the requested page and the total number of results.
+type Store interface {
+	Sboms(ctx context.Context, filter SbomFilter, page Page) ([]SbomSummary, int, error)
+	// returns ErrNotFound if there is no SBOM with the id
+	Sbom(ctx context.Context, id bson.ObjectID) (*sbom.StoredSbom, error)
+	// returns ErrTooManyResults if a version range search matches
+	// more than MaxRangeResults component versions
+	Components(ctx context.Context, filter ComponentFilter, page Page) ([]ComponentSummary, int, error)
+	// SBOMs containing a component, the version range of the filter is applied
+	Images(ctx context.Context, filter ComponentFilter, page Page) ([]SbomSummary, int, error)
+	// latest technical lag of every component with the name and type
+	Lag(ctx context.Context, name string, componentType string) ([]lag.ComponentLag, error)
+	// returns ErrNotFound if no versions are stored for the component
+	Versions(ctx context.Context, componentId string) (*semver.ComponentVersions, error)
+}
+
+func matchesSource(s sbom.Source, source string) bool {
+	return source == "" || s.Id == source || s.Name == source || s.Version == source
+}
+
+// matches name, type, version, and the version range. fields are
+// ignored if empty.
+func (f *ComponentFilter) matches(c *sbom.Component) bool {
+	if f.Name != "" && c.Name != f.Name {
+		return false
+	}
+	if f.Type != "" && c.Type != f.Type {
+		return false
+	}
+	if f.Version != "" && c.Version != f.Version {
+		return false
+	}
+	return f.inRange(c.Type, c.Version)
+}
+
+// versions are compared in the order of the component type, e.g.,
+// 1:2.3-4 of a deb package with the dpkg order
+func (f *ComponentFilter) inRange(componentType string, version string) bool {
+	compare := semver.ForComponentType(componentType)
+	if f.MinVersion != "" {
+		if cmp, err := compare(version, f.MinVersion); err != nil || cmp < 0 {
+			return false
+		}
+	}
+	if f.MaxVersion != "" {
+		if cmp, err := compare(version, f.MaxVersion); err != nil || cmp > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// component types with their own version order, the empty type
+// stands for the relaxed semver order of all other types
+var versionOrders = []string{"", "deb", "apk"}
+
+// reports whether bound can be compared in the version order of the
+// component type. without type any version order may apply.
+func validBound(bound string, componentType string) bool {
+	types := versionOrders
+	if componentType != "" {
+		types = []string{componentType}
+	}
+	return slices.ContainsFunc(types, func(t string) bool {
+		_, err := semver.ForComponentType(t)(bound, bound)
+		return err == nil
+	})
+}
+
+// components are ordered by name, type, and version
+func compareComponents(a, b ComponentSummary) int {
+	if c := strings.Compare(a.Name, b.Name); c != 0 {
+		return c
+	}
+	if c := strings.Compare(a.Type, b.Type); c != 0 {
+		return c
+	}
+	return strings.Compare(a.Version, b.Version)
+}
+
+func summarize(s *sbom.StoredSbom) SbomSummary {
+	return SbomSummary{
+		Id:         s.Id,
+		Source:     s.Source,
+		Distro:     s.Distro,
+		Components: len(s.Components),
+	}
+}
+
+// returns the elements of the page
+func paginate[T any](elems []T, page Page) []T {
+	start := min(page.Offset, len(elems))
+	end := min(start+page.Limit, len(elems))
+	return slices.Clip(elems[start:end])
+}
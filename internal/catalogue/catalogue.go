@@ -0,0 +1,132 @@
+package catalogue
+
+import (
+	"fmt"
+
+	"sbom-processor/internal/sbom"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Entry of the component catalogue. It contains the attributes
+// that are the same in every image, the per-image attributes are
+// kept in the Refs of the SBOMs.
+type Entry struct {
+	Key      string         `bson:"_id,omitempty" json:"key"`
+	Name     string         `bson:"name" json:"name"`
+	Type     string         `bson:"type" json:"type"`
+	Language string         `bson:"language" json:"language"`
+	Version  string         `bson:"version" json:"version"`
+	Purl     string         `bson:"purl,omitempty" json:"purl,omitempty"`
+	Licenses []sbom.License `bson:"licenses,omitempty" json:"licenses,omitempty"`
+}
+
+// Ref to a catalogue entry with the attributes of the component
+// in a single image, i.e., the syft id the dependencies refer to
+// and the digests of the files
+type Ref struct {
+	Key      string                  `bson:"key" json:"key"`
+	Id       string                  `bson:"id" json:"id"`
+	Metadata *sbom.ComponentMetadata `bson:"metadata,omitempty" json:"metadata,omitempty"`
+}
+
+// Sbom in the normalized layout, the fields besides the
+// components are stored as in the embedded layout
+type Sbom struct {
+	Id           bson.ObjectID     `bson:"_id,omitempty" json:"id"`
+	Refs         []Ref             `bson:"refs" json:"refs"`
+	Dependencies []sbom.Dependency `bson:"dependencies" json:"dependencies"`
+	Source       sbom.Source       `bson:"source" json:"source"`
+	Distro       sbom.Distro       `bson:"distro" json:"distro"`
+	Digest       string            `bson:"digest,omitempty" json:"digest,omitempty"`
+}
+
+// Normalized SBOM together with the catalogue entries it refers to
+type Normalized struct {
+	Sbom    Sbom
+	Entries []Entry
+}
+
+// Key identifies a component in the catalogue. The purl is used if
+// present, name, version, and type otherwise.
+func Key(c *sbom.Component) string {
+	if c.Purl != "" {
+		return c.Purl
+	}
+	// purls start with pkg:, so the keys can't collide
+	return c.Type + "/" + c.Name + "@" + c.Version
+}
+
+// Normalize splits the components of s into references and
+// catalogue entries. Components sharing a key, e.g., the same
+// package at different locations, result in a single entry.
+func Normalize(s *sbom.CyclonedxSbom) *Normalized {
+	res := &Normalized{
+		Sbom: Sbom{
+			Refs:         make([]Ref, len(s.Components)),
+			Dependencies: s.Dependencies,
+			Source:       s.Source,
+			Distro:       s.Distro,
+			Digest:       s.Digest,
+		},
+	}
+
+	seen := make(map[string]bool, len(s.Components))
+	for i, c := range s.Components {
+		key := Key(&c)
+		res.Sbom.Refs[i] = Ref{
+			Key:      key,
+			Id:       c.Id,
+			Metadata: c.Metadata,
+		}
+
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		res.Entries = append(res.Entries, Entry{
+			Key:      key,
+			Name:     c.Name,
+			Type:     c.Type,
+			Language: c.Language,
+			Version:  c.Version,
+			Purl:     c.Purl,
+			Licenses: c.Licenses,
+		})
+	}
+
+	return res
+}
+
+// Rehydrate restores the embedded layout of s from the catalogue
+// entries keyed by their Key
+func Rehydrate(s *Sbom, entries map[string]Entry) (*sbom.StoredSbom, error) {
+	components := make([]sbom.Component, len(s.Refs))
+	for i, r := range s.Refs {
+		e, ok := entries[r.Key]
+		if !ok {
+			return nil, fmt.Errorf("component %s of sbom %s not in the catalogue", r.Key, s.Id.Hex())
+		}
+		components[i] = sbom.Component{
+			Name:     e.Name,
+			Type:     e.Type,
+			Id:       r.Id,
+			Language: e.Language,
+			Version:  e.Version,
+			Purl:     e.Purl,
+			Metadata: r.Metadata,
+			Licenses: e.Licenses,
+		}
+	}
+
+	return &sbom.StoredSbom{
+		Id: s.Id,
+		CyclonedxSbom: sbom.CyclonedxSbom{
+			Components:   components,
+			Dependencies: s.Dependencies,
+			Source:       s.Source,
+			Distro:       s.Distro,
+			Digest:       s.Digest,
+		},
+	}, nil
+}
@@ -0,0 +1,76 @@
+package catalogue
+
+import (
+	"reflect"
+	"testing"
+
+	"sbom-processor/internal/sbom"
+)
+
+func testSbom() *sbom.CyclonedxSbom {
+	return &sbom.CyclonedxSbom{
+		Components: []sbom.Component{
+			{Name: "openssl", Type: "deb", Id: "a", Version: "3.0.11", Purl: "pkg:deb/debian/openssl@3.0.11",
+				Licenses: []sbom.License{{Value: "Apache-2.0", SpdxExpression: "Apache-2.0"}}},
+			{Name: "guava", Type: "java-archive", Id: "b", Language: "java", Version: "32.0.0",
+				Metadata: &sbom.ComponentMetadata{Digest: []sbom.Digest{{Algorithm: "sha1", Value: "abc"}}}},
+			// the same jar at another location
+			{Name: "guava", Type: "java-archive", Id: "c", Language: "java", Version: "32.0.0",
+				Metadata: &sbom.ComponentMetadata{Digest: []sbom.Digest{{Algorithm: "sha1", Value: "def"}}}},
+		},
+		Dependencies: []sbom.Dependency{{Ref: "a", DependsOn: []sbom.Target{{Child: "b", Type: "contains"}}}},
+		Source:       sbom.Source{Id: "src", Name: "debian:12"},
+		Distro:       sbom.Distro{Id: "debian", Version: "12"},
+		Digest:       "sha256:content",
+	}
+}
+
+func TestKey(t *testing.T) {
+	s := testSbom()
+	if k := Key(&s.Components[0]); k != "pkg:deb/debian/openssl@3.0.11" {
+		t.Fatalf("expected the purl as key, got %s", k)
+	}
+	if k := Key(&s.Components[1]); k != "java-archive/guava@32.0.0" {
+		t.Fatalf("unexpected key %s", k)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	n := Normalize(testSbom())
+
+	if len(n.Sbom.Refs) != 3 {
+		t.Fatalf("expected a ref per component, got %d", len(n.Sbom.Refs))
+	}
+	if len(n.Entries) != 2 {
+		t.Fatalf("expected 2 unique entries, got %+v", n.Entries)
+	}
+	if n.Sbom.Refs[2].Key != n.Sbom.Refs[1].Key || n.Sbom.Refs[2].Id != "c" {
+		t.Fatalf("unexpected ref %+v", n.Sbom.Refs[2])
+	}
+	if n.Sbom.Refs[2].Metadata.Digest[0].Value != "def" {
+		t.Fatalf("expected the digest to be kept per image")
+	}
+}
+
+func TestRehydrate(t *testing.T) {
+	s := testSbom()
+	n := Normalize(s)
+
+	entries := make(map[string]Entry)
+	for _, e := range n.Entries {
+		entries[e.Key] = e
+	}
+
+	res, err := Rehydrate(&n.Sbom, entries)
+	if err != nil {
+		t.Fatalf("rehydrate failed %s", err)
+	}
+	if !reflect.DeepEqual(res.CyclonedxSbom, *s) {
+		t.Fatalf("round trip changed the sbom\n%+v\n%+v", res.CyclonedxSbom, *s)
+	}
+
+	delete(entries, n.Sbom.Refs[0].Key)
+	if _, err := Rehydrate(&n.Sbom, entries); err == nil {
+		t.Fatalf("expected an error for a missing entry")
+	}
+}
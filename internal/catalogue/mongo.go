@@ -0,0 +1,290 @@
+package catalogue
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+
+	"sbom-processor/internal/sbom"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Store reads and writes SBOMs in the normalized layout. Reads
+// return the embedded layout and accept collections that contain
+// both layouts, e.g., while the migration is running.
+type Store struct {
+	// sbom.StoredSbom or Sbom
+	Sboms *mongo.Collection
+	// Entry keyed by the component key, nil if all SBOMs are embedded
+	Components *mongo.Collection
+}
+
+// Collection returns the catalogue collection, nil if name is empty
+func Collection(database *mongo.Database, name string) *mongo.Collection {
+	if name == "" {
+		return nil
+	}
+	return database.Collection(name)
+}
+
+// Insert stores new SBOMs and adds their components to the catalogue
+func (s *Store) Insert(ctx context.Context, docs []*Normalized) error {
+	if err := s.upsert(ctx, docs); err != nil {
+		return err
+	}
+
+	sboms := make([]Sbom, len(docs))
+	for i, d := range docs {
+		sboms[i] = d.Sbom
+	}
+	_, err := s.Sboms.InsertMany(ctx, sboms)
+	return err
+}
+
+// Replace converts SBOMs stored in the embedded layout in place.
+// SBOMs already converted are left unchanged.
+func (s *Store) Replace(ctx context.Context, docs []*Normalized) error {
+	if err := s.upsert(ctx, docs); err != nil {
+		return err
+	}
+
+	models := make([]mongo.WriteModel, len(docs))
+	for i, d := range docs {
+		models[i] = mongo.NewReplaceOneModel().
+			SetFilter(bson.D{
+				{Key: "_id", Value: d.Sbom.Id},
+				{Key: "refs", Value: bson.D{{Key: "$exists", Value: false}}},
+			}).
+			SetReplacement(d.Sbom)
+	}
+	_, err := s.Sboms.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+// entries are only inserted, the first image a component is
+// found in defines its catalogue attributes
+func (s *Store) upsert(ctx context.Context, docs []*Normalized) error {
+	seen := make(map[string]bool)
+	var models []mongo.WriteModel
+	for _, d := range docs {
+		for _, e := range d.Entries {
+			if seen[e.Key] {
+				continue
+			}
+			seen[e.Key] = true
+
+			key := e.Key
+			// _id is taken from the filter
+			e.Key = ""
+			models = append(models, mongo.NewUpdateOneModel().
+				SetFilter(bson.D{{Key: "_id", Value: key}}).
+				SetUpdate(bson.D{{Key: "$setOnInsert", Value: e}}).
+				SetUpsert(true))
+		}
+	}
+
+	if len(models) == 0 {
+		return nil
+	}
+	_, err := s.Components.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+// Pipeline rehydrates normalized SBOMs of the sboms collection from
+// the catalogue collection, SBOMs in the embedded layout are passed
+// through. The refs of a normalized SBOM are joined with their
+// catalogue entries one by one, which uses the _id index of the
+// catalogue and keeps the order of the refs.
+func Pipeline(sboms string, components string) mongo.Pipeline {
+	component := bson.D{
+		{Key: "name", Value: "$$c.name"},
+		{Key: "type", Value: "$$c.type"},
+		{Key: "id", Value: "$$r.id"},
+		{Key: "language", Value: "$$c.language"},
+		{Key: "version", Value: "$$c.version"},
+		{Key: "purl", Value: "$$c.purl"},
+		{Key: "metadata", Value: "$$r.metadata"},
+		{Key: "licenses", Value: "$$c.licenses"},
+	}
+
+	// runs on the SBOM itself, SBOMs without refs result in no components
+	rehydrate := mongo.Pipeline{
+		{{Key: "$unwind", Value: "$refs"}},
+		{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: components},
+			{Key: "localField", Value: "refs.key"},
+			{Key: "foreignField", Value: "_id"},
+			{Key: "as", Value: "entry"},
+		}}},
+		{{Key: "$replaceRoot", Value: bson.D{{Key: "newRoot", Value: bson.D{{Key: "$let", Value: bson.D{
+			{Key: "vars", Value: bson.D{
+				{Key: "c", Value: bson.D{{Key: "$first", Value: "$entry"}}},
+				{Key: "r", Value: "$refs"},
+			}},
+			{Key: "in", Value: component},
+		}}}}}}},
+	}
+
+	return mongo.Pipeline{
+		{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: sboms},
+			{Key: "localField", Value: "_id"},
+			{Key: "foreignField", Value: "_id"},
+			{Key: "pipeline", Value: rehydrate},
+			{Key: "as", Value: "rehydrated"},
+		}}},
+		{{Key: "$set", Value: bson.D{{Key: "components", Value: bson.D{{Key: "$cond", Value: bson.A{
+			bson.D{{Key: "$isArray", Value: "$refs"}}, "$rehydrated", "$components",
+		}}}}}}},
+		{{Key: "$unset", Value: bson.A{"refs", "rehydrated"}}},
+	}
+}
+
+// splits pipeline after the leading $match stages that don't read
+// the components. they can run before the rehydration and use the
+// indexes of the SBOMs.
+func hoist(pipeline mongo.Pipeline) (mongo.Pipeline, mongo.Pipeline) {
+	i := 0
+	for i < len(pipeline) && len(pipeline[i]) == 1 && pipeline[i][0].Key == "$match" && !readsComponents(pipeline[i][0].Value) {
+		i++
+	}
+	return pipeline[:i], pipeline[i:]
+}
+
+// reports whether a filter refers to the components field
+func readsComponents(v any) bool {
+	isComponents := func(path string) bool {
+		path = strings.TrimPrefix(path, "$")
+		return path == "components" || strings.HasPrefix(path, "components.")
+	}
+
+	switch v := v.(type) {
+	case bson.D:
+		return slices.ContainsFunc(v, func(e bson.E) bool { return isComponents(e.Key) || readsComponents(e.Value) })
+	case bson.M:
+		for k, e := range v {
+			if isComponents(k) || readsComponents(e) {
+				return true
+			}
+		}
+	case map[string]any:
+		return readsComponents(bson.M(v))
+	case bson.A:
+		return slices.ContainsFunc(v, readsComponents)
+	case []any:
+		return readsComponents(bson.A(v))
+	case string:
+		return isComponents(v)
+	}
+	return false
+}
+
+// Find returns a cursor over the SBOMs matching filter that
+// decodes to sbom.StoredSbom regardless of the layout
+func (s *Store) Find(ctx context.Context, filter any) (*mongo.Cursor, error) {
+	if s.Components == nil {
+		return s.Sboms.Find(ctx, filter)
+	}
+	return s.Aggregate(ctx, mongo.Pipeline{{{Key: "$match", Value: filter}}}, options.Aggregate().SetBatchSize(1000))
+}
+
+// Aggregate runs pipeline on the SBOMs in the embedded layout, i.e.,
+// normalized SBOMs are rehydrated before the first stage that may
+// read the components. Stages can therefore read the components
+// regardless of the layout.
+func (s *Store) Aggregate(ctx context.Context, pipeline mongo.Pipeline, opts ...options.Lister[options.AggregateOptions]) (*mongo.Cursor, error) {
+	if s.Components == nil {
+		return s.Sboms.Aggregate(ctx, pipeline, opts...)
+	}
+	return s.Sboms.Aggregate(ctx, rehydrated(pipeline, s.Sboms.Name(), s.Components.Name()), opts...)
+}
+
+// pipeline with the rehydration before the first stage that may
+// read the components
+func rehydrated(pipeline mongo.Pipeline, sboms string, components string) mongo.Pipeline {
+	before, after := hoist(pipeline)
+	return slices.Concat(before, Pipeline(sboms, components), after)
+}
+
+// UniqueNames groups the components of componentType of all SBOMs
+// by their name. Run it with Aggregate to include normalized SBOMs.
+func UniqueNames(componentType string) mongo.Pipeline {
+	return mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{{Key: "components.type", Value: componentType}}}},
+		{{Key: "$unwind", Value: "$components"}},
+		{{Key: "$match", Value: bson.D{{Key: "components.type", Value: componentType}}}},
+		{{Key: "$group", Value: bson.D{{Key: "_id", Value: "$components.name"}}}},
+	}
+}
+
+// ErrNormalized is returned by Verify if the collection contains
+// normalized SBOMs but the catalogue isn't set
+var ErrNormalized = errors.New("the collection contains normalized SBOMs, the component catalogue must be set")
+
+// Verify checks that all SBOMs can be read. Without the catalogue
+// normalized SBOMs would be read without components.
+func (s *Store) Verify(ctx context.Context) error {
+	if s.Components != nil {
+		return nil
+	}
+
+	err := s.Sboms.FindOne(ctx, bson.D{{Key: "refs", Value: bson.D{{Key: "$exists", Value: true}}}},
+		options.FindOne().SetProjection(bson.D{{Key: "_id", Value: 1}})).Err()
+	switch {
+	case err == mongo.ErrNoDocuments:
+		return nil
+	case err != nil:
+		return err
+	default:
+		return ErrNormalized
+	}
+}
+
+// Sbom returns the SBOM with the given id in the embedded layout,
+// mongo.ErrNoDocuments if there is none
+func (s *Store) Sbom(ctx context.Context, id bson.ObjectID) (*sbom.StoredSbom, error) {
+	var doc struct {
+		Sbom       `bson:",inline"`
+		Components []sbom.Component `bson:"components"`
+	}
+	if err := s.Sboms.FindOne(ctx, bson.D{{Key: "_id", Value: id}}).Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	if doc.Refs == nil || s.Components == nil {
+		return &sbom.StoredSbom{
+			Id: doc.Id,
+			CyclonedxSbom: sbom.CyclonedxSbom{
+				Components:   doc.Components,
+				Dependencies: doc.Dependencies,
+				Source:       doc.Source,
+				Distro:       doc.Distro,
+				Digest:       doc.Digest,
+			},
+		}, nil
+	}
+
+	keys := make([]string, len(doc.Refs))
+	for i, r := range doc.Refs {
+		keys[i] = r.Key
+	}
+	cursor, err := s.Components.Find(ctx, bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: keys}}}})
+	if err != nil {
+		return nil, err
+	}
+
+	var found []Entry
+	if err := cursor.All(ctx, &found); err != nil {
+		return nil, err
+	}
+
+	entries := make(map[string]Entry, len(found))
+	for _, e := range found {
+		entries[e.Key] = e
+	}
+	return Rehydrate(&doc.Sbom, entries)
+}
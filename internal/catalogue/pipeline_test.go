@@ -0,0 +1,439 @@
+package catalogue
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"sbom-processor/internal/sbom"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// memoryDb evaluates aggregation pipelines on documents in memory.
+// It only supports the stages and operators used by Pipeline and
+// UniqueNames, unknown ones fail the test.
+type memoryDb struct {
+	t           *testing.T
+	collections map[string][]map[string]any
+	// number of $lookup sub-pipelines run
+	lookups int
+}
+
+func newMemoryDb(t *testing.T) *memoryDb {
+	return &memoryDb{t: t, collections: map[string][]map[string]any{}}
+}
+
+func (db *memoryDb) insert(collection string, docs ...any) {
+	for _, d := range docs {
+		data, err := bson.Marshal(d)
+		if err != nil {
+			db.t.Fatalf("unable to marshal %+v: %s", d, err)
+		}
+		var raw bson.D
+		if err := bson.Unmarshal(data, &raw); err != nil {
+			db.t.Fatalf("unable to unmarshal %+v: %s", d, err)
+		}
+		db.collections[collection] = append(db.collections[collection], plain(raw).(map[string]any))
+	}
+}
+
+// converts documents to maps and arrays to slices
+func plain(v any) any {
+	switch v := v.(type) {
+	case bson.D:
+		m := make(map[string]any, len(v))
+		for _, e := range v {
+			m[e.Key] = plain(e.Value)
+		}
+		return m
+	case bson.A:
+		a := make([]any, len(v))
+		for i, e := range v {
+			a[i] = plain(e)
+		}
+		return a
+	default:
+		return v
+	}
+}
+
+// decodes the plain document into v
+func (db *memoryDb) decode(doc map[string]any, v any) {
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		db.t.Fatalf("unable to marshal %+v: %s", doc, err)
+	}
+	if err := bson.Unmarshal(data, v); err != nil {
+		db.t.Fatalf("unable to decode %+v: %s", doc, err)
+	}
+}
+
+func (db *memoryDb) aggregate(collection string, pipeline mongo.Pipeline) []map[string]any {
+	return db.run(db.collections[collection], pipeline)
+}
+
+func (db *memoryDb) run(docs []map[string]any, pipeline mongo.Pipeline) []map[string]any {
+	for _, stage := range pipeline {
+		op, arg := stage[0].Key, stage[0].Value
+		var next []map[string]any
+		switch op {
+		case "$match":
+			for _, d := range docs {
+				if db.matches(d, arg.(bson.D)) {
+					next = append(next, d)
+				}
+			}
+		case "$lookup":
+			spec := plain(arg).(map[string]any)
+			var sub mongo.Pipeline
+			for _, e := range arg.(bson.D) {
+				if e.Key == "pipeline" {
+					sub = e.Value.(mongo.Pipeline)
+				}
+			}
+			for _, d := range docs {
+				local := flatten(lookup(d, spec["localField"].(string)))
+				var matching []map[string]any
+				for _, f := range db.collections[spec["from"].(string)] {
+					if slices.ContainsFunc(local, func(l any) bool { return reflect.DeepEqual(l, f[spec["foreignField"].(string)]) }) {
+						matching = append(matching, f)
+					}
+				}
+				// the pipeline runs on the matching documents
+				if sub != nil {
+					db.lookups++
+					matching = db.run(matching, sub)
+				}
+				joined := []any{}
+				for _, m := range matching {
+					joined = append(joined, m)
+				}
+				next = append(next, with(d, spec["as"].(string), joined))
+			}
+		case "$replaceRoot":
+			for _, d := range docs {
+				next = append(next, db.eval(arg.(bson.D)[0].Value, d, nil).(map[string]any))
+			}
+		case "$set":
+			for _, d := range docs {
+				for _, e := range arg.(bson.D) {
+					d = with(d, e.Key, db.eval(e.Value, d, nil))
+				}
+				next = append(next, d)
+			}
+		case "$unset":
+			for _, d := range docs {
+				// copy before removing the fields
+				d = with(d, "", nil)
+				for _, f := range arg.(bson.A) {
+					delete(d, f.(string))
+				}
+				next = append(next, d)
+			}
+		case "$unwind":
+			field := strings.TrimPrefix(arg.(string), "$")
+			for _, d := range docs {
+				values, _ := d[field].([]any)
+				for _, v := range values {
+					next = append(next, with(d, field, v))
+				}
+			}
+		case "$group":
+			seen := map[string]bool{}
+			for _, d := range docs {
+				id := db.eval(arg.(bson.D)[0].Value, d, nil)
+				if key := fmt.Sprint(id); !seen[key] {
+					seen[key] = true
+					next = append(next, map[string]any{"_id": id})
+				}
+			}
+		default:
+			db.t.Fatalf("unsupported stage %s", op)
+		}
+		docs = next
+	}
+	return docs
+}
+
+// copy of d with field set to v
+func with(d map[string]any, field string, v any) map[string]any {
+	res := make(map[string]any, len(d)+1)
+	for k, e := range d {
+		res[k] = e
+	}
+	if field != "" {
+		res[field] = v
+	}
+	return res
+}
+
+func lookup(v any, path string) any {
+	for _, p := range strings.Split(path, ".") {
+		switch curr := v.(type) {
+		case map[string]any:
+			v = curr[p]
+		case []any:
+			var res []any
+			for _, e := range curr {
+				if m, ok := e.(map[string]any); ok && m[p] != nil {
+					res = append(res, m[p])
+				}
+			}
+			v = res
+		default:
+			return nil
+		}
+	}
+	return v
+}
+
+func flatten(v any) []any {
+	a, ok := v.([]any)
+	if !ok {
+		return []any{v}
+	}
+	var res []any
+	for _, e := range a {
+		res = append(res, flatten(e)...)
+	}
+	return res
+}
+
+func (db *memoryDb) matches(d map[string]any, filter bson.D) bool {
+	for _, e := range filter {
+		value := lookup(d, e.Key)
+		if cond, ok := e.Value.(bson.D); ok && cond[0].Key == "$exists" {
+			if (value != nil) != cond[0].Value.(bool) {
+				return false
+			}
+			continue
+		}
+		if !slices.ContainsFunc(flatten(value), func(v any) bool { return reflect.DeepEqual(v, e.Value) }) {
+			return false
+		}
+	}
+	return true
+}
+
+func (db *memoryDb) eval(expr any, d map[string]any, vars map[string]any) any {
+	switch expr := expr.(type) {
+	case string:
+		switch {
+		case strings.HasPrefix(expr, "$$"):
+			name, path, _ := strings.Cut(strings.TrimPrefix(expr, "$$"), ".")
+			if path == "" {
+				return vars[name]
+			}
+			return lookup(vars[name], path)
+		case strings.HasPrefix(expr, "$"):
+			return lookup(d, strings.TrimPrefix(expr, "$"))
+		}
+		return expr
+	case bson.A:
+		res := make([]any, len(expr))
+		for i, e := range expr {
+			res[i] = db.eval(e, d, vars)
+		}
+		return res
+	case bson.D:
+		if len(expr) == 0 || !strings.HasPrefix(expr[0].Key, "$") {
+			res := map[string]any{}
+			for _, e := range expr {
+				if v := db.eval(e.Value, d, vars); v != nil {
+					res[e.Key] = v
+				}
+			}
+			return res
+		}
+		return db.operator(expr[0].Key, expr[0].Value, d, vars)
+	default:
+		return expr
+	}
+}
+
+func (db *memoryDb) operator(op string, arg any, d map[string]any, vars map[string]any) any {
+	spec := func(key string) any {
+		for _, e := range arg.(bson.D) {
+			if e.Key == key {
+				return e.Value
+			}
+		}
+		return nil
+	}
+
+	switch op {
+	case "$cond":
+		args := arg.(bson.A)
+		if db.eval(args[0], d, vars) == true {
+			return db.eval(args[1], d, vars)
+		}
+		return db.eval(args[2], d, vars)
+	case "$isArray":
+		_, ok := db.eval(arg, d, vars).([]any)
+		return ok
+	case "$eq":
+		args := arg.(bson.A)
+		return reflect.DeepEqual(db.eval(args[0], d, vars), db.eval(args[1], d, vars))
+	case "$first":
+		a, _ := db.eval(arg, d, vars).([]any)
+		if len(a) == 0 {
+			return nil
+		}
+		return a[0]
+	case "$map", "$filter":
+		input, _ := db.eval(spec("input"), d, vars).([]any)
+		as, ok := spec("as").(string)
+		if !ok {
+			as = "this"
+		}
+		res := []any{}
+		for _, e := range input {
+			scope := with(vars, as, e)
+			if op == "$map" {
+				res = append(res, db.eval(spec("in"), d, scope))
+			} else if db.eval(spec("cond"), d, scope) == true {
+				res = append(res, e)
+			}
+		}
+		return res
+	case "$let":
+		scope := vars
+		for _, v := range spec("vars").(bson.D) {
+			scope = with(scope, v.Key, db.eval(v.Value, d, vars))
+		}
+		return db.eval(spec("in"), d, scope)
+	default:
+		db.t.Fatalf("unsupported operator %s", op)
+		return nil
+	}
+}
+
+// an embedded and a normalized SBOM sharing guava
+func mixedLayouts(t *testing.T) (*memoryDb, *sbom.CyclonedxSbom) {
+	db := newMemoryDb(t)
+
+	db.insert("sboms", sbom.StoredSbom{
+		Id: bson.NewObjectID(),
+		CyclonedxSbom: sbom.CyclonedxSbom{Components: []sbom.Component{
+			{Name: "guava", Type: "java-archive", Id: "x", Version: "31.0.0"},
+			{Name: "zlib", Type: "deb", Id: "y", Version: "1.2.13"},
+		}},
+	})
+
+	normalized := testSbom()
+	normalized.Components = append(normalized.Components,
+		sbom.Component{Name: "log4j-core", Type: "java-archive", Id: "d", Language: "java", Version: "2.17.1"})
+	n := Normalize(normalized)
+	n.Sbom.Id = bson.NewObjectID()
+	db.insert("sboms", n.Sbom)
+	for _, e := range n.Entries {
+		db.insert("components", e)
+	}
+
+	return db, normalized
+}
+
+func TestPipelineRehydrates(t *testing.T) {
+	db, normalized := mixedLayouts(t)
+
+	docs := db.aggregate("sboms", Pipeline("sboms", "components"))
+	if len(docs) != 2 {
+		t.Fatalf("both SBOMs expected, got %d", len(docs))
+	}
+
+	var embedded, rehydrated sbom.StoredSbom
+	db.decode(docs[0], &embedded)
+	db.decode(docs[1], &rehydrated)
+	if len(embedded.Components) != 2 || embedded.Components[0].Name != "guava" {
+		t.Fatalf("embedded SBOMs must be passed through, got %+v", embedded.Components)
+	}
+	if !reflect.DeepEqual(rehydrated.Components, normalized.Components) {
+		t.Fatalf("unexpected rehydrated components\n%+v\n%+v", rehydrated.Components, normalized.Components)
+	}
+	if _, ok := docs[1]["refs"]; ok {
+		t.Fatalf("refs must be removed after the rehydration")
+	}
+}
+
+// the unique names read by the export and the maven cache
+func TestUniqueNamesNormalized(t *testing.T) {
+	db, _ := mixedLayouts(t)
+
+	names := func(docs []map[string]any) []string {
+		var res []string
+		for _, d := range docs {
+			res = append(res, d["_id"].(string))
+		}
+		slices.Sort(res)
+		return res
+	}
+
+	// without the rehydration only the embedded components are found
+	if res := names(db.aggregate("sboms", UniqueNames("java-archive"))); !slices.Equal(res, []string{"guava"}) {
+		t.Fatalf("unexpected names of the embedded SBOMs %v", res)
+	}
+
+	// as run by Store.Aggregate with the catalogue
+	pipeline := append(Pipeline("sboms", "components"), UniqueNames("java-archive")...)
+	if res := names(db.aggregate("sboms", pipeline)); !slices.Equal(res, []string{"guava", "log4j-core"}) {
+		t.Fatalf("unexpected names of both layouts %v", res)
+	}
+}
+
+func TestHoist(t *testing.T) {
+	bySource := bson.D{{Key: "$match", Value: bson.D{{Key: "source.name", Value: "nginx"}}}}
+	byDigest := bson.D{{Key: "$match", Value: bson.D{{Key: "digest", Value: bson.D{{Key: "$exists", Value: true}}}}}}
+	byType := bson.D{{Key: "$match", Value: bson.D{{Key: "$or", Value: bson.A{
+		bson.D{{Key: "source.name", Value: "nginx"}},
+		bson.D{{Key: "components.type", Value: "deb"}},
+	}}}}}
+	byExpr := bson.D{{Key: "$match", Value: bson.D{{Key: "$expr", Value: bson.D{{Key: "$gt", Value: bson.A{bson.D{{Key: "$size", Value: "$components"}}, 10}}}}}}}
+	unwind := bson.D{{Key: "$unwind", Value: "$components"}}
+
+	tests := []struct {
+		pipeline mongo.Pipeline
+		hoisted  int
+	}{
+		{mongo.Pipeline{bySource, byDigest, unwind}, 2},
+		{mongo.Pipeline{bySource, byType, byDigest}, 1},
+		{mongo.Pipeline{byExpr, bySource}, 0},
+		{mongo.Pipeline{unwind, bySource}, 0},
+		{mongo.Pipeline{bySource}, 1},
+	}
+
+	for i, tt := range tests {
+		before, after := hoist(tt.pipeline)
+		if len(before) != tt.hoisted || len(before)+len(after) != len(tt.pipeline) {
+			t.Fatalf("test %d: expected %d hoisted stages, got %d", i, tt.hoisted, len(before))
+		}
+	}
+}
+
+// only the SBOMs matching the hoisted stages are rehydrated
+func TestRehydratedAfterMatch(t *testing.T) {
+	db, normalized := mixedLayouts(t)
+	db.collections["sboms"][1]["source"] = map[string]any{"name": "normalized"}
+
+	pipeline := rehydrated(mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{{Key: "source.name", Value: "normalized"}}}},
+		{{Key: "$match", Value: bson.D{{Key: "components.type", Value: "java-archive"}}}},
+	}, "sboms", "components")
+	if pipeline[0][0].Key != "$match" || pipeline[1][0].Key != "$lookup" {
+		t.Fatalf("the source match must run before the rehydration, got %v", pipeline[:2])
+	}
+
+	docs := db.run(db.collections["sboms"], pipeline)
+	if len(docs) != 1 || db.lookups != 1 {
+		t.Fatalf("one rehydrated SBOM expected, got %d after %d rehydrations", len(docs), db.lookups)
+	}
+
+	var res sbom.StoredSbom
+	db.decode(docs[0], &res)
+	if !reflect.DeepEqual(res.Components, normalized.Components) {
+		t.Fatalf("unexpected rehydrated components %+v", res.Components)
+	}
+}
@@ -0,0 +1,220 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Index specification of a collection
+type Index struct {
+	// defaults to the name MongoDB generates from the keys,
+	// e.g., name_1_system_1
+	Name string
+	// fields and their order, 1 or -1
+	Keys   bson.D
+	Unique bool
+	// only documents matching the filter are indexed
+	Partial bson.D
+	// TTL, documents are removed once the date in the single key
+	// field is older than ExpireAfter. 0 keeps them.
+	ExpireAfter time.Duration
+}
+
+// IndexName returns the name of the index in the database
+func (i Index) IndexName() string {
+	if i.Name != "" {
+		return i.Name
+	}
+
+	parts := make([]string, len(i.Keys))
+	for n, k := range i.Keys {
+		parts[n] = fmt.Sprintf("%s_%v", k.Key, k.Value)
+	}
+	return strings.Join(parts, "_")
+}
+
+func (i Index) model() mongo.IndexModel {
+	opts := options.Index().SetName(i.IndexName())
+	if i.Unique {
+		opts.SetUnique(true)
+	}
+	if len(i.Partial) > 0 {
+		opts.SetPartialFilterExpression(i.Partial)
+	}
+	if i.ExpireAfter > 0 {
+		opts.SetExpireAfterSeconds(int32(i.ExpireAfter.Seconds()))
+	}
+	return mongo.IndexModel{Keys: i.Keys, Options: opts}
+}
+
+// index as listed by the database
+type existingIndex struct {
+	Name               string `bson:"name"`
+	Key                bson.D `bson:"key"`
+	Unique             bool   `bson:"unique"`
+	Partial            bson.D `bson:"partialFilterExpression"`
+	ExpireAfterSeconds *int64 `bson:"expireAfterSeconds"`
+}
+
+// matches reports whether e has the keys and options of i
+func (i Index) matches(e existingIndex) bool {
+	if len(i.Keys) != len(e.Key) || i.Unique != e.Unique {
+		return false
+	}
+	// key values are decoded as int32 or float64, compare them printed
+	for n, k := range i.Keys {
+		if k.Key != e.Key[n].Key || fmt.Sprint(k.Value) != fmt.Sprint(e.Key[n].Value) {
+			return false
+		}
+	}
+
+	expire := int64(0)
+	if e.ExpireAfterSeconds != nil {
+		expire = *e.ExpireAfterSeconds
+	}
+	if int64(i.ExpireAfter.Seconds()) != expire {
+		return false
+	}
+
+	return sameFilter(i.Partial, e.Partial)
+}
+
+func sameFilter(a, b bson.D) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return len(a) == len(b)
+	}
+	// relaxed extended JSON doesn't distinguish the number types
+	ja, errA := bson.MarshalExtJSON(a, false, false)
+	jb, errB := bson.MarshalExtJSON(b, false, false)
+	return errA == nil && errB == nil && string(ja) == string(jb)
+}
+
+// Drift between the specified and the existing indexes of a collection
+type Drift struct {
+	Collection string
+	Missing    []Index
+	// exist with the same name but different keys or options
+	Changed []Index
+	// exist but aren't specified, the _id index is never reported
+	Unknown []string
+}
+
+func (d *Drift) Empty() bool {
+	return len(d.Missing) == 0 && len(d.Changed) == 0 && len(d.Unknown) == 0
+}
+
+func diff(collection string, spec []Index, existing []existingIndex) *Drift {
+	d := &Drift{Collection: collection}
+	known := map[string]bool{"_id_": true}
+
+	for _, i := range spec {
+		found := false
+		for _, e := range existing {
+			if e.Name == i.IndexName() {
+				found = true
+				known[e.Name] = true
+				if !i.matches(e) {
+					d.Changed = append(d.Changed, i)
+				}
+				break
+			}
+		}
+		if found {
+			continue
+		}
+
+		// an equivalent index created under another name
+		for _, e := range existing {
+			if !known[e.Name] && i.matches(e) {
+				found = true
+				known[e.Name] = true
+				break
+			}
+		}
+		if !found {
+			d.Missing = append(d.Missing, i)
+		}
+	}
+
+	for _, e := range existing {
+		if !known[e.Name] {
+			d.Unknown = append(d.Unknown, e.Name)
+		}
+	}
+
+	return d
+}
+
+// Check compares the indexes of coll with spec
+func Check(ctx context.Context, coll *mongo.Collection, spec []Index) (*Drift, error) {
+	cursor, err := coll.Indexes().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var existing []existingIndex
+	if err := cursor.All(ctx, &existing); err != nil {
+		return nil, err
+	}
+
+	return diff(coll.Name(), spec, existing), nil
+}
+
+// Sync creates the missing indexes of coll. If drop is set, changed
+// indexes are recreated and unknown ones dropped. The returned drift
+// is the state before the sync.
+func Sync(ctx context.Context, coll *mongo.Collection, spec []Index, drop bool) (*Drift, error) {
+	d, err := Check(ctx, coll, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	create := d.Missing
+	if drop {
+		for _, name := range d.Unknown {
+			if err := coll.Indexes().DropOne(ctx, name); err != nil {
+				return d, err
+			}
+			logger.Debug("Index dropped", "collection", coll.Name(), "idx name", name)
+		}
+		for _, i := range d.Changed {
+			if err := coll.Indexes().DropOne(ctx, i.IndexName()); err != nil {
+				return d, err
+			}
+		}
+		create = append(create, d.Changed...)
+	}
+
+	for _, i := range create {
+		name, err := coll.Indexes().CreateOne(ctx, i.model())
+		if err != nil {
+			return d, err
+		}
+		logger.Debug("Index successfully created", "collection", coll.Name(), "idx name", name)
+	}
+
+	return d, nil
+}
+
+// Verify ensures that coll has the indexes a command depends on.
+// Missing indexes are created, indexes that differ from the spec
+// are reported as error and left as they are.
+func Verify(ctx context.Context, coll *mongo.Collection, spec []Index) error {
+	d, err := Sync(ctx, coll, spec, false)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, i := range d.Changed {
+		errs = append(errs, fmt.Errorf("index %s of %s differs from the specification, run the indexes command to recreate it", i.IndexName(), coll.Name()))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestIndexName(t *testing.T) {
+	i := Index{Keys: bson.D{{Key: "name", Value: 1}, {Key: "published", Value: -1}}}
+	if n := i.IndexName(); n != "name_1_published_-1" {
+		t.Fatalf("unexpected name %s", n)
+	}
+
+	i.Name = "custom"
+	if n := i.IndexName(); n != "custom" {
+		t.Fatalf("expected the given name, got %s", n)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	ttl := int64(3600)
+	spec := []Index{
+		{Keys: asc("name", "system")},
+		{Keys: asc("sha1"), Partial: exists("sha1")},
+		{Keys: asc("id"), Unique: true},
+		{Keys: asc("finished_at"), ExpireAfter: time.Hour},
+		{Keys: asc("repository")},
+	}
+	// as listed by the database, key values are int32
+	existing := []existingIndex{
+		{Name: "_id_", Key: bson.D{{Key: "_id", Value: int32(1)}}},
+		{Name: "name_1_system_1", Key: bson.D{{Key: "name", Value: int32(1)}, {Key: "system", Value: int32(1)}}},
+		{Name: "sha1_1", Key: bson.D{{Key: "sha1", Value: int32(1)}},
+			Partial: bson.D{{Key: "sha1", Value: bson.D{{Key: "$exists", Value: true}}}}},
+		// created before the index was unique
+		{Name: "id_1", Key: bson.D{{Key: "id", Value: int32(1)}}},
+		{Name: "finished_at_1", Key: bson.D{{Key: "finished_at", Value: int32(1)}}, ExpireAfterSeconds: &ttl},
+		// created by hand under another name
+		{Name: "by_repository", Key: bson.D{{Key: "repository", Value: float64(1)}}},
+		{Name: "name_1", Key: bson.D{{Key: "name", Value: int32(1)}}},
+	}
+
+	d := diff("test", spec, existing)
+
+	if len(d.Missing) != 0 {
+		t.Fatalf("unexpected missing indexes %+v", d.Missing)
+	}
+	if len(d.Changed) != 1 || d.Changed[0].IndexName() != "id_1" {
+		t.Fatalf("expected id_1 to be changed, got %+v", d.Changed)
+	}
+	if len(d.Unknown) != 1 || d.Unknown[0] != "name_1" {
+		t.Fatalf("expected name_1 to be unknown, got %v", d.Unknown)
+	}
+
+	d = diff("test", spec, existing[:1])
+	if len(d.Missing) != len(spec) || len(d.Unknown) != 0 || d.Empty() {
+		t.Fatalf("expected all indexes to be missing, got %+v", d)
+	}
+}
+
+func TestSpec(t *testing.T) {
+	for _, c := range Collections() {
+		seen := make(map[string]bool)
+		for _, i := range Spec(c) {
+			if len(i.Keys) == 0 {
+				t.Fatalf("index without keys in %s", c)
+			}
+			if seen[i.IndexName()] {
+				t.Fatalf("duplicate index %s in %s", i.IndexName(), c)
+			}
+			seen[i.IndexName()] = true
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package db
+
+import (
+	"maps"
+	"slices"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func asc(fields ...string) bson.D {
+	keys := make(bson.D, len(fields))
+	for i, f := range fields {
+		keys[i] = bson.E{Key: f, Value: 1}
+	}
+	return keys
+}
+
+func exists(field string) bson.D {
+	return bson.D{{Key: field, Value: bson.D{{Key: "$exists", Value: true}}}}
+}
+
+// indexes of all collections by their default name
+var indexes = map[string][]Index{
+	"sboms": {
+		// refs only exist in the normalized layout
+		{Keys: asc("refs.key"), Partial: exists("refs")},
+		// SBOMs transformed before the digest was added have none
+		{Keys: asc("digest"), Partial: exists("digest")},
+		{Keys: asc("source.image.registry", "source.image.repository"), Partial: exists("source.image")},
+		{Keys: asc("source.oci.vendor"), Partial: exists("source.oci.vendor")},
+		{Keys: asc("source.oci.source"), Partial: exists("source.oci.source")},
+	},
+	"versions": {{Keys: asc("component_id"), Unique: true}},
+	// shared by the versions harvester and the maven cache
+	"blacklist": {
+		{Keys: asc("component_id"), Unique: true, Partial: exists("component_id")},
+		{Keys: asc("name"), Partial: exists("name")},
+	},
+	"deps_metadata": {{Keys: asc("name", "system")}},
+	"mvn_mirror": {
+		{Keys: asc("name")},
+		// only set for archives resolved by their digest
+		{Keys: asc("sha1"), Partial: exists("sha1")},
+	},
+	"multi_result": {{Keys: asc("name")}},
+	"lag":          {{Keys: asc("sbom_id"), Unique: true}},
+	"licenses": {
+		{Keys: asc("sbom_id"), Unique: true},
+		{Keys: asc("components.licenses")},
+		{Keys: asc("components.category")},
+	},
+	// advisories, timelines, and statistics are replaced by their key
+	"osv": {
+		{Keys: asc("id"), Unique: true},
+		{Keys: asc("affected.package.name")},
+	},
+	"findings":   {{Keys: asc("sbom_id")}},
+	"timelines":  {{Keys: asc("repository"), Unique: true}},
+	"sbom_stats": {{Keys: asc("sbom_id"), Unique: true}},
+}
+
+// Spec returns the indexes of the collection with the given
+// default name, nil if it has none besides _id
+func Spec(collection string) []Index {
+	return indexes[collection]
+}
+
+// Collections returns the default names of all collections with indexes
+func Collections() []string {
+	return slices.Sorted(maps.Keys(indexes))
+}
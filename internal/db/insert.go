@@ -0,0 +1,38 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// InsertNew inserts docs into a collection with a unique index and
+// skips the documents that are already stored, e.g., by concurrent
+// workers. Returns the number of inserted documents.
+func InsertNew[T any](ctx context.Context, coll *mongo.Collection, docs []T) (int, error) {
+	if len(docs) == 0 {
+		return 0, nil
+	}
+
+	_, err := coll.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+	duplicates, err := skipDuplicates(err)
+	return len(docs) - duplicates, err
+}
+
+// returns the number of duplicate key errors of an unordered insert.
+// err is returned if it contains any other error.
+func skipDuplicates(err error) (int, error) {
+	var bwe mongo.BulkWriteException
+	if err == nil || !errors.As(err, &bwe) || bwe.WriteConcernError != nil {
+		return 0, err
+	}
+
+	for _, we := range bwe.WriteErrors {
+		if !mongo.IsDuplicateKeyError(we.WriteError) {
+			return 0, err
+		}
+	}
+	return len(bwe.WriteErrors), nil
+}
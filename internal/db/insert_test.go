@@ -0,0 +1,37 @@
+package db
+
+import (
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+func TestSkipDuplicates(t *testing.T) {
+	duplicate := mongo.BulkWriteError{WriteError: mongo.WriteError{Index: 0, Code: 11000, Message: "E11000 duplicate key error"}}
+	invalid := mongo.BulkWriteError{WriteError: mongo.WriteError{Index: 1, Code: 2, Message: "bad value"}}
+
+	if n, err := skipDuplicates(nil); n != 0 || err != nil {
+		t.Fatalf("nothing to skip expected, got %d %v", n, err)
+	}
+
+	n, err := skipDuplicates(mongo.BulkWriteException{WriteErrors: []mongo.BulkWriteError{duplicate, duplicate}})
+	if n != 2 || err != nil {
+		t.Fatalf("two skipped duplicates expected, got %d %v", n, err)
+	}
+
+	if _, err := skipDuplicates(mongo.BulkWriteException{WriteErrors: []mongo.BulkWriteError{duplicate, invalid}}); err == nil {
+		t.Fatalf("errors besides duplicates must be returned")
+	}
+
+	if _, err := skipDuplicates(mongo.BulkWriteException{
+		WriteErrors:       []mongo.BulkWriteError{duplicate},
+		WriteConcernError: &mongo.WriteConcernError{Code: 64},
+	}); err == nil {
+		t.Fatalf("write concern errors must be returned")
+	}
+
+	if _, err := skipDuplicates(errors.New("connection reset")); err == nil {
+		t.Fatalf("other errors must be returned")
+	}
+}
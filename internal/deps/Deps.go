@@ -1,11 +1,16 @@
 package deps
 
 import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"net/url"
+
+	"sbom-processor/internal/metrics"
 )
 
 type Deps struct {
@@ -19,6 +24,12 @@ type Version struct {
 	PublishedAt string `bson:"publishedAt" json:"publishedAt"`
 }
 
+type VersionKey struct {
+	System  string `bson:"system" json:"system"`
+	Name    string `bson:"name" json:"name"`
+	Version string `bson:"version" json:"version"`
+}
+
 type CacheRequest struct {
 	Name   string
 	System string
@@ -33,8 +44,25 @@ type VersionsApiResponse struct {
 	PublishedAt string  `bson:"publishedAt" json:"publishedAt"`
 }
 
-func DepsWorkerDo(c CacheRequest) (*Deps, error) {
-	deps, err := queryApi(c)
+type HashQueryResponse struct {
+	Results []HashQueryResult `json:"results"`
+}
+
+type HashQueryResult struct {
+	Version struct {
+		VersionKey VersionKey `json:"versionKey"`
+	} `json:"version"`
+}
+
+type VersionInfo struct {
+	VersionKey VersionKey `json:"versionKey"`
+	Licenses   []string   `json:"licenses"`
+}
+
+const depsBasePath string = "https://api.deps.dev/v3/"
+
+func DepsWorkerDo(ctx context.Context, c CacheRequest) (*Deps, error) {
+	deps, err := queryApi(ctx, c)
 	if err != nil {
 		// TODO: check if we can retry the request
 		return nil, err
@@ -53,13 +81,13 @@ func DepsWorkerDo(c CacheRequest) (*Deps, error) {
 	}, nil
 }
 
-func queryApi(c CacheRequest) (*DepsApiResponse, error) {
+func queryApi(ctx context.Context, c CacheRequest) (*DepsApiResponse, error) {
 	encodedName := url.QueryEscape(c.Name)
 	encodedSystem := url.QueryEscape(c.System)
 
 	// GET /v3/systems/{packageKey.system}/packages/{packageKey.name}
 	url := fmt.Sprintf("https://api.deps.dev/v3/systems/%s/packages/%s", encodedSystem, encodedName)
-	resp, err := http.Get(url)
+	resp, err := metrics.Get(ctx, "deps_dev", url)
 	if err != nil {
 		slog.Default().Debug("Request failed with", "url", url, "err", err.Error())
 		return nil, err
@@ -71,7 +99,7 @@ func queryApi(c CacheRequest) (*DepsApiResponse, error) {
 			retry := resp.Header.Get("Retry-After")
 			slog.Default().Debug("Failed due to too many requests.", "retry-after", retry)
 		}
-		err := fmt.Errorf("request failed with status code %d", resp.StatusCode)
+		err := &metrics.StatusError{Code: resp.StatusCode}
 		slog.Default().Debug("Request failed with", "url", url, "err", err.Error())
 		return nil, err
 	}
@@ -85,3 +113,92 @@ func queryApi(c CacheRequest) (*DepsApiResponse, error) {
 
 	return &deps, nil
 }
+
+// QueryHash returns all package versions known to deps.dev whose
+// artifact has the given hex encoded hash. hashType is one of the
+// hash types supported by deps.dev, e.g., SHA1 or SHA256.
+func QueryHash(ctx context.Context, hashType string, hexValue string) ([]VersionKey, error) {
+	return queryHash(ctx, depsBasePath, hashType, hexValue)
+}
+
+func queryHash(ctx context.Context, basePath string, hashType string, hexValue string) ([]VersionKey, error) {
+	raw, err := hex.DecodeString(hexValue)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex encoded hash %s: %w", hexValue, err)
+	}
+
+	// deps.dev expects the base64 encoded hash bytes
+	// GET /v3/query?hash.type={type}&hash.value={value}
+	params := url.Values{}
+	params.Set("hash.type", hashType)
+	params.Set("hash.value", base64.StdEncoding.EncodeToString(raw))
+	url := basePath + "query?" + params.Encode()
+
+	resp, err := metrics.Get(ctx, "deps_dev", url)
+	if err != nil {
+		slog.Default().Debug("Request failed with", "url", url, "err", err.Error())
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		err := &metrics.StatusError{Code: resp.StatusCode}
+		slog.Default().Debug("Request failed with", "url", url, "err", err.Error())
+		return nil, err
+	}
+
+	var res HashQueryResponse
+	decoder := json.NewDecoder(resp.Body)
+	if err := decoder.Decode(&res); err != nil {
+		slog.Default().Debug("Decoding of response failed", "url", url, "err", err.Error())
+		return nil, err
+	}
+
+	keys := make([]VersionKey, len(res.Results))
+	for i, r := range res.Results {
+		keys[i] = r.Version.VersionKey
+	}
+
+	return keys, nil
+}
+
+// QueryVersion returns the metadata deps.dev stores for a single
+// package version, e.g., its licenses. Returns nil if the version
+// is unknown.
+func QueryVersion(ctx context.Context, key VersionKey) (*VersionInfo, error) {
+	return queryVersion(ctx, depsBasePath, key)
+}
+
+func queryVersion(ctx context.Context, basePath string, key VersionKey) (*VersionInfo, error) {
+	// GET /v3/systems/{system}/packages/{name}/versions/{version}
+	url := fmt.Sprintf("%ssystems/%s/packages/%s/versions/%s", basePath,
+		url.PathEscape(key.System), url.PathEscape(key.Name), url.PathEscape(key.Version))
+
+	resp, err := metrics.Get(ctx, "deps_dev", url)
+	if err != nil {
+		slog.Default().Debug("Request failed with", "url", url, "err", err.Error())
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		err := &metrics.StatusError{Code: resp.StatusCode}
+		slog.Default().Debug("Request failed with", "url", url, "err", err.Error())
+		return nil, err
+	}
+
+	var res VersionInfo
+	decoder := json.NewDecoder(resp.Body)
+	if err := decoder.Decode(&res); err != nil {
+		slog.Default().Debug("Decoding of response failed", "url", url, "err", err.Error())
+		return nil, err
+	}
+
+	return &res, nil
+}
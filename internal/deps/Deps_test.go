@@ -0,0 +1,81 @@
+package deps
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestQueryHash(t *testing.T) {
+	srv := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/query" {
+					t.Errorf("unexpected path %s", r.URL.Path)
+				}
+				if r.URL.Query().Get("hash.type") != "SHA1" ||
+					r.URL.Query().Get("hash.value") != "NTeftlJv0BnzMVQrTpri5WbFeTM=" {
+					t.Errorf("unexpected query %s", r.URL.RawQuery)
+				}
+				_, _ = w.Write([]byte(`{"results": [{"version": {"versionKey": {"system": "MAVEN", "name": "com.google.inject:guice", "version": "4.0"}}}]}`))
+			},
+		))
+
+	defer srv.Close()
+
+	keys, err := queryHash(context.Background(), srv.URL+"/", "SHA1", "35379fb6526fd019f331542b4e9ae2e566c57933")
+	if err != nil {
+		t.Fatalf("no error expected for valid request, %s", err.Error())
+	}
+
+	if len(keys) != 1 {
+		t.Fatalf("exactly one result expected")
+	}
+
+	if keys[0].System != "MAVEN" ||
+		keys[0].Name != "com.google.inject:guice" ||
+		keys[0].Version != "4.0" {
+		t.Fatalf("Unexpected values after parsing JSON %+v", keys[0])
+	}
+}
+
+func TestQueryHashInvalidHex(t *testing.T) {
+	_, err := queryHash(context.Background(), "http://localhost/", "SHA1", "not hex")
+	if err == nil {
+		t.Fatalf("error expected for invalid hex value")
+	}
+}
+
+func TestQueryVersion(t *testing.T) {
+	srv := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.EscapedPath() != "/systems/GO/packages/github.com%2Fstretchr%2Ftestify/versions/v1.8.4" {
+					t.Errorf("unexpected path %s", r.URL.EscapedPath())
+				}
+				_, _ = w.Write([]byte(`{"versionKey": {"system": "GO", "name": "github.com/stretchr/testify", "version": "v1.8.4"}, "licenses": ["MIT"]}`))
+			},
+		))
+
+	defer srv.Close()
+
+	info, err := queryVersion(context.Background(), srv.URL+"/", VersionKey{System: "GO", Name: "github.com/stretchr/testify", Version: "v1.8.4"})
+	if err != nil {
+		t.Fatalf("no error expected for valid request, %s", err.Error())
+	}
+
+	if len(info.Licenses) != 1 || info.Licenses[0] != "MIT" {
+		t.Fatalf("Unexpected values after parsing JSON %+v", info)
+	}
+}
+
+func TestQueryVersionNotFound(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	info, err := queryVersion(context.Background(), srv.URL+"/", VersionKey{System: "NPM", Name: "unknown", Version: "1.0.0"})
+	if err != nil || info != nil {
+		t.Fatalf("unknown versions must not be an error, got %+v %v", info, err)
+	}
+}
@@ -0,0 +1,150 @@
+package graph
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// node properties exported to all formats
+var nodeProperties = []string{"name", "type", "version", "language"}
+
+func properties(n *Node) []string {
+	if n.Component == nil {
+		return []string{n.Id, "", "", ""}
+	}
+	c := n.Component
+	return []string{c.Name, c.Type, c.Version, c.Language}
+}
+
+// label of nodes that are components and nodes that aren't, e.g., files
+func label(n *Node) string {
+	if n.Component == nil {
+		return "Ref"
+	}
+	return "Component"
+}
+
+// WriteDot writes g in the graphviz DOT format. Node properties are
+// written as attributes and the relationship type as edge label.
+func WriteDot(w io.Writer, name string, g *Graph) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, "digraph %s {\n", strconv.Quote(name))
+	for _, n := range g.Nodes() {
+		props := properties(n)
+		fmt.Fprintf(bw, "  %s [label=%s", strconv.Quote(n.Id), strconv.Quote(props[0]))
+		for i, p := range nodeProperties {
+			fmt.Fprintf(bw, ", %s=%s", p, strconv.Quote(props[i]))
+		}
+		fmt.Fprintf(bw, ", kind=%s];\n", strconv.Quote(label(n)))
+	}
+	for _, e := range g.Edges() {
+		fmt.Fprintf(bw, "  %s -> %s [label=%s];\n", strconv.Quote(e.From), strconv.Quote(e.To), strconv.Quote(e.Type))
+	}
+	fmt.Fprint(bw, "}\n")
+
+	return bw.Flush()
+}
+
+// WriteGraphML writes g in the GraphML format. Node properties and
+// the relationship type are declared as GraphML keys.
+func WriteGraphML(w io.Writer, name string, g *Graph) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprint(bw, xml.Header)
+	fmt.Fprint(bw, "<graphml xmlns=\"http://graphml.graphdrawing.org/xmlns\">\n")
+	for _, p := range nodeProperties {
+		fmt.Fprintf(bw, "  <key id=\"%s\" for=\"node\" attr.name=\"%s\" attr.type=\"string\"/>\n", p, p)
+	}
+	fmt.Fprint(bw, "  <key id=\"kind\" for=\"node\" attr.name=\"kind\" attr.type=\"string\"/>\n")
+	fmt.Fprint(bw, "  <key id=\"label\" for=\"edge\" attr.name=\"label\" attr.type=\"string\"/>\n")
+	fmt.Fprintf(bw, "  <graph id=\"%s\" edgedefault=\"directed\">\n", escape(name))
+
+	for _, n := range g.Nodes() {
+		fmt.Fprintf(bw, "    <node id=\"%s\">\n", escape(n.Id))
+		for i, p := range properties(n) {
+			fmt.Fprintf(bw, "      <data key=\"%s\">%s</data>\n", nodeProperties[i], escape(p))
+		}
+		fmt.Fprintf(bw, "      <data key=\"kind\">%s</data>\n", label(n))
+		fmt.Fprint(bw, "    </node>\n")
+	}
+	for i, e := range g.Edges() {
+		fmt.Fprintf(bw, "    <edge id=\"e%d\" source=\"%s\" target=\"%s\">\n", i, escape(e.From), escape(e.To))
+		fmt.Fprintf(bw, "      <data key=\"label\">%s</data>\n", escape(e.Type))
+		fmt.Fprint(bw, "    </edge>\n")
+	}
+
+	fmt.Fprint(bw, "  </graph>\n</graphml>\n")
+
+	return bw.Flush()
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// Neo4jWriter writes graphs to a node and a relationship CSV file
+// in the format expected by neo4j-admin import. Multiple graphs can
+// be written to the same files, node ids are prefixed with the id
+// of the graph to keep them unique.
+type Neo4jWriter struct {
+	nodes         *csv.Writer
+	relationships *csv.Writer
+}
+
+func NewNeo4jWriter(nodes, relationships io.Writer) (*Neo4jWriter, error) {
+	w := Neo4jWriter{
+		nodes:         csv.NewWriter(nodes),
+		relationships: csv.NewWriter(relationships),
+	}
+
+	header := append([]string{"id:ID"}, nodeProperties...)
+	header = append(header, "sbom", ":LABEL")
+	if err := w.nodes.Write(header); err != nil {
+		return nil, err
+	}
+
+	if err := w.relationships.Write([]string{":START_ID", ":END_ID", ":TYPE", "type"}); err != nil {
+		return nil, err
+	}
+
+	return &w, nil
+}
+
+func (w *Neo4jWriter) Write(graphId string, g *Graph) error {
+	for _, n := range g.Nodes() {
+		record := append([]string{graphId + "/" + n.Id}, properties(n)...)
+		record = append(record, graphId, label(n))
+		if err := w.nodes.Write(record); err != nil {
+			return err
+		}
+	}
+
+	for _, e := range g.Edges() {
+		record := []string{graphId + "/" + e.From, graphId + "/" + e.To, relationshipType(e.Type), e.Type}
+		if err := w.relationships.Write(record); err != nil {
+			return err
+		}
+	}
+
+	w.nodes.Flush()
+	w.relationships.Flush()
+	return errors.Join(w.nodes.Error(), w.relationships.Error())
+}
+
+// neo4j relationship types are upper case by convention, e.g.,
+// dependency-of becomes DEPENDENCY_OF
+func relationshipType(t string) string {
+	if t == "" {
+		return "DEPENDS_ON"
+	}
+	return strings.ToUpper(strings.NewReplacer("-", "_", " ", "_").Replace(t))
+}
@@ -0,0 +1,133 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"strings"
+	"testing"
+
+	"sbom-processor/internal/sbom"
+)
+
+func exportSbom() *sbom.CyclonedxSbom {
+	return &sbom.CyclonedxSbom{
+		Components: []sbom.Component{
+			{Id: "a", Name: "openssl", Type: "deb", Version: "3.0.11"},
+			{Id: "b", Name: "guice", Type: "java-archive", Version: "4.0", Language: "java"},
+		},
+		Dependencies: []sbom.Dependency{
+			{Ref: "a", DependsOn: []sbom.Target{{Child: "b", Type: "dependency-of"}, {Child: "/usr/lib/\"x\"", Type: Contains}}},
+		},
+	}
+}
+
+func TestWriteDot(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteDot(&buf, "test", New(exportSbom())); err != nil {
+		t.Fatalf("no error expected %s", err)
+	}
+
+	out := buf.String()
+	for _, expected := range []string{
+		`digraph "test" {`,
+		`"b" [label="guice", name="guice", type="java-archive", version="4.0", language="java", kind="Component"];`,
+		`"b" -> "a" [label="dependency-of"];`,
+		`"a" -> "/usr/lib/\"x\"" [label="contains"];`,
+	} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("%s not contained in\n%s", expected, out)
+		}
+	}
+}
+
+func TestWriteGraphML(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteGraphML(&buf, "test", New(exportSbom())); err != nil {
+		t.Fatalf("no error expected %s", err)
+	}
+
+	var doc struct {
+		Graph struct {
+			Nodes []struct {
+				Id string `xml:"id,attr"`
+			} `xml:"node"`
+			Edges []struct {
+				Source string `xml:"source,attr"`
+				Data   string `xml:"data"`
+			} `xml:"edge"`
+		} `xml:"graph"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid xml %s", err)
+	}
+
+	if len(doc.Graph.Nodes) != 3 || len(doc.Graph.Edges) != 2 {
+		t.Fatalf("unexpected number of nodes or edges %+v", doc)
+	}
+
+	if doc.Graph.Edges[0].Source != "b" || doc.Graph.Edges[0].Data != "dependency-of" {
+		t.Fatalf("unexpected edge %+v", doc.Graph.Edges[0])
+	}
+}
+
+func TestNeo4jWriter(t *testing.T) {
+	var nodes, rels bytes.Buffer
+	w, err := NewNeo4jWriter(&nodes, &rels)
+	if err != nil {
+		t.Fatalf("no error expected %s", err)
+	}
+
+	g := New(exportSbom())
+	if err := w.Write("s1", g); err != nil {
+		t.Fatalf("no error expected %s", err)
+	}
+	if err := w.Write("s2", g); err != nil {
+		t.Fatalf("no error expected %s", err)
+	}
+
+	nodeRecords, err := csv.NewReader(&nodes).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid node csv %s", err)
+	}
+	if len(nodeRecords) != 7 || nodeRecords[0][0] != "id:ID" {
+		t.Fatalf("header and 6 nodes expected, got %v", nodeRecords)
+	}
+	if nodeRecords[4][0] != "s2/a" || nodeRecords[4][5] != "s2" || nodeRecords[4][6] != "Component" {
+		t.Fatalf("unexpected node record %v", nodeRecords[4])
+	}
+
+	relRecords, err := csv.NewReader(&rels).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid relationship csv %s", err)
+	}
+	if len(relRecords) != 5 {
+		t.Fatalf("header and 4 relationships expected, got %v", relRecords)
+	}
+	if relRecords[1][0] != "s1/b" || relRecords[1][2] != "DEPENDENCY_OF" {
+		t.Fatalf("unexpected relationship record %v", relRecords[1])
+	}
+}
+
+type failingWriter struct {
+	err error
+}
+
+func (w failingWriter) Write(p []byte) (int, error) {
+	return 0, w.err
+}
+
+func TestNeo4jWriterErrors(t *testing.T) {
+	errNodes := errors.New("nodes")
+	errRels := errors.New("relationships")
+	w, err := NewNeo4jWriter(failingWriter{errNodes}, failingWriter{errRels})
+	if err != nil {
+		t.Fatalf("no error expected before the first flush %s", err)
+	}
+
+	err = w.Write("s1", New(exportSbom()))
+	if !errors.Is(err, errNodes) || !errors.Is(err, errRels) {
+		t.Fatalf("the errors of both files expected, got %v", err)
+	}
+}
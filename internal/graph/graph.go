@@ -0,0 +1,437 @@
+package graph
+
+import (
+	"maps"
+	"slices"
+
+	"sbom-processor/internal/sbom"
+)
+
+// relationship types used by syft
+const (
+	Contains     = sbom.RelationshipContains
+	EvidentBy    = sbom.RelationshipEvidentBy
+	DependencyOf = sbom.RelationshipDependencyOf
+)
+
+type Node struct {
+	Id        string
+	Component *sbom.Component // nil if the ref isn't a component, e.g., a file or the image
+}
+
+// an edge points from the dependent to its dependency
+// or from the container to its content
+type Edge struct {
+	From string
+	To   string
+	Type string
+}
+
+// Graph is an indexed view on the dependencies of a CyclonedxSbom.
+// Nodes and edges are kept in insertion order to make all results
+// deterministic. A Graph isn't safe for concurrent use.
+type Graph struct {
+	nodes map[string]*Node
+	order []string
+	edges []Edge
+	out   map[string][]Edge
+	in    map[string][]Edge
+	root  string
+	// computed on first use, reset when the graph changes
+	depths map[string]int
+}
+
+// New builds the graph from the components and dependencies of s.
+// Refs without a matching component, e.g., syft file nodes, become
+// nodes without component. Syft dependency-of edges point from the
+// dependency to its dependent and are reversed, so that all edges
+// point from the dependent to its dependency.
+// The node with the id of s.Source is used as image root.
+func New(s *sbom.CyclonedxSbom) *Graph {
+	g := empty()
+
+	for i := range s.Components {
+		g.addNode(s.Components[i].Id, &s.Components[i])
+	}
+
+	for _, d := range s.Dependencies {
+		for _, t := range d.DependsOn {
+			if t.Type == DependencyOf {
+				g.addEdge(Edge{From: t.Child, To: d.Ref, Type: t.Type})
+			} else {
+				g.addEdge(Edge{From: d.Ref, To: t.Child, Type: t.Type})
+			}
+		}
+	}
+
+	if _, ok := g.nodes[s.Source.Id]; ok && s.Source.Id != "" {
+		g.root = s.Source.Id
+	}
+
+	return g
+}
+
+func empty() *Graph {
+	return &Graph{
+		nodes: make(map[string]*Node),
+		out:   make(map[string][]Edge),
+		in:    make(map[string][]Edge),
+	}
+}
+
+func (g *Graph) addNode(id string, c *sbom.Component) {
+	if n, ok := g.nodes[id]; ok {
+		if n.Component == nil {
+			n.Component = c
+		}
+		return
+	}
+
+	g.nodes[id] = &Node{Id: id, Component: c}
+	g.order = append(g.order, id)
+	g.depths = nil
+}
+
+func (g *Graph) addEdge(e Edge) {
+	g.addNode(e.From, nil)
+	g.addNode(e.To, nil)
+
+	if slices.Contains(g.out[e.From], e) {
+		return
+	}
+
+	g.edges = append(g.edges, e)
+	g.out[e.From] = append(g.out[e.From], e)
+	g.in[e.To] = append(g.in[e.To], e)
+	g.depths = nil
+}
+
+func (g *Graph) Node(id string) (*Node, bool) {
+	n, ok := g.nodes[id]
+	return n, ok
+}
+
+func (g *Graph) Nodes() []*Node {
+	nodes := make([]*Node, len(g.order))
+	for i, id := range g.order {
+		nodes[i] = g.nodes[id]
+	}
+	return nodes
+}
+
+func (g *Graph) Edges() []Edge {
+	return slices.Clone(g.edges)
+}
+
+// returns the image root or an empty string if the
+// SBOM doesn't contain a node for the image
+func (g *Graph) ImageRoot() string {
+	return g.root
+}
+
+// Roots returns all nodes without incoming edges
+func (g *Graph) Roots() []string {
+	var roots []string
+	for _, id := range g.order {
+		if len(g.in[id]) == 0 {
+			roots = append(roots, id)
+		}
+	}
+	return roots
+}
+
+// DirectDependencies returns the targets of all outgoing edges of id
+func (g *Graph) DirectDependencies(id string) []string {
+	return unique(g.out[id], func(e Edge) string { return e.To })
+}
+
+// TransitiveDependencies returns all nodes reachable from id
+// that aren't direct dependencies of id
+func (g *Graph) TransitiveDependencies(id string) []string {
+	direct := g.DirectDependencies(id)
+	var transitive []string
+	for _, n := range g.reachable(id, g.out, func(e Edge) string { return e.To }) {
+		if !slices.Contains(direct, n) {
+			transitive = append(transitive, n)
+		}
+	}
+	return transitive
+}
+
+// Dependents returns the sources of all incoming edges of id
+func (g *Graph) Dependents(id string) []string {
+	return unique(g.in[id], func(e Edge) string { return e.From })
+}
+
+// AllDependents returns all nodes from which id is reachable
+func (g *Graph) AllDependents(id string) []string {
+	return g.reachable(id, g.in, func(e Edge) string { return e.From })
+}
+
+// breadth first search starting at id. id itself is only
+// contained in the result if it is part of a cycle.
+func (g *Graph) reachable(id string, adj map[string][]Edge, next func(Edge) string) []string {
+	visited := map[string]bool{}
+	var res []string
+	queue := []string{id}
+
+	for len(queue) > 0 {
+		curr := queue[0]
+		queue = queue[1:]
+
+		for _, e := range adj[curr] {
+			n := next(e)
+			if visited[n] {
+				continue
+			}
+			visited[n] = true
+			res = append(res, n)
+			queue = append(queue, n)
+		}
+	}
+
+	return res
+}
+
+// Depths returns the length of the shortest path from the image
+// root to every reachable node. If there is no image root the
+// distance to the closest root is used.
+func (g *Graph) Depths() map[string]int {
+	return maps.Clone(g.cachedDepths())
+}
+
+func (g *Graph) cachedDepths() map[string]int {
+	if g.depths != nil {
+		return g.depths
+	}
+
+	starts := g.Roots()
+	if g.root != "" {
+		starts = []string{g.root}
+	}
+
+	depths := make(map[string]int, len(g.nodes))
+	queue := make([]string, 0, len(starts))
+	for _, s := range starts {
+		depths[s] = 0
+		queue = append(queue, s)
+	}
+
+	for len(queue) > 0 {
+		curr := queue[0]
+		queue = queue[1:]
+
+		for _, e := range g.out[curr] {
+			if _, ok := depths[e.To]; ok {
+				continue
+			}
+			depths[e.To] = depths[curr] + 1
+			queue = append(queue, e.To)
+		}
+	}
+
+	g.depths = depths
+	return depths
+}
+
+// Depth returns the depth of id or -1 if id is unreachable
+func (g *Graph) Depth(id string) int {
+	d, ok := g.cachedDepths()[id]
+	if !ok {
+		return -1
+	}
+	return d
+}
+
+// Paths returns all paths without repeated nodes from one node to another.
+// As the number of paths can grow exponentially limit caps the number of
+// returned paths. limit <= 0 means no limit.
+func (g *Graph) Paths(from, to string, limit int) [][]string {
+	if _, ok := g.nodes[to]; !ok {
+		return nil
+	}
+
+	// only nodes from which to is reachable can be on a path
+	canReach := map[string]bool{to: true}
+	for _, n := range g.AllDependents(to) {
+		canReach[n] = true
+	}
+	if !canReach[from] {
+		return nil
+	}
+
+	var paths [][]string
+	onPath := map[string]bool{}
+	path := []string{}
+
+	var walk func(curr string) bool
+	walk = func(curr string) bool {
+		path = append(path, curr)
+		onPath[curr] = true
+		defer func() {
+			path = path[:len(path)-1]
+			onPath[curr] = false
+		}()
+
+		if curr == to {
+			paths = append(paths, slices.Clone(path))
+			return limit <= 0 || len(paths) < limit
+		}
+
+		for _, n := range g.DirectDependencies(curr) {
+			if onPath[n] || !canReach[n] {
+				continue
+			}
+			if !walk(n) {
+				return false
+			}
+		}
+		return true
+	}
+
+	walk(from)
+
+	return paths
+}
+
+// PathsFromRoot returns the paths from the image root to id
+func (g *Graph) PathsFromRoot(id string, limit int) [][]string {
+	if g.root == "" {
+		return nil
+	}
+	return g.Paths(g.root, id, limit)
+}
+
+// Cycles returns the strongly connected components of the graph
+// that contain a cycle, i.e., more than one node or a self loop.
+func (g *Graph) Cycles() [][]string {
+	// tarjan's algorithm
+	index := 0
+	indices := map[string]int{}
+	lowlink := map[string]int{}
+	onStack := map[string]bool{}
+	stack := []string{}
+	var cycles [][]string
+
+	var strongConnect func(v string)
+	strongConnect = func(v string) {
+		indices[v] = index
+		lowlink[v] = index
+		index++
+		stack = append(stack, v)
+		onStack[v] = true
+
+		selfLoop := false
+		for _, e := range g.out[v] {
+			if e.To == v {
+				selfLoop = true
+			}
+			if _, ok := indices[e.To]; !ok {
+				strongConnect(e.To)
+				lowlink[v] = min(lowlink[v], lowlink[e.To])
+			} else if onStack[e.To] {
+				lowlink[v] = min(lowlink[v], indices[e.To])
+			}
+		}
+
+		if lowlink[v] != indices[v] {
+			return
+		}
+
+		var scc []string
+		for {
+			w := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[w] = false
+			scc = append(scc, w)
+			if w == v {
+				break
+			}
+		}
+
+		if len(scc) > 1 || selfLoop {
+			slices.Reverse(scc)
+			cycles = append(cycles, scc)
+		}
+	}
+
+	for _, id := range g.order {
+		if _, ok := indices[id]; !ok {
+			strongConnect(id)
+		}
+	}
+
+	return cycles
+}
+
+// IsPackage reports whether id is a component or the image root
+func (g *Graph) IsPackage(id string) bool {
+	n, ok := g.nodes[id]
+	return ok && (n.Component != nil || id == g.root)
+}
+
+// PackageView collapses all nodes that aren't packages, i.e., syft
+// file nodes. Edges between packages are kept. A package that contains
+// a file through which another package is evident, contains this
+// package in the collapsed view.
+func (g *Graph) PackageView() *Graph {
+	pv := empty()
+	pv.root = g.root
+
+	for _, id := range g.order {
+		if g.IsPackage(id) {
+			pv.addNode(id, g.nodes[id].Component)
+		}
+	}
+
+	for _, e := range g.edges {
+		if g.IsPackage(e.From) && g.IsPackage(e.To) {
+			pv.addEdge(e)
+		}
+	}
+
+	for _, id := range g.order {
+		if g.IsPackage(id) {
+			continue
+		}
+
+		var containers, evidenced []string
+		for _, e := range g.in[id] {
+			if !g.IsPackage(e.From) {
+				continue
+			}
+			switch e.Type {
+			case EvidentBy:
+				evidenced = append(evidenced, e.From)
+			default:
+				containers = append(containers, e.From)
+			}
+		}
+		for _, e := range g.out[id] {
+			if g.IsPackage(e.To) {
+				evidenced = append(evidenced, e.To)
+			}
+		}
+
+		for _, c := range containers {
+			for _, p := range evidenced {
+				if c != p {
+					pv.addEdge(Edge{From: c, To: p, Type: Contains})
+				}
+			}
+		}
+	}
+
+	return pv
+}
+
+func unique(edges []Edge, key func(Edge) string) []string {
+	var res []string
+	for _, e := range edges {
+		k := key(e)
+		if !slices.Contains(res, k) {
+			res = append(res, k)
+		}
+	}
+	return res
+}
@@ -0,0 +1,200 @@
+package graph
+
+import (
+	"slices"
+	"testing"
+
+	"sbom-processor/internal/sbom"
+)
+
+// image
+// ├── a
+// │   ├── b
+// │   │   └── d
+// │   └── c
+// │       └── d
+// └── file (contains)
+// e is a dependency of c (syft dependency-of edge)
+// f contains file2 which is evidence for g
+func testSbom() *sbom.CyclonedxSbom {
+	return &sbom.CyclonedxSbom{
+		Source: sbom.Source{Id: "image"},
+		Components: []sbom.Component{
+			{Id: "image", Name: "image"},
+			{Id: "a", Name: "a"},
+			{Id: "b", Name: "b"},
+			{Id: "c", Name: "c"},
+			{Id: "d", Name: "d"},
+			{Id: "e", Name: "e"},
+			{Id: "f", Name: "f"},
+			{Id: "g", Name: "g"},
+		},
+		Dependencies: []sbom.Dependency{
+			{Ref: "image", DependsOn: []sbom.Target{{Child: "a"}, {Child: "file", Type: Contains}, {Child: "f"}}},
+			{Ref: "a", DependsOn: []sbom.Target{{Child: "b"}, {Child: "c"}}},
+			{Ref: "b", DependsOn: []sbom.Target{{Child: "d"}}},
+			{Ref: "c", DependsOn: []sbom.Target{{Child: "d"}}},
+			{Ref: "e", DependsOn: []sbom.Target{{Child: "c", Type: DependencyOf}}},
+			{Ref: "f", DependsOn: []sbom.Target{{Child: "file2", Type: Contains}}},
+			{Ref: "g", DependsOn: []sbom.Target{{Child: "file2", Type: EvidentBy}}},
+		},
+	}
+}
+
+func TestGraphDependencies(t *testing.T) {
+	g := New(testSbom())
+
+	if g.ImageRoot() != "image" {
+		t.Fatalf("unexpected image root %s", g.ImageRoot())
+	}
+
+	direct := g.DirectDependencies("a")
+	if !slices.Equal(direct, []string{"b", "c"}) {
+		t.Fatalf("unexpected direct dependencies %v", direct)
+	}
+
+	transitive := g.TransitiveDependencies("a")
+	slices.Sort(transitive)
+	if !slices.Equal(transitive, []string{"d", "e"}) {
+		t.Fatalf("unexpected transitive dependencies %v", transitive)
+	}
+
+	dependents := g.Dependents("d")
+	if !slices.Equal(dependents, []string{"b", "c"}) {
+		t.Fatalf("unexpected dependents %v", dependents)
+	}
+
+	all := g.AllDependents("d")
+	slices.Sort(all)
+	if !slices.Equal(all, []string{"a", "b", "c", "image"}) {
+		t.Fatalf("unexpected dependents %v", all)
+	}
+}
+
+func TestGraphRoots(t *testing.T) {
+	g := New(testSbom())
+
+	roots := g.Roots()
+	if !slices.Equal(roots, []string{"image", "g"}) {
+		t.Fatalf("unexpected roots %v", roots)
+	}
+}
+
+func TestGraphDepth(t *testing.T) {
+	g := New(testSbom())
+
+	expected := map[string]int{"image": 0, "a": 1, "b": 2, "d": 3, "e": 3, "g": -1}
+	for id, depth := range expected {
+		if d := g.Depth(id); d != depth {
+			t.Fatalf("unexpected depth for %s. Expected %d, got %d", id, depth, d)
+		}
+	}
+}
+
+func TestGraphPaths(t *testing.T) {
+	g := New(testSbom())
+
+	paths := g.PathsFromRoot("d", 0)
+	if len(paths) != 2 {
+		t.Fatalf("two paths expected, got %v", paths)
+	}
+
+	if !slices.Equal(paths[0], []string{"image", "a", "b", "d"}) ||
+		!slices.Equal(paths[1], []string{"image", "a", "c", "d"}) {
+		t.Fatalf("unexpected paths %v", paths)
+	}
+
+	paths = g.PathsFromRoot("d", 1)
+	if len(paths) != 1 {
+		t.Fatalf("limit not applied %v", paths)
+	}
+}
+
+func TestGraphPathsUnreachable(t *testing.T) {
+	g := New(testSbom())
+
+	// g is only evident by a file
+	for _, to := range []string{"g", "unknown"} {
+		if paths := g.PathsFromRoot(to, 0); len(paths) != 0 {
+			t.Fatalf("no paths expected to %s, got %v", to, paths)
+		}
+	}
+
+	if paths := g.Paths("a", "f", 0); len(paths) != 0 {
+		t.Fatalf("no paths expected from a to f, got %v", paths)
+	}
+
+	if paths := g.Paths("a", "e", 0); len(paths) != 1 || !slices.Equal(paths[0], []string{"a", "c", "e"}) {
+		t.Fatalf("unexpected paths %v", paths)
+	}
+}
+
+func TestGraphDepthsCached(t *testing.T) {
+	g := New(testSbom())
+
+	depths := g.Depths()
+	depths["d"] = 42
+	if g.Depth("d") != 3 {
+		t.Fatalf("the cached depths must not be changed by callers, got %d", g.Depth("d"))
+	}
+
+	g.addEdge(Edge{From: "image", To: "d"})
+	if g.Depth("d") != 1 {
+		t.Fatalf("the cached depths must be reset when the graph changes, got %d", g.Depth("d"))
+	}
+}
+
+func TestGraphCycles(t *testing.T) {
+	s := testSbom()
+	if len(New(s).Cycles()) != 0 {
+		t.Fatalf("no cycles expected")
+	}
+
+	s.Dependencies = append(s.Dependencies,
+		sbom.Dependency{Ref: "d", DependsOn: []sbom.Target{{Child: "a"}}},
+		sbom.Dependency{Ref: "f", DependsOn: []sbom.Target{{Child: "f"}}},
+	)
+
+	cycles := New(s).Cycles()
+	if len(cycles) != 2 {
+		t.Fatalf("two cycles expected, got %v", cycles)
+	}
+
+	for _, c := range cycles {
+		slices.Sort(c)
+	}
+	slices.SortFunc(cycles, func(a, b []string) int { return len(b) - len(a) })
+
+	if !slices.Equal(cycles[0], []string{"a", "b", "c", "d"}) ||
+		!slices.Equal(cycles[1], []string{"f"}) {
+		t.Fatalf("unexpected cycles %v", cycles)
+	}
+}
+
+func TestGraphPackageView(t *testing.T) {
+	g := New(testSbom())
+
+	if g.IsPackage("file") || !g.IsPackage("a") {
+		t.Fatalf("files must not be packages")
+	}
+
+	pv := g.PackageView()
+
+	for _, n := range pv.Nodes() {
+		if n.Id == "file" || n.Id == "file2" {
+			t.Fatalf("file nodes must be collapsed")
+		}
+	}
+
+	if !slices.Equal(pv.DirectDependencies("f"), []string{"g"}) {
+		t.Fatalf("f must contain g in the package view, got %v", pv.DirectDependencies("f"))
+	}
+
+	if !slices.Equal(pv.DirectDependencies("image"), []string{"a", "f"}) {
+		t.Fatalf("unexpected dependencies of the image %v", pv.DirectDependencies("image"))
+	}
+
+	if pv.Depth("g") != 2 {
+		t.Fatalf("g must be reachable in the package view")
+	}
+}
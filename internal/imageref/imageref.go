@@ -0,0 +1,117 @@
+package imageref
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// registry of images without registry in their reference
+const DockerHub = "docker.io"
+
+// namespace of the official Docker Hub images, e.g., nginx
+const officialNamespace = "library/"
+
+// hosts that are aliases of Docker Hub
+var dockerHubAliases = []string{"index.docker.io", "registry-1.docker.io"}
+
+var (
+	// path component of a repository, e.g., library or nginx
+	pathComponent = regexp.MustCompile(`^[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*$`)
+	tagPattern    = regexp.MustCompile(`^\w[\w.-]{0,127}$`)
+	digestPattern = regexp.MustCompile(`^[a-z0-9]+(?:[.+_-][a-z0-9]+)*:[a-zA-Z0-9=_-]+$`)
+)
+
+// Reference to an OCI image as described in
+// https://github.com/distribution/reference
+// [registry/]repository[:tag][@digest]
+type Reference struct {
+	Registry   string `bson:"registry" json:"registry"`
+	Repository string `bson:"repository" json:"repository"`
+	Tag        string `bson:"tag,omitempty" json:"tag,omitempty"`
+	Digest     string `bson:"digest,omitempty" json:"digest,omitempty"`
+}
+
+// Parse parses and normalizes an image reference. References
+// without registry belong to Docker Hub, official images without
+// namespace to the library namespace, i.e., nginx:1.25 is
+// normalized to docker.io/library/nginx:1.25.
+func Parse(raw string) (*Reference, error) {
+	if raw == "" {
+		return nil, fmt.Errorf("empty image reference")
+	}
+
+	var r Reference
+	name, digest, found := strings.Cut(raw, "@")
+	if found {
+		if !digestPattern.MatchString(digest) {
+			return nil, fmt.Errorf("invalid image reference %s: invalid digest %s", raw, digest)
+		}
+		r.Digest = digest
+	}
+
+	// ports contain colons as well, tags follow the last slash
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		r.Tag = name[i+1:]
+		name = name[:i]
+		if !tagPattern.MatchString(r.Tag) {
+			return nil, fmt.Errorf("invalid image reference %s: invalid tag %s", raw, r.Tag)
+		}
+	}
+
+	r.Registry, r.Repository = DockerHub, name
+	if first, rest, found := strings.Cut(name, "/"); found && isRegistry(first) {
+		r.Registry, r.Repository = first, rest
+	}
+	for _, alias := range dockerHubAliases {
+		if r.Registry == alias {
+			r.Registry = DockerHub
+		}
+	}
+	if r.Registry == DockerHub && !strings.Contains(r.Repository, "/") {
+		r.Repository = officialNamespace + r.Repository
+	}
+
+	if r.Repository == "" {
+		return nil, fmt.Errorf("invalid image reference %s: missing repository", raw)
+	}
+	for _, c := range strings.Split(r.Repository, "/") {
+		if !pathComponent.MatchString(c) {
+			return nil, fmt.Errorf("invalid image reference %s: invalid repository component %q", raw, c)
+		}
+	}
+
+	return &r, nil
+}
+
+// the first component is a registry if it's a host name with
+// domain or port, or localhost
+func isRegistry(s string) bool {
+	return strings.ContainsAny(s, ".:") || s == "localhost"
+}
+
+// Name returns the fully qualified name without tag and digest,
+// e.g., docker.io/library/nginx
+func (r *Reference) Name() string {
+	return r.Registry + "/" + r.Repository
+}
+
+// Familiar returns the name as shown by docker, i.e., without the
+// Docker Hub registry and library namespace, e.g., nginx
+func (r *Reference) Familiar() string {
+	if r.Registry != DockerHub {
+		return r.Name()
+	}
+	return strings.TrimPrefix(r.Repository, officialNamespace)
+}
+
+func (r *Reference) String() string {
+	s := r.Name()
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
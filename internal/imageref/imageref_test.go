@@ -0,0 +1,50 @@
+package imageref
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	digest := "sha256:e4f8f5a3f2d7b9c1a0e6d5c4b3a29180f7e6d5c4b3a29180f7e6d5c4b3a29180"
+	tests := []struct {
+		raw      string
+		expected Reference
+		familiar string
+	}{
+		{"nginx", Reference{Registry: "docker.io", Repository: "library/nginx"}, "nginx"},
+		{"nginx:1.25", Reference{Registry: "docker.io", Repository: "library/nginx", Tag: "1.25"}, "nginx"},
+		{"docker.io/library/nginx:1.25", Reference{Registry: "docker.io", Repository: "library/nginx", Tag: "1.25"}, "nginx"},
+		{"index.docker.io/nginx", Reference{Registry: "docker.io", Repository: "library/nginx"}, "nginx"},
+		{"bitnami/redis:7.2", Reference{Registry: "docker.io", Repository: "bitnami/redis", Tag: "7.2"}, "bitnami/redis"},
+		{"localhost:5000/foo:1.0", Reference{Registry: "localhost:5000", Repository: "foo", Tag: "1.0"}, "localhost:5000/foo"},
+		{"localhost/foo", Reference{Registry: "localhost", Repository: "foo"}, "localhost/foo"},
+		{"ghcr.io/org/team/app@" + digest, Reference{Registry: "ghcr.io", Repository: "org/team/app", Digest: digest}, "ghcr.io/org/team/app"},
+		{"quay.io/org/app:v1@" + digest, Reference{Registry: "quay.io", Repository: "org/app", Tag: "v1", Digest: digest}, "quay.io/org/app"},
+	}
+
+	for _, test := range tests {
+		r, err := Parse(test.raw)
+		if err != nil {
+			t.Fatalf("parse of %s failed %s", test.raw, err)
+		}
+		if *r != test.expected {
+			t.Fatalf("expected %+v for %s, got %+v", test.expected, test.raw, *r)
+		}
+		if r.Familiar() != test.familiar {
+			t.Fatalf("expected familiar name %s, got %s", test.familiar, r.Familiar())
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, raw := range []string{"", "Nginx:1.25", "nginx:", "nginx@sha256", "ghcr.io/", "/tmp/rootfs", "nginx:1.25:2"} {
+		if r, err := Parse(raw); err == nil {
+			t.Fatalf("expected an error for %q, got %+v", raw, r)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	r, _ := Parse("nginx:1.25@sha256:abc")
+	if r.String() != "docker.io/library/nginx:1.25@sha256:abc" {
+		t.Fatalf("unexpected reference %s", r.String())
+	}
+}
@@ -0,0 +1,78 @@
+package json
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+)
+
+// ArrayWriter writes elements as a JSON array without keeping
+// them in memory. Close writes the closing bracket. Writes aren't
+// buffered, w should be buffered by the caller.
+type ArrayWriter[T any] struct {
+	w     io.Writer
+	count int
+}
+
+func NewArrayWriter[T any](w io.Writer) (*ArrayWriter[T], error) {
+	if _, err := io.WriteString(w, "["); err != nil {
+		return nil, err
+	}
+	return &ArrayWriter[T]{w: w}, nil
+}
+
+func (a *ArrayWriter[T]) Write(element T) error {
+	b, err := json.Marshal(element)
+	if err != nil {
+		return err
+	}
+
+	if a.count > 0 {
+		if _, err := io.WriteString(a.w, ",\n"); err != nil {
+			return err
+		}
+	}
+	a.count++
+
+	_, err = a.w.Write(b)
+	return err
+}
+
+func (a *ArrayWriter[T]) Close() error {
+	_, err := io.WriteString(a.w, "]\n")
+	return err
+}
+
+// DecodeArray decodes the elements of a JSON array one by one.
+// Iteration stops after the first error.
+func DecodeArray[T any](r io.Reader) iter.Seq2[*T, error] {
+	return func(yield func(*T, error) bool) {
+		decoder := json.NewDecoder(r)
+
+		t, err := decoder.Token()
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		if d, ok := t.(json.Delim); !ok || d != '[' {
+			yield(nil, fmt.Errorf("expected JSON array, got %v", t))
+			return
+		}
+
+		for decoder.More() {
+			var element T
+			if err := decoder.Decode(&element); err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(&element, nil) {
+				return
+			}
+		}
+
+		if _, err := decoder.Token(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
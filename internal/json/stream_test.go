@@ -0,0 +1,65 @@
+package json
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+type element struct {
+	Name string `json:"name"`
+}
+
+func TestArrayWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewArrayWriter[element](&buf)
+	if err != nil {
+		t.Fatalf("no error expected %s", err)
+	}
+	w.Write(element{Name: "a"})
+	w.Write(element{Name: "b"})
+	if err := w.Close(); err != nil {
+		t.Fatalf("no error expected %s", err)
+	}
+
+	var res []element
+	if err := json.Unmarshal(buf.Bytes(), &res); err != nil {
+		t.Fatalf("invalid JSON %s: %s", buf.String(), err)
+	}
+	if len(res) != 2 || res[1].Name != "b" {
+		t.Fatalf("unexpected elements %v", res)
+	}
+
+	buf.Reset()
+	w, _ = NewArrayWriter[element](&buf)
+	w.Close()
+	if strings.TrimSpace(buf.String()) != "[]" {
+		t.Fatalf("empty array expected, got %s", buf.String())
+	}
+}
+
+func TestDecodeArray(t *testing.T) {
+	var names []string
+	for e, err := range DecodeArray[element](strings.NewReader(`[{"name": "a"}, {"name": "b"}]`)) {
+		if err != nil {
+			t.Fatalf("no error expected %s", err)
+		}
+		names = append(names, e.Name)
+	}
+	if len(names) != 2 || names[0] != "a" {
+		t.Fatalf("unexpected elements %v", names)
+	}
+
+	for _, invalid := range []string{`{"name": "a"}`, `[{"name": "a"}, {"name": 1}]`, `[{"name": "a"}`, ``} {
+		failed := false
+		for _, err := range DecodeArray[element](strings.NewReader(invalid)) {
+			if err != nil {
+				failed = true
+			}
+		}
+		if !failed {
+			t.Fatalf("error expected for %s", invalid)
+		}
+	}
+}
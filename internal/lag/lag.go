@@ -0,0 +1,141 @@
+package lag
+
+import (
+	"time"
+
+	"sbom-processor/internal/sbom"
+	"sbom-processor/internal/semver"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type Status string
+
+const (
+	// the distance to the latest release was calculated
+	StatusOk Status = "ok"
+	// there is no version information for the component
+	StatusNoVersionData Status = "no_version_data"
+	// the used version or the known versions couldn't be compared
+	StatusInvalidVersion Status = "invalid_version"
+)
+
+type ComponentLag struct {
+	ComponentId string                  `bson:"component_id" json:"component_id"`
+	Name        string                  `bson:"name" json:"name"`
+	Type        string                  `bson:"type" json:"type"`
+	Version     string                  `bson:"version" json:"version"`
+	Status      Status                  `bson:"status" json:"status"`
+	Origin      string                  `bson:"origin,omitempty" json:"origin,omitempty"` // collection the versions were taken from
+	Distance    *semver.VersionDistance `bson:"distance,omitempty" json:"distance,omitempty"`
+}
+
+type Aggregate struct {
+	Components     int `bson:"components" json:"components"`
+	Ok             int `bson:"ok" json:"ok"`
+	NoVersionData  int `bson:"no_version_data" json:"no_version_data"`
+	InvalidVersion int `bson:"invalid_version" json:"invalid_version"`
+	// sums over all components with status ok
+	MissedReleases int64 `bson:"missed_releases" json:"missed_releases"`
+	MissedMajor    int64 `bson:"missed_major" json:"missed_major"`
+	MissedMinor    int64 `bson:"missed_minor" json:"missed_minor"`
+	MissedPatch    int64 `bson:"missed_patch" json:"missed_patch"`
+	// number of components with status ok that miss at least one release
+	Outdated           int     `bson:"outdated" json:"outdated"`
+	MeanMissedReleases float64 `bson:"mean_missed_releases" json:"mean_missed_releases"`
+	MaxMissedReleases  int64   `bson:"max_missed_releases" json:"max_missed_releases"`
+}
+
+// technical lag of all components of a single SBOM
+type SbomLag struct {
+	SbomId     bson.ObjectID  `bson:"sbom_id" json:"sbom_id"`
+	Source     sbom.Source    `bson:"source" json:"source"`
+	ComputedAt time.Time      `bson:"computed_at" json:"computed_at"`
+	Components []ComponentLag `bson:"components" json:"components"`
+	Aggregate  Aggregate      `bson:"aggregate" json:"aggregate"`
+}
+
+// VersionLookup provides all known versions of a component.
+// A nil slice without error means that no version data is available.
+// origin names the source of the versions, e.g., the collection.
+type VersionLookup interface {
+	Lookup(c *sbom.Component) (versions []string, origin string, err error)
+}
+
+// Compute joins every component of s with its known versions and
+// calculates the distance to the latest release. Components without
+// version data are kept and marked with StatusNoVersionData.
+func Compute(s *sbom.StoredSbom, lookup VersionLookup) *SbomLag {
+	res := SbomLag{
+		SbomId:     s.Id,
+		Source:     s.Source,
+		ComputedAt: time.Now().UTC(),
+		Components: make([]ComponentLag, 0, len(s.Components)),
+	}
+
+	for i := range s.Components {
+		c := &s.Components[i]
+		res.Components = append(res.Components, computeComponent(c, lookup))
+	}
+
+	res.Aggregate = aggregate(res.Components)
+
+	return &res
+}
+
+func computeComponent(c *sbom.Component, lookup VersionLookup) ComponentLag {
+	cl := ComponentLag{
+		ComponentId: c.Id,
+		Name:        c.Name,
+		Type:        c.Type,
+		Version:     c.Version,
+		Status:      StatusNoVersionData,
+	}
+
+	versions, origin, err := lookup.Lookup(c)
+	if err != nil || len(versions) == 0 {
+		return cl
+	}
+	cl.Origin = origin
+
+	// os packages aren't versioned with semver, e.g., 1:2.3-4
+	d, err := semver.GetVersionDistanceFor(c.Type, c.Version, versions)
+	if err != nil {
+		cl.Status = StatusInvalidVersion
+		return cl
+	}
+
+	cl.Status = StatusOk
+	cl.Distance = d
+
+	return cl
+}
+
+func aggregate(components []ComponentLag) Aggregate {
+	a := Aggregate{Components: len(components)}
+
+	for _, c := range components {
+		switch c.Status {
+		case StatusOk:
+			a.Ok += 1
+			a.MissedReleases += c.Distance.MissedReleases
+			a.MissedMajor += c.Distance.MissedMajor
+			a.MissedMinor += c.Distance.MissedMinor
+			a.MissedPatch += c.Distance.MissedPatch
+			a.MaxMissedReleases = max(a.MaxMissedReleases, c.Distance.MissedReleases)
+			if c.Distance.MissedReleases > 0 {
+				a.Outdated += 1
+			}
+		case StatusNoVersionData:
+			a.NoVersionData += 1
+		case StatusInvalidVersion:
+			a.InvalidVersion += 1
+		}
+	}
+
+	if a.Ok > 0 {
+		a.MeanMissedReleases = float64(a.MissedReleases) / float64(a.Ok)
+	}
+
+	return a
+}
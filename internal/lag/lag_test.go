@@ -0,0 +1,105 @@
+package lag
+
+import (
+	"fmt"
+	"sbom-processor/internal/sbom"
+	"testing"
+)
+
+type mapLookup map[string][]string
+
+func (m mapLookup) Lookup(c *sbom.Component) ([]string, string, error) {
+	if c.Name == "broken" {
+		return nil, "map", fmt.Errorf("lookup failed")
+	}
+	return m[c.Name], "map", nil
+}
+
+func TestCompute(t *testing.T) {
+	s := sbom.StoredSbom{
+		CyclonedxSbom: sbom.CyclonedxSbom{
+			Components: []sbom.Component{
+				{Id: "1", Name: "outdated", Version: "1.0.0"},
+				{Id: "2", Name: "latest", Version: "2.0.0"},
+				{Id: "3", Name: "unknown", Version: "1.0.0"},
+				{Id: "4", Name: "broken", Version: "1.0.0"},
+				{Id: "5", Name: "invalid", Version: "not a version"},
+			},
+		},
+	}
+
+	lookup := mapLookup{
+		"outdated": {"1.0.0", "1.0.1", "1.2.0", "2.0.0"},
+		"latest":   {"1.0.0", "2.0.0"},
+		"invalid":  {"1.0.0"},
+	}
+
+	l := Compute(&s, lookup)
+
+	if len(l.Components) != len(s.Components) {
+		t.Fatalf("every component must be contained in the result, got %d", len(l.Components))
+	}
+
+	expected := []Status{StatusOk, StatusOk, StatusNoVersionData, StatusNoVersionData, StatusInvalidVersion}
+	for i, c := range l.Components {
+		if c.Status != expected[i] {
+			t.Fatalf("unexpected status for %s. Expected %s, got %s", c.Name, expected[i], c.Status)
+		}
+	}
+
+	if l.Components[0].Distance.MissedReleases != 3 {
+		t.Fatalf("unexpected number of missed releases. Expected 3, got %d", l.Components[0].Distance.MissedReleases)
+	}
+
+	a := l.Aggregate
+	if a.Components != 5 || a.Ok != 2 || a.NoVersionData != 2 || a.InvalidVersion != 1 {
+		t.Fatalf("unexpected status counts %+v", a)
+	}
+
+	if a.MissedReleases != 3 || a.MaxMissedReleases != 3 || a.Outdated != 1 {
+		t.Fatalf("unexpected lag aggregates %+v", a)
+	}
+
+	if a.MeanMissedReleases != 1.5 {
+		t.Fatalf("unexpected mean missed releases. Expected 1.5, got %f", a.MeanMissedReleases)
+	}
+}
+
+func TestComputeDebian(t *testing.T) {
+	s := sbom.StoredSbom{
+		CyclonedxSbom: sbom.CyclonedxSbom{
+			Components: []sbom.Component{
+				{Id: "1", Name: "libc6", Type: "deb", Version: "2.36-9+deb12u4"},
+				{Id: "2", Name: "openssh", Type: "deb", Version: "1:9.2p1-2"},
+				{Id: "3", Name: "invalid", Type: "deb", Version: "not a version"},
+			},
+		},
+	}
+
+	lookup := mapLookup{
+		"libc6":   {"2.36-9", "2.36-9+deb12u3", "2.36-9+deb12u4", "2.36-9+deb12u7", "2.37-1~bpo12+1", "2.37-1"},
+		"openssh": {"1:9.2p1-2", "1:9.2p1-2+deb12u3", "1:9.6p1-3", "10.0-1"},
+		"invalid": {"1.0-1"},
+	}
+
+	l := Compute(&s, lookup)
+
+	expected := []Status{StatusOk, StatusOk, StatusInvalidVersion}
+	for i, c := range l.Components {
+		if c.Status != expected[i] {
+			t.Fatalf("unexpected status for %s. Expected %s, got %s", c.Name, expected[i], c.Status)
+		}
+	}
+
+	// 2.37-1~bpo12+1 is a backport and sorts before 2.37-1
+	libc := l.Components[0].Distance
+	if libc.MissedReleases != 3 || libc.MissedMajor != 0 || libc.MissedMinor != 1 || libc.MissedPatch != 0 {
+		t.Fatalf("unexpected distance of libc6 %+v", libc)
+	}
+
+	// 10.0-1 has no epoch and sorts before all versions with epoch 1
+	openssh := l.Components[1].Distance
+	if openssh.MissedReleases != 2 || openssh.MissedMajor != 0 || openssh.MissedMinor != 4 {
+		t.Fatalf("unexpected distance of openssh %+v", openssh)
+	}
+}
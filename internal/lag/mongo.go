@@ -0,0 +1,112 @@
+package lag
+
+import (
+	"context"
+
+	"sbom-processor/internal/deps"
+	"sbom-processor/internal/mvn"
+	"sbom-processor/internal/sbom"
+	"sbom-processor/internal/semver"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	OriginVersions     = "versions"
+	OriginDepsMetadata = "deps_metadata"
+)
+
+// MongoLookup reads the versions from the collections filled
+// by the version harvesters.
+type MongoLookup struct {
+	// semver.ComponentVersions keyed by the component id
+	Versions *mongo.Collection
+	// deps.Deps keyed by name and system
+	DepsMetadata *mongo.Collection
+	// mvn.MvnCacheEntry used to map java archives to their maven coordinates
+	MvnMirror *mongo.Collection
+
+	Ctx context.Context
+}
+
+func (l *MongoLookup) Lookup(c *sbom.Component) ([]string, string, error) {
+	if c.Type == "java-archive" {
+		return l.lookupMaven(c)
+	}
+
+	var compVers semver.ComponentVersions
+	err := l.Versions.FindOne(l.Ctx, bson.D{{Key: "component_id", Value: c.Id}}).Decode(&compVers)
+	if err == mongo.ErrNoDocuments {
+		return nil, OriginVersions, nil
+	}
+	if err != nil {
+		return nil, OriginVersions, err
+	}
+
+	versions := make([]string, len(compVers.Versions))
+	for i, v := range compVers.Versions {
+		versions[i] = v.Version
+	}
+
+	return versions, OriginVersions, nil
+}
+
+// java archives are only known by their artifact name. we use the
+// maven mirror to get the group and query deps_metadata with it.
+func (l *MongoLookup) lookupMaven(c *sbom.Component) ([]string, string, error) {
+	var entry mvn.MvnCacheEntry
+	err := mongo.ErrNoDocuments
+	if sha1 := c.Digest("sha1"); sha1 != "" {
+		err = l.MvnMirror.FindOne(l.Ctx, bson.D{{Key: "sha1", Value: sha1}}).Decode(&entry)
+	}
+	if err == mongo.ErrNoDocuments {
+		err = l.MvnMirror.FindOne(l.Ctx, bson.D{{Key: "name", Value: c.Name}}).Decode(&entry)
+	}
+	if err == mongo.ErrNoDocuments || (err == nil && len(entry.Docs) == 0) {
+		return nil, OriginDepsMetadata, nil
+	}
+	if err != nil {
+		return nil, OriginDepsMetadata, err
+	}
+
+	doc := entry.Docs[0]
+	var d deps.Deps
+	err = l.DepsMetadata.FindOne(l.Ctx, bson.D{
+		{Key: "name", Value: doc.Group + ":" + doc.Artifact},
+		{Key: "system", Value: "MAVEN"},
+	}).Decode(&d)
+	if err == mongo.ErrNoDocuments {
+		return nil, OriginDepsMetadata, nil
+	}
+	if err != nil {
+		return nil, OriginDepsMetadata, err
+	}
+
+	versions := make([]string, len(d.Versions))
+	for i, v := range d.Versions {
+		versions[i] = v.Version
+	}
+
+	return versions, OriginDepsMetadata, nil
+}
+
+// Store replaces the lag of the SBOMs in coll, so that repeated
+// calculations keep a single document per SBOM
+func Store(ctx context.Context, coll *mongo.Collection, l []*SbomLag) error {
+	if len(l) == 0 {
+		return nil
+	}
+
+	models := make([]mongo.WriteModel, len(l))
+	for i, doc := range l {
+		models[i] = mongo.NewReplaceOneModel().
+			SetFilter(bson.D{{Key: "sbom_id", Value: doc.SbomId}}).
+			SetReplacement(doc).
+			SetUpsert(true)
+	}
+
+	_, err := coll.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	return err
+}
@@ -0,0 +1,97 @@
+package license
+
+import (
+	"context"
+	"sync"
+
+	"sbom-processor/internal/deps"
+	"sbom-processor/internal/purl"
+	"sbom-processor/internal/sbom"
+)
+
+// purl types and their deps.dev system
+var depsSystems = map[string]string{
+	"maven":  "MAVEN",
+	"npm":    "NPM",
+	"pypi":   "PYPI",
+	"golang": "GO",
+	"cargo":  "CARGO",
+	"nuget":  "NUGET",
+}
+
+// DepsLookup queries the licenses of a package version from deps.dev.
+// Results are cached since the same versions occur in many images.
+type DepsLookup struct {
+	Ctx context.Context
+
+	mu    sync.Mutex
+	cache map[deps.VersionKey][]string
+	query func(ctx context.Context, key deps.VersionKey) (*deps.VersionInfo, error)
+}
+
+func NewDepsLookup(ctx context.Context) *DepsLookup {
+	return &DepsLookup{
+		Ctx:   ctx,
+		cache: make(map[deps.VersionKey][]string),
+		query: deps.QueryVersion,
+	}
+}
+
+func (l *DepsLookup) Licenses(c *sbom.Component) ([]string, error) {
+	key, ok := versionKey(c)
+	if !ok {
+		return nil, nil
+	}
+
+	l.mu.Lock()
+	licenses, ok := l.cache[key]
+	l.mu.Unlock()
+	if ok {
+		return licenses, nil
+	}
+
+	info, err := l.query(l.Ctx, key)
+	if err != nil {
+		// not cached, the request may succeed for the next image
+		return nil, err
+	}
+	if info != nil {
+		licenses = info.Licenses
+	}
+
+	l.mu.Lock()
+	l.cache[key] = licenses
+	l.mu.Unlock()
+
+	return licenses, nil
+}
+
+// returns the deps.dev version key of c. only components with a
+// purl of a system known to deps.dev can be queried.
+func versionKey(c *sbom.Component) (deps.VersionKey, bool) {
+	p, err := purl.Parse(c.Purl)
+	if err != nil {
+		return deps.VersionKey{}, false
+	}
+
+	system, ok := depsSystems[p.Type]
+	if !ok {
+		return deps.VersionKey{}, false
+	}
+
+	key := deps.VersionKey{System: system, Name: p.Name, Version: p.Version}
+	if key.Version == "" {
+		key.Version = c.Version
+	}
+
+	if p.Namespace != "" {
+		switch system {
+		case "MAVEN":
+			key.Name = p.Namespace + ":" + p.Name
+		case "NPM", "GO":
+			key.Name = p.Namespace + "/" + p.Name
+		}
+	}
+
+	return key, key.Version != ""
+}
@@ -0,0 +1,287 @@
+package license
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const (
+	And = "AND"
+	Or  = "OR"
+)
+
+// Expression is a normalized SPDX license expression. It is either
+// a single license, optionally with an exception, or a compound of
+// its arguments.
+type Expression struct {
+	Op        string // AND, OR, or empty for a single license
+	License   string
+	Exception string
+	Args      []*Expression
+}
+
+// "or later" and "or any later version" are part of the license
+// name and must not be read as OR operator
+var orLaterSuffix = regexp.MustCompile(`(?i)\s+or\s+(any\s+)?later(\s+version)?`)
+
+// Parse reads a license expression and normalizes all license names
+// to SPDX ids. Operators are accepted in any case, license names may
+// contain spaces, e.g., "Apache License 2.0 or MIT".
+func Parse(raw string) (*Expression, error) {
+	p := parser{tokens: tokenize(orLaterSuffix.ReplaceAllString(raw, "+"))}
+	if len(p.tokens) == 0 {
+		return nil, fmt.Errorf("empty license expression")
+	}
+
+	e, err := p.or()
+	if err != nil {
+		return nil, fmt.Errorf("invalid license expression %s: %w", raw, err)
+	}
+	if !p.done() {
+		return nil, fmt.Errorf("invalid license expression %s: unexpected %s", raw, p.peek())
+	}
+
+	return e, nil
+}
+
+// splits raw into parentheses, operators, and license names.
+// consecutive words that aren't operators form one license name.
+func tokenize(raw string) []string {
+	raw = strings.NewReplacer("(", " ( ", ")", " ) ").Replace(raw)
+
+	var tokens []string
+	var name []string
+	flush := func() {
+		if len(name) > 0 {
+			tokens = append(tokens, strings.Join(name, " "))
+			name = nil
+		}
+	}
+
+	for _, w := range strings.Fields(raw) {
+		switch strings.ToUpper(w) {
+		case "(", ")", And, Or, "WITH":
+			flush()
+			tokens = append(tokens, strings.ToUpper(w))
+		default:
+			name = append(name, w)
+		}
+	}
+	flush()
+
+	return tokens
+}
+
+func isOperator(t string) bool {
+	return t == "(" || t == ")" || t == And || t == Or || t == "WITH"
+}
+
+// recursive descent parser, WITH binds stronger than AND,
+// AND binds stronger than OR
+type parser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *parser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *parser) peek() string {
+	if p.done() {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() string {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+func (p *parser) or() (*Expression, error) {
+	return p.compound(Or, p.and)
+}
+
+func (p *parser) and() (*Expression, error) {
+	return p.compound(And, p.with)
+}
+
+func (p *parser) compound(op string, operand func() (*Expression, error)) (*Expression, error) {
+	var args []*Expression
+	for {
+		e, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, e)
+
+		if p.peek() != op {
+			break
+		}
+		p.next()
+	}
+
+	return compound(op, args), nil
+}
+
+func (p *parser) with() (*Expression, error) {
+	e, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.peek() != "WITH" {
+		return e, nil
+	}
+	p.next()
+
+	exception := p.next()
+	if exception == "" || isOperator(exception) {
+		return nil, fmt.Errorf("exception expected after WITH")
+	}
+	if e.Op != "" || e.Exception != "" {
+		return nil, fmt.Errorf("WITH requires a single license")
+	}
+	e.Exception, _ = exceptionId(exception)
+
+	return e, nil
+}
+
+func (p *parser) primary() (*Expression, error) {
+	t := p.next()
+	switch {
+	case t == "":
+		return nil, fmt.Errorf("unexpected end")
+	case t == "(":
+		e, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		return e, nil
+	case isOperator(t):
+		return nil, fmt.Errorf("unexpected %s", t)
+	default:
+		id, _ := Id(t)
+		return &Expression{License: id}, nil
+	}
+}
+
+// returns the compound of args. nested compounds with the same
+// operator are flattened and duplicate arguments removed.
+func compound(op string, args []*Expression) *Expression {
+	var flat []*Expression
+	seen := map[string]bool{}
+	for _, a := range args {
+		nested := []*Expression{a}
+		if a.Op == op {
+			nested = a.Args
+		}
+		for _, n := range nested {
+			if s := n.String(); !seen[s] {
+				seen[s] = true
+				flat = append(flat, n)
+			}
+		}
+	}
+
+	if len(flat) == 1 {
+		return flat[0]
+	}
+	return &Expression{Op: op, Args: flat}
+}
+
+// Combine returns the conjunction of all expressions, e.g., the
+// licenses a package declares in multiple places.
+func Combine(expressions []*Expression) *Expression {
+	if len(expressions) == 0 {
+		return nil
+	}
+	return compound(And, expressions)
+}
+
+// String returns the expression in SPDX syntax. compound arguments
+// are put in parentheses.
+func (e *Expression) String() string {
+	if e.Op == "" {
+		if e.Exception != "" {
+			return e.License + " WITH " + e.Exception
+		}
+		return e.License
+	}
+
+	parts := make([]string, len(e.Args))
+	for i, a := range e.Args {
+		if a.Op != "" {
+			parts[i] = "(" + a.String() + ")"
+		} else {
+			parts[i] = a.String()
+		}
+	}
+	return strings.Join(parts, " "+e.Op+" ")
+}
+
+// Licenses returns the distinct license ids of the expression
+func (e *Expression) Licenses() []string {
+	if e.Op == "" {
+		return []string{e.License}
+	}
+
+	var res []string
+	for _, a := range e.Args {
+		for _, l := range a.Licenses() {
+			if !slices.Contains(res, l) {
+				res = append(res, l)
+			}
+		}
+	}
+	return res
+}
+
+var rank = map[Category]int{
+	Permissive:     0,
+	WeakCopyleft:   1,
+	StrongCopyleft: 2,
+}
+
+// Category returns the category of the expression. For a choice (OR)
+// the least restrictive known option applies, for a conjunction (AND)
+// the most restrictive one. A conjunction with an unknown license is
+// unknown. Linking exceptions lower strong copyleft to weak copyleft.
+func (e *Expression) Category() Category {
+	switch e.Op {
+	case "":
+		c := CategoryOf(e.License)
+		if c == StrongCopyleft && slices.Contains(exceptions, e.Exception) {
+			return WeakCopyleft
+		}
+		return c
+	case Or:
+		res := Unknown
+		for _, a := range e.Args {
+			c := a.Category()
+			if c != Unknown && (res == Unknown || rank[c] < rank[res]) {
+				res = c
+			}
+		}
+		return res
+	default:
+		res := Permissive
+		for _, a := range e.Args {
+			c := a.Category()
+			if c == Unknown {
+				return Unknown
+			}
+			if rank[c] > rank[res] {
+				res = c
+			}
+		}
+		return res
+	}
+}
@@ -0,0 +1,100 @@
+package license
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestId(t *testing.T) {
+	tests := map[string]string{
+		"Apache 2":                    "Apache-2.0",
+		"Apache License, Version 2.0": "Apache-2.0",
+		"The Apache Software License, Version 2.0": "Apache-2.0",
+		"apache-2.0":                             "Apache-2.0",
+		"MIT License":                            "MIT",
+		"Expat":                                  "MIT",
+		"BSD-3-clause":                           "BSD-3-Clause",
+		"GPL-2":                                  "GPL-2.0-only",
+		"GPLv2":                                  "GPL-2.0-only",
+		"GPL-2.0":                                "GPL-2.0-only",
+		"GPL-2+":                                 "GPL-2.0-or-later",
+		"LGPL-2.1+":                              "LGPL-2.1-or-later",
+		"GNU Lesser General Public License v2.1": "LGPL-2.1-only",
+		"Apache-2.0+":                            "Apache-2.0+",
+		"public-domain":                          "LicenseRef-public-domain",
+	}
+
+	for name, expected := range tests {
+		id, known := Id(name)
+		if id != expected || !known {
+			t.Fatalf("unexpected id for %s. Expected %s, got %s", name, expected, id)
+		}
+	}
+
+	id, known := Id("Custom License (c) ACME")
+	if known || id != "LicenseRef-Custom-License-c-ACME" {
+		t.Fatalf("unknown licenses must be a LicenseRef, got %s", id)
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := map[string]string{
+		"MIT":                "MIT",
+		"Apache 2 or MIT":    "Apache-2.0 OR MIT",
+		"GPL-2+ or Artistic": "GPL-2.0-or-later OR Artistic-1.0",
+		"GPL-2 or later":     "GPL-2.0-or-later",
+		"GNU General Public License v2 or any later version": "GPL-2.0-or-later",
+		"(MIT OR Apache-2.0) AND BSD-3-Clause":               "(MIT OR Apache-2.0) AND BSD-3-Clause",
+		"MIT OR Apache-2.0 AND BSD-3-Clause":                 "MIT OR (Apache-2.0 AND BSD-3-Clause)",
+		"MIT AND (BSD-3-Clause AND Zlib)":                    "MIT AND BSD-3-Clause AND Zlib",
+		"MIT OR MIT":                                         "MIT",
+		"GPL-2.0 WITH classpath exception 2.0":               "GPL-2.0-only WITH Classpath-exception-2.0",
+	}
+
+	for raw, expected := range tests {
+		e, err := Parse(raw)
+		if err != nil {
+			t.Fatalf("no error expected for %s, got %s", raw, err)
+		}
+		if e.String() != expected {
+			t.Fatalf("unexpected expression for %s. Expected %s, got %s", raw, expected, e.String())
+		}
+	}
+
+	for _, invalid := range []string{"", "(MIT", "MIT OR", "AND MIT", "MIT WITH", "(MIT OR Zlib) WITH LLVM-exception"} {
+		if _, err := Parse(invalid); err == nil {
+			t.Fatalf("error expected for %s", invalid)
+		}
+	}
+}
+
+func TestCategory(t *testing.T) {
+	tests := map[string]Category{
+		"MIT":          Permissive,
+		"LGPL-2.1+":    WeakCopyleft,
+		"GPL-3.0-only": StrongCopyleft,
+		"GPL-2.0-only WITH Classpath-exception-2.0": WeakCopyleft,
+		"GPL-2+ OR Artistic":                        Permissive,
+		"MIT AND GPL-2":                             StrongCopyleft,
+		"MIT AND Custom":                            Unknown,
+		"Custom OR LGPL-3.0":                        WeakCopyleft,
+		"Custom":                                    Unknown,
+	}
+
+	for raw, expected := range tests {
+		e, err := Parse(raw)
+		if err != nil {
+			t.Fatalf("no error expected for %s, got %s", raw, err)
+		}
+		if c := e.Category(); c != expected {
+			t.Fatalf("unexpected category for %s. Expected %s, got %s", raw, expected, c)
+		}
+	}
+}
+
+func TestLicenses(t *testing.T) {
+	e, _ := Parse("(MIT OR Apache-2.0) AND (MIT OR Zlib)")
+	if !slices.Equal(e.Licenses(), []string{"MIT", "Apache-2.0", "Zlib"}) {
+		t.Fatalf("unexpected licenses %v", e.Licenses())
+	}
+}
@@ -0,0 +1,169 @@
+package license
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"sbom-processor/internal/sbom"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const (
+	OriginSyft    = "syft"
+	OriginDepsDev = "deps_dev"
+)
+
+type ComponentLicense struct {
+	ComponentId string   `bson:"component_id" json:"component_id"`
+	Name        string   `bson:"name" json:"name"`
+	Type        string   `bson:"type" json:"type"`
+	Version     string   `bson:"version" json:"version"`
+	Declared    []string `bson:"declared" json:"declared"`                         // licenses as found in the source
+	Expression  string   `bson:"expression,omitempty" json:"expression,omitempty"` // normalized SPDX expression
+	Licenses    []string `bson:"licenses" json:"licenses"`                         // SPDX ids contained in the expression
+	Category    Category `bson:"category" json:"category"`
+	Origin      string   `bson:"origin,omitempty" json:"origin,omitempty"`
+}
+
+type LicenseCount struct {
+	License string `bson:"license" json:"license"`
+	Count   int    `bson:"count" json:"count"`
+}
+
+type Aggregate struct {
+	Components     int `bson:"components" json:"components"`
+	Permissive     int `bson:"permissive" json:"permissive"`
+	WeakCopyleft   int `bson:"weak_copyleft" json:"weak_copyleft"`
+	StrongCopyleft int `bson:"strong_copyleft" json:"strong_copyleft"`
+	Unknown        int `bson:"unknown" json:"unknown"`
+	// components without any license information
+	Undeclared int `bson:"undeclared" json:"undeclared"`
+	// number of components per license, most frequent first
+	Licenses []LicenseCount `bson:"licenses" json:"licenses"`
+}
+
+type SbomLicenses struct {
+	SbomId     bson.ObjectID      `bson:"sbom_id" json:"sbom_id"`
+	Source     sbom.Source        `bson:"source" json:"source"`
+	ComputedAt time.Time          `bson:"computed_at" json:"computed_at"`
+	Components []ComponentLicense `bson:"components" json:"components"`
+	Aggregate  Aggregate          `bson:"aggregate" json:"aggregate"`
+}
+
+// Lookup returns the licenses of components that don't declare any
+type Lookup interface {
+	Licenses(c *sbom.Component) ([]string, error)
+}
+
+// Compute normalizes the licenses of all components of s. Licenses
+// missing in the SBOM are taken from fallback if it isn't nil.
+func Compute(s *sbom.StoredSbom, fallback Lookup) *SbomLicenses {
+	res := SbomLicenses{
+		SbomId:     s.Id,
+		Source:     s.Source,
+		ComputedAt: time.Now().UTC(),
+		Components: make([]ComponentLicense, 0, len(s.Components)),
+	}
+
+	for i := range s.Components {
+		res.Components = append(res.Components, computeComponent(&s.Components[i], fallback))
+	}
+
+	res.Aggregate = aggregate(res.Components)
+
+	return &res
+}
+
+func computeComponent(c *sbom.Component, fallback Lookup) ComponentLicense {
+	cl := ComponentLicense{
+		ComponentId: c.Id,
+		Name:        c.Name,
+		Type:        c.Type,
+		Version:     c.Version,
+		Declared:    c.DeclaredLicenses(),
+		Licenses:    []string{},
+		Category:    Unknown,
+	}
+
+	if len(cl.Declared) > 0 {
+		cl.Origin = OriginSyft
+	} else if fallback != nil {
+		declared, err := fallback.Licenses(c)
+		if err == nil && len(declared) > 0 {
+			cl.Declared = declared
+			cl.Origin = OriginDepsDev
+		}
+	}
+
+	if e := Resolve(cl.Declared); e != nil {
+		cl.Expression = e.String()
+		cl.Licenses = e.Licenses()
+		cl.Category = e.Category()
+	}
+
+	return cl
+}
+
+// Resolve combines the declared licenses of a component into one
+// expression. All declared licenses apply, values that can't be
+// parsed are kept as a single LicenseRef. Returns nil if nothing
+// is declared.
+func Resolve(declared []string) *Expression {
+	var expressions []*Expression
+	for _, d := range declared {
+		// SPDX values for missing license information
+		if d == "" || d == "NOASSERTION" || d == "NONE" {
+			continue
+		}
+
+		e, err := Parse(d)
+		if err != nil {
+			id, _ := Id(d)
+			e = &Expression{License: id}
+		}
+		expressions = append(expressions, e)
+	}
+
+	return Combine(expressions)
+}
+
+func aggregate(components []ComponentLicense) Aggregate {
+	a := Aggregate{Components: len(components)}
+
+	counts := map[string]int{}
+	for _, c := range components {
+		if c.Expression == "" {
+			a.Undeclared += 1
+		}
+
+		switch c.Category {
+		case Permissive:
+			a.Permissive += 1
+		case WeakCopyleft:
+			a.WeakCopyleft += 1
+		case StrongCopyleft:
+			a.StrongCopyleft += 1
+		default:
+			a.Unknown += 1
+		}
+
+		for _, l := range c.Licenses {
+			counts[l] += 1
+		}
+	}
+
+	a.Licenses = make([]LicenseCount, 0, len(counts))
+	for l, n := range counts {
+		a.Licenses = append(a.Licenses, LicenseCount{License: l, Count: n})
+	}
+	slices.SortFunc(a.Licenses, func(x, y LicenseCount) int {
+		if c := cmp.Compare(y.Count, x.Count); c != 0 {
+			return c
+		}
+		return cmp.Compare(x.License, y.License)
+	})
+
+	return a
+}
@@ -0,0 +1,104 @@
+package license
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+
+	"sbom-processor/internal/deps"
+	"sbom-processor/internal/sbom"
+)
+
+type mapLookup map[string][]string
+
+func (l mapLookup) Licenses(c *sbom.Component) ([]string, error) {
+	return l[c.Name], nil
+}
+
+func TestCompute(t *testing.T) {
+	s := sbom.StoredSbom{
+		CyclonedxSbom: sbom.CyclonedxSbom{
+			Components: []sbom.Component{
+				{Id: "1", Name: "libc6", Type: "deb", Licenses: []sbom.License{{Value: "GPL-2"}, {Value: "LGPL-2.1+"}}},
+				{Id: "2", Name: "guice", Type: "java-archive"},
+				{Id: "3", Name: "zlib1g", Type: "deb", Licenses: []sbom.License{{Value: "Zlib", SpdxExpression: "Zlib"}}},
+				{Id: "4", Name: "file", Type: "binary"},
+			},
+		},
+	}
+
+	l := Compute(&s, mapLookup{"guice": {"Apache-2.0"}})
+
+	expected := []struct {
+		expression string
+		category   Category
+		origin     string
+	}{
+		{"GPL-2.0-only AND LGPL-2.1-or-later", StrongCopyleft, OriginSyft},
+		{"Apache-2.0", Permissive, OriginDepsDev},
+		{"Zlib", Permissive, OriginSyft},
+		{"", Unknown, ""},
+	}
+
+	for i, e := range expected {
+		c := l.Components[i]
+		if c.Expression != e.expression || c.Category != e.category || c.Origin != e.origin {
+			t.Fatalf("unexpected license of %s %+v", c.Name, c)
+		}
+	}
+
+	a := l.Aggregate
+	if a.Components != 4 || a.Permissive != 2 || a.StrongCopyleft != 1 || a.Unknown != 1 || a.Undeclared != 1 {
+		t.Fatalf("unexpected aggregate %+v", a)
+	}
+
+	if len(a.Licenses) != 4 || a.Licenses[0].License != "Apache-2.0" || a.Licenses[0].Count != 1 {
+		t.Fatalf("unexpected license counts %+v", a.Licenses)
+	}
+}
+
+func TestComputeWithoutFallback(t *testing.T) {
+	s := sbom.StoredSbom{CyclonedxSbom: sbom.CyclonedxSbom{Components: []sbom.Component{{Id: "1", Name: "guice"}}}}
+
+	l := Compute(&s, nil)
+	if l.Components[0].Category != Unknown || l.Aggregate.Undeclared != 1 {
+		t.Fatalf("unexpected result %+v", l.Components[0])
+	}
+}
+
+func TestDepsLookup(t *testing.T) {
+	var queried []deps.VersionKey
+	l := NewDepsLookup(context.Background())
+	l.query = func(ctx context.Context, key deps.VersionKey) (*deps.VersionInfo, error) {
+		queried = append(queried, key)
+		if key.System == "NPM" {
+			return nil, fmt.Errorf("request failed")
+		}
+		return &deps.VersionInfo{VersionKey: key, Licenses: []string{"Apache-2.0"}}, nil
+	}
+
+	guice := sbom.Component{Name: "guice", Version: "4.0", Purl: "pkg:maven/com.google.inject/guice@4.0"}
+	for range 2 {
+		licenses, err := l.Licenses(&guice)
+		if err != nil || !slices.Equal(licenses, []string{"Apache-2.0"}) {
+			t.Fatalf("unexpected licenses %v %v", licenses, err)
+		}
+	}
+
+	if len(queried) != 1 || queried[0].Name != "com.google.inject:guice" || queried[0].System != "MAVEN" {
+		t.Fatalf("results must be cached, got queries %+v", queried)
+	}
+
+	if _, err := l.Licenses(&sbom.Component{Purl: "pkg:npm/%40angular/core@16.0.0"}); err == nil {
+		t.Fatalf("error expected for failed request")
+	}
+	if queried[1].Name != "@angular/core" {
+		t.Fatalf("unexpected npm name %s", queried[1].Name)
+	}
+
+	licenses, err := l.Licenses(&sbom.Component{Name: "libc6", Type: "deb", Purl: "pkg:deb/debian/libc6@2.36"})
+	if err != nil || licenses != nil || len(queried) != 2 {
+		t.Fatalf("os packages must not be queried")
+	}
+}
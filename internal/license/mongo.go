@@ -0,0 +1,28 @@
+package license
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Store replaces the licenses of the SBOMs in coll, so that repeated
+// extractions keep a single document per SBOM
+func Store(ctx context.Context, coll *mongo.Collection, l []*SbomLicenses) error {
+	if len(l) == 0 {
+		return nil
+	}
+
+	models := make([]mongo.WriteModel, len(l))
+	for i, doc := range l {
+		models[i] = mongo.NewReplaceOneModel().
+			SetFilter(bson.D{{Key: "sbom_id", Value: doc.SbomId}}).
+			SetReplacement(doc).
+			SetUpsert(true)
+	}
+
+	_, err := coll.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	return err
+}
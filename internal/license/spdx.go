@@ -0,0 +1,268 @@
+package license
+
+import (
+	"regexp"
+	"strings"
+)
+
+type Category string
+
+const (
+	Permissive     Category = "permissive"
+	WeakCopyleft   Category = "weak_copyleft"
+	StrongCopyleft Category = "strong_copyleft"
+	Unknown        Category = "unknown"
+)
+
+// SPDX license ids and their category. the list covers the
+// licenses commonly found in container images, all other ids
+// are kept as LicenseRef and categorized as unknown.
+var categories = map[string]Category{
+	"0BSD":              Permissive,
+	"AFL-3.0":           Permissive,
+	"Apache-1.1":        Permissive,
+	"Apache-2.0":        Permissive,
+	"Artistic-1.0":      Permissive,
+	"Artistic-1.0-Perl": Permissive,
+	"Artistic-2.0":      Permissive,
+	"BlueOak-1.0.0":     Permissive,
+	"BSD-1-Clause":      Permissive,
+	"BSD-2-Clause":      Permissive,
+	"BSD-3-Clause":      Permissive,
+	"BSD-4-Clause":      Permissive,
+	"BSL-1.0":           Permissive,
+	"CC-BY-4.0":         Permissive,
+	"CC0-1.0":           Permissive,
+	"curl":              Permissive,
+	"FTL":               Permissive,
+	"HPND":              Permissive,
+	"ICU":               Permissive,
+	"IJG":               Permissive,
+	"ISC":               Permissive,
+	"Libpng":            Permissive,
+	"libpng-2.0":        Permissive,
+	"MIT":               Permissive,
+	"MIT-0":             Permissive,
+	"NCSA":              Permissive,
+	"OpenSSL":           Permissive,
+	"PostgreSQL":        Permissive,
+	"PSF-2.0":           Permissive,
+	"Python-2.0":        Permissive,
+	"Ruby":              Permissive,
+	"Unicode-DFS-2016":  Permissive,
+	"Unlicense":         Permissive,
+	"W3C":               Permissive,
+	"WTFPL":             Permissive,
+	"X11":               Permissive,
+	"Zlib":              Permissive,
+	// not a license, but commonly declared by os packages
+	"LicenseRef-public-domain": Permissive,
+
+	"CDDL-1.0":          WeakCopyleft,
+	"CDDL-1.1":          WeakCopyleft,
+	"CPL-1.0":           WeakCopyleft,
+	"EPL-1.0":           WeakCopyleft,
+	"EPL-2.0":           WeakCopyleft,
+	"LGPL-2.0-only":     WeakCopyleft,
+	"LGPL-2.0-or-later": WeakCopyleft,
+	"LGPL-2.1-only":     WeakCopyleft,
+	"LGPL-2.1-or-later": WeakCopyleft,
+	"LGPL-3.0-only":     WeakCopyleft,
+	"LGPL-3.0-or-later": WeakCopyleft,
+	"MPL-1.1":           WeakCopyleft,
+	"MPL-2.0":           WeakCopyleft,
+	"CC-BY-SA-4.0":      WeakCopyleft,
+
+	"AGPL-3.0-only":     StrongCopyleft,
+	"AGPL-3.0-or-later": StrongCopyleft,
+	"EUPL-1.1":          StrongCopyleft,
+	"EUPL-1.2":          StrongCopyleft,
+	"GPL-1.0-only":      StrongCopyleft,
+	"GPL-1.0-or-later":  StrongCopyleft,
+	"GPL-2.0-only":      StrongCopyleft,
+	"GPL-2.0-or-later":  StrongCopyleft,
+	"GPL-3.0-only":      StrongCopyleft,
+	"GPL-3.0-or-later":  StrongCopyleft,
+	"OSL-3.0":           StrongCopyleft,
+	"SSPL-1.0":          StrongCopyleft,
+}
+
+// exceptions that allow linking without the copyleft obligations
+// of the license, e.g., GPL-2.0-only WITH Classpath-exception-2.0
+var exceptions = []string{
+	"Autoconf-exception-2.0",
+	"Autoconf-exception-3.0",
+	"Bison-exception-2.2",
+	"Classpath-exception-2.0",
+	"GCC-exception-2.0",
+	"GCC-exception-3.1",
+	"LLVM-exception",
+	"OpenSSL-exception",
+}
+
+// license names found in package metadata and their SPDX id. the
+// keys are compared after normalization with key, so variants like
+// "Apache License, Version 2.0" and "apache-2" share one entry.
+var aliases = map[string]string{
+	"apache2":                           "Apache-2.0",
+	"asl2":                              "Apache-2.0",
+	"apachesoftware2":                   "Apache-2.0",
+	"expat":                             "MIT",
+	"mitx11":                            "MIT",
+	"bsd2":                              "BSD-2-Clause",
+	"bsd2clause":                        "BSD-2-Clause",
+	"2clausebsd":                        "BSD-2-Clause",
+	"simplifiedbsd":                     "BSD-2-Clause",
+	"freebsd":                           "BSD-2-Clause",
+	"bsd3":                              "BSD-3-Clause",
+	"3clausebsd":                        "BSD-3-Clause",
+	"newbsd":                            "BSD-3-Clause",
+	"modifiedbsd":                       "BSD-3-Clause",
+	"revisedbsd":                        "BSD-3-Clause",
+	"bsd4clause":                        "BSD-4-Clause",
+	"boostsoftware1":                    "BSL-1.0",
+	"boost":                             "BSL-1.0",
+	"cc0":                               "CC0-1.0",
+	"zlib/libpng":                       "Zlib",
+	"artistic":                          "Artistic-1.0",
+	"perl":                              "Artistic-1.0-Perl",
+	"python":                            "Python-2.0",
+	"psf":                               "PSF-2.0",
+	"pythonsoftwarefoundation":          "PSF-2.0",
+	"publicdomain":                      "LicenseRef-public-domain",
+	"mpl2":                              "MPL-2.0",
+	"mozillapublic2":                    "MPL-2.0",
+	"mpl1.1":                            "MPL-1.1",
+	"epl1":                              "EPL-1.0",
+	"eclipsepublic1":                    "EPL-1.0",
+	"epl2":                              "EPL-2.0",
+	"eclipsepublic2":                    "EPL-2.0",
+	"cddl1":                             "CDDL-1.0",
+	"cddl1.1":                           "CDDL-1.1",
+	"commondevelopmentanddistribution1": "CDDL-1.0",
+	"gpl1":                              "GPL-1.0-only",
+	"gpl2":                              "GPL-2.0-only",
+	"gpl3":                              "GPL-3.0-only",
+	"generalpublic2":                    "GPL-2.0-only",
+	"generalpublic3":                    "GPL-3.0-only",
+	"lgpl2":                             "LGPL-2.0-only",
+	"lgpl2.1":                           "LGPL-2.1-only",
+	"lgpl3":                             "LGPL-3.0-only",
+	"librarygeneralpublic2":             "LGPL-2.0-only",
+	"lessergeneralpublic2.1":            "LGPL-2.1-only",
+	"lessergeneralpublic3":              "LGPL-3.0-only",
+	"agpl3":                             "AGPL-3.0-only",
+	"afferogeneralpublic3":              "AGPL-3.0-only",
+}
+
+// SPDX ids superseded by an -only id, used when an or-later
+// variant is requested with the + suffix
+var orLater = map[string]string{
+	"GPL-1.0-only":  "GPL-1.0-or-later",
+	"GPL-2.0-only":  "GPL-2.0-or-later",
+	"GPL-3.0-only":  "GPL-3.0-or-later",
+	"LGPL-2.0-only": "LGPL-2.0-or-later",
+	"LGPL-2.1-only": "LGPL-2.1-or-later",
+	"LGPL-3.0-only": "LGPL-3.0-or-later",
+	"AGPL-3.0-only": "AGPL-3.0-or-later",
+}
+
+// words that don't distinguish licenses
+var fillerWords = map[string]bool{
+	"the":      true,
+	"gnu":      true,
+	"license":  true,
+	"licence":  true,
+	"licensed": true,
+	"version":  true,
+}
+
+var versionPrefix = regexp.MustCompile(`v(\d)`)
+
+// returns the lookup key of a license name. filler words, separators
+// and a trailing .0 are removed, e.g., "The Apache License, Version 2.0"
+// and "Apache-2" both become apache2.
+func key(name string) string {
+	var words []string
+	for _, w := range strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return r == ' ' || r == ',' || r == '\t'
+	}) {
+		if !fillerWords[w] {
+			words = append(words, w)
+		}
+	}
+
+	k := strings.Join(words, "")
+	k = strings.NewReplacer("-", "", "_", "").Replace(k)
+	k = versionPrefix.ReplaceAllString(k, "$1")
+	for strings.HasSuffix(k, ".0") {
+		k = strings.TrimSuffix(k, ".0")
+	}
+	return k
+}
+
+// index of all known ids and aliases by their key
+var ids = func() map[string]string {
+	res := make(map[string]string, len(categories)+len(aliases))
+	for id := range categories {
+		res[key(id)] = id
+	}
+	for alias, id := range aliases {
+		res[key(alias)] = id
+	}
+	return res
+}()
+
+var invalidIdChars = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+
+// Id returns the SPDX id of a single license name. names ending
+// with + or "or later" are mapped to the or-later variant. Unknown
+// names are returned as LicenseRef, the bool is false in this case.
+func Id(name string) (string, bool) {
+	name = strings.TrimSpace(name)
+
+	if base, ok := strings.CutSuffix(name, "+"); ok {
+		id, known := Id(base)
+		if !known {
+			return id, false
+		}
+		if later, ok := orLater[id]; ok {
+			return later, true
+		}
+		// SPDX allows the + operator on all ids
+		return id + "+", true
+	}
+
+	if id, ok := ids[key(name)]; ok {
+		return id, true
+	}
+
+	ref := strings.Trim(invalidIdChars.ReplaceAllString(name, "-"), "-")
+	if ref == "" {
+		ref = "unknown"
+	}
+	return "LicenseRef-" + ref, false
+}
+
+// returns the SPDX exception id of name or a sanitized name if the
+// exception is unknown
+func exceptionId(name string) (string, bool) {
+	k := key(name)
+	for _, e := range exceptions {
+		if key(e) == k {
+			return e, true
+		}
+	}
+	return strings.Trim(invalidIdChars.ReplaceAllString(strings.TrimSpace(name), "-"), "-"), false
+}
+
+// CategoryOf returns the category of a single SPDX id
+func CategoryOf(id string) Category {
+	if c, ok := categories[id]; ok {
+		return c
+	}
+	if base, ok := strings.CutSuffix(id, "+"); ok {
+		return CategoryOf(base)
+	}
+	return Unknown
+}
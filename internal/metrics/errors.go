@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// StatusError is returned for unexpected status codes of external APIs
+type StatusError struct {
+	Code int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("request failed with status code %d", e.Code)
+}
+
+func (e *StatusError) Class() string {
+	return fmt.Sprintf("http_%d", e.Code)
+}
+
+// ErrorClass groups errors for the failure counts, e.g., network,
+// decode, or http_429. Errors can define their class with a
+// Class() string method.
+func ErrorClass(err error) string {
+	var classified interface{ Class() string }
+	var netErr net.Error
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var pathErr *fs.PathError
+
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &classified):
+		return classified.Class()
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case mongo.IsDuplicateKeyError(err):
+		return "duplicate_key"
+	case mongo.IsNetworkError(err), mongo.IsTimeout(err):
+		return "database"
+	case errors.As(err, &netErr):
+		return "network"
+	case errors.As(err, &syntaxErr), errors.As(err, &typeErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return "decode"
+	case errors.As(err, &pathErr):
+		return "io"
+	default:
+		return "other"
+	}
+}
@@ -0,0 +1,54 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// upper bounds of the request latency in seconds
+var latencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// Get performs a GET request to an external api and records its
+// latency and status code. Failed requests are recorded with the
+// status "error". The request is aborted when ctx is canceled.
+func Get(ctx context.Context, api string, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return Client(api).Do(req)
+}
+
+// Client returns a client that records its requests to the external
+// api like Get. It's passed to packages that shouldn't depend on the
+// metrics themselves.
+func Client(api string) *http.Client {
+	return &http.Client{Transport: observedTransport{api: api, next: http.DefaultTransport}}
+}
+
+type observedTransport struct {
+	api  string
+	next http.RoundTripper
+}
+
+func (t observedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	Observe(t.api, start, resp, err)
+	return resp, err
+}
+
+// Observe records a request to an external api that started at start
+func Observe(api string, start time.Time, resp *http.Response, err error) {
+	status := "error"
+	if err == nil {
+		status = strconv.Itoa(resp.StatusCode)
+	}
+
+	Default.Histogram("external_request_duration_seconds", "Latency of requests to external APIs.",
+		Labels{"api": api}, latencyBuckets).Observe(time.Since(start).Seconds())
+	Default.Counter("external_requests_total", "Requests to external APIs by status code, error if the request failed.",
+		Labels{"api": api, "status": status}).Inc()
+}
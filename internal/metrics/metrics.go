@@ -0,0 +1,296 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Labels of a single series, e.g., {"job": "transform"}
+type Labels map[string]string
+
+type kind string
+
+const (
+	counterKind   kind = "counter"
+	gaugeKind     kind = "gauge"
+	histogramKind kind = "histogram"
+)
+
+// a series writes its samples in the Prometheus text format
+type series interface {
+	write(w io.Writer, name string, labels string)
+}
+
+type family struct {
+	help   string
+	kind   kind
+	series map[string]series // keyed by the rendered labels
+}
+
+// Registry holds all metrics of a process. Metrics are created on
+// first use and shared afterwards, e.g., two progresses of the same
+// job update the same counters.
+type Registry struct {
+	mu       sync.Mutex
+	families map[string]*family
+}
+
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]*family)}
+}
+
+// Default is the registry served by Serve
+var Default = NewRegistry()
+
+// returns the series with the labels or creates it with create.
+// panics if the name is registered with another kind.
+func (r *Registry) get(name, help string, k kind, labels Labels, create func() series) series {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	f, ok := r.families[name]
+	if !ok {
+		f = &family{help: help, kind: k, series: make(map[string]series)}
+		r.families[name] = f
+	}
+	if f.kind != k {
+		panic(fmt.Sprintf("metric %s is a %s, not a %s", name, f.kind, k))
+	}
+
+	key := renderLabels(labels)
+	s, ok := f.series[key]
+	if !ok {
+		s = create()
+		f.series[key] = s
+	}
+	return s
+}
+
+func (r *Registry) Counter(name, help string, labels Labels) *Counter {
+	return r.get(name, help, counterKind, labels, func() series { return &Counter{} }).(*Counter)
+}
+
+func (r *Registry) Gauge(name, help string, labels Labels) *Gauge {
+	return r.get(name, help, gaugeKind, labels, func() series { return &Gauge{} }).(*Gauge)
+}
+
+// Histogram with the given upper bounds, the +Inf bucket is added
+func (r *Registry) Histogram(name, help string, labels Labels, buckets []float64) *Histogram {
+	return r.get(name, help, histogramKind, labels, func() series { return newHistogram(buckets) }).(*Histogram)
+}
+
+// WriteTo writes all metrics in the Prometheus text format, families
+// and series are sorted by name
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	buf := bufio.NewWriter(w)
+	cw := &countingWriter{w: buf}
+
+	names := make([]string, 0, len(r.families))
+	for name := range r.families {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		f := r.families[name]
+		fmt.Fprintf(cw, "# HELP %s %s\n", name, strings.ReplaceAll(f.help, "\n", " "))
+		fmt.Fprintf(cw, "# TYPE %s %s\n", name, f.kind)
+
+		keys := make([]string, 0, len(f.series))
+		for k := range f.series {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			f.series[k].write(cw, name, k)
+		}
+	}
+
+	if err := buf.Flush(); err != nil {
+		return cw.n, err
+	}
+	return cw.n, cw.err
+}
+
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.WriteTo(w)
+	})
+}
+
+// Serve exposes the default registry on addr at /metrics in the
+// background. Nothing is served if addr is empty.
+func Serve(addr string) error {
+	if addr == "" {
+		return nil
+	}
+
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", Default.Handler())
+	go http.Serve(l, mux)
+
+	return nil
+}
+
+type Counter struct {
+	v atomic.Int64
+}
+
+func (c *Counter) Add(n int64) {
+	c.v.Add(n)
+}
+
+func (c *Counter) Inc() {
+	c.v.Add(1)
+}
+
+func (c *Counter) Value() int64 {
+	return c.v.Load()
+}
+
+func (c *Counter) write(w io.Writer, name string, labels string) {
+	fmt.Fprintf(w, "%s%s %d\n", name, labels, c.Value())
+}
+
+type Gauge struct {
+	bits atomic.Uint64
+}
+
+func (g *Gauge) Set(v float64) {
+	g.bits.Store(math.Float64bits(v))
+}
+
+func (g *Gauge) Value() float64 {
+	return math.Float64frombits(g.bits.Load())
+}
+
+func (g *Gauge) write(w io.Writer, name string, labels string) {
+	fmt.Fprintf(w, "%s%s %s\n", name, labels, formatFloat(g.Value()))
+}
+
+type Histogram struct {
+	buckets []float64
+	counts  []atomic.Uint64 // not cumulative, the last one is +Inf
+	count   atomic.Uint64
+	sum     atomic.Uint64 // float64 bits
+}
+
+func newHistogram(buckets []float64) *Histogram {
+	b := slices.Clone(buckets)
+	slices.Sort(b)
+	return &Histogram{buckets: b, counts: make([]atomic.Uint64, len(b)+1)}
+}
+
+func (h *Histogram) Observe(v float64) {
+	h.counts[sort.SearchFloat64s(h.buckets, v)].Add(1)
+	h.count.Add(1)
+	for {
+		old := h.sum.Load()
+		if h.sum.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+v)) {
+			return
+		}
+	}
+}
+
+func (h *Histogram) Count() uint64 {
+	return h.count.Load()
+}
+
+func (h *Histogram) Sum() float64 {
+	return math.Float64frombits(h.sum.Load())
+}
+
+func (h *Histogram) write(w io.Writer, name string, labels string) {
+	var cumulative uint64
+	for i := range h.counts {
+		cumulative += h.counts[i].Load()
+		le := "+Inf"
+		if i < len(h.buckets) {
+			le = formatFloat(h.buckets[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", name, withLabel(labels, "le", le), cumulative)
+	}
+	fmt.Fprintf(w, "%s_sum%s %s\n", name, labels, formatFloat(h.Sum()))
+	fmt.Fprintf(w, "%s_count%s %d\n", name, labels, h.Count())
+}
+
+// renders labels sorted by name, e.g., {api="maven",status="200"}
+func renderLabels(labels Labels) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(labels))
+	for k := range labels {
+		names = append(names, k)
+	}
+	slices.Sort(names)
+
+	pairs := make([]string, len(names))
+	for i, k := range names {
+		pairs[i] = k + "=" + quote(labels[k])
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// appends a label to rendered labels
+func withLabel(labels string, name string, value string) string {
+	pair := name + "=" + quote(value)
+	if labels == "" {
+		return "{" + pair + "}"
+	}
+	return labels[:len(labels)-1] + "," + pair + "}"
+}
+
+func quote(v string) string {
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, `"`, `\"`)
+	v = strings.ReplaceAll(v, "\n", `\n`)
+	return `"` + v + `"`
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
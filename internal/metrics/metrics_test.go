@@ -0,0 +1,209 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteTo(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("requests_total", "Requests.", Labels{"status": "200", "api": "maven"}).Add(3)
+	r.Counter("requests_total", "Requests.", Labels{"api": "maven", "status": "200"}).Inc()
+	r.Counter("requests_total", "Requests.", Labels{"api": `a"b`, "status": "error"}).Inc()
+	r.Gauge("items_total", "Items.", nil).Set(2.5)
+	h := r.Histogram("latency_seconds", "Latency.", Labels{"api": "deps"}, []float64{1, 0.1})
+	h.Observe(0.05)
+	h.Observe(0.5)
+	h.Observe(3)
+
+	var b bytes.Buffer
+	if _, err := r.WriteTo(&b); err != nil {
+		t.Fatalf("write failed %s", err)
+	}
+
+	expected := `# HELP items_total Items.
+# TYPE items_total gauge
+items_total 2.5
+# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{api="deps",le="0.1"} 1
+latency_seconds_bucket{api="deps",le="1"} 2
+latency_seconds_bucket{api="deps",le="+Inf"} 3
+latency_seconds_sum{api="deps"} 3.55
+latency_seconds_count{api="deps"} 3
+# HELP requests_total Requests.
+# TYPE requests_total counter
+requests_total{api="a\"b",status="error"} 1
+requests_total{api="maven",status="200"} 4
+`
+	if b.String() != expected {
+		t.Fatalf("unexpected output\n%s", b.String())
+	}
+}
+
+func TestKindMismatch(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("x", "", nil)
+
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected a panic for a gauge with the name of a counter")
+		}
+	}()
+	r.Gauge("x", "", nil)
+}
+
+func TestProgress(t *testing.T) {
+	now := time.Now()
+	clock := now
+	p := newProgress(NewRegistry(), "test", func() time.Time { return clock })
+
+	if s := p.Snapshot(); s.Eta != -1 || s.Rate != 0 {
+		t.Fatalf("unexpected snapshot before start %+v", s)
+	}
+
+	p.SetTotal(100)
+	p.Processed(15)
+	for range 3 {
+		p.Fail(errors.New("failed"))
+	}
+	p.Skipped(2)
+	clock = now.Add(10 * time.Second)
+
+	s := p.Snapshot()
+	if s.Done() != 20 || s.Rate != 2 {
+		t.Fatalf("unexpected snapshot %+v", s)
+	}
+	if s.Eta != 40*time.Second {
+		t.Fatalf("unexpected eta %s", s.Eta)
+	}
+
+	p.SetTotal(0)
+	if s := p.Snapshot(); s.Eta != -1 {
+		t.Fatalf("eta without total %s", s.Eta)
+	}
+}
+
+func TestProgressLog(t *testing.T) {
+	var b bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&b, nil))
+
+	p := newProgress(NewRegistry(), "log", time.Now)
+	p.SetTotal(10)
+	p.Start(logger, time.Millisecond)
+	p.Processed(4)
+	time.Sleep(20 * time.Millisecond)
+	p.Stop(logger)
+	// stopping twice is fine
+	p.Stop(logger)
+
+	out := b.String()
+	if !strings.Contains(out, `msg=Progress job=log`) {
+		t.Fatalf("missing periodic log line\n%s", out)
+	}
+	if strings.Count(out, "Progress finished") != 1 || !strings.Contains(out, "total=10") {
+		t.Fatalf("unexpected final log line\n%s", out)
+	}
+}
+
+func TestTrack(t *testing.T) {
+	p := newProgress(NewRegistry(), "track", time.Now)
+	work := Track(p, func(i *int) (*int, error) {
+		switch *i {
+		case 0:
+			return nil, errors.New("failed")
+		case 1:
+			return nil, nil
+		default:
+			return i, nil
+		}
+	})
+
+	for i := range 4 {
+		work(&i)
+	}
+
+	if s := p.Snapshot(); s.Failed != 1 || s.Skipped != 1 || s.Processed != 2 {
+		t.Fatalf("unexpected counts %+v", s)
+	}
+}
+
+func TestTrackWrite(t *testing.T) {
+	p := newProgress(NewRegistry(), "write", time.Now)
+	collect := TrackWrite(p, func(i []*int) error {
+		if len(i) > 1 {
+			return &StatusError{Code: http.StatusServiceUnavailable}
+		}
+		return nil
+	})
+
+	one, two := 1, 2
+	collect([]*int{&one})
+	collect([]*int{&one, &two})
+
+	s := p.Snapshot()
+	if s.WriteFailed != 2 || s.Errors["http_503"] != 2 {
+		t.Fatalf("unexpected counts %+v", s)
+	}
+}
+
+func TestErrorClass(t *testing.T) {
+	var syntaxErr error
+	if err := json.Unmarshal([]byte("{"), &struct{}{}); err != nil {
+		syntaxErr = err
+	}
+
+	tests := map[string]error{
+		"":         nil,
+		"http_404": fmt.Errorf("query failed: %w", &StatusError{Code: http.StatusNotFound}),
+		"canceled": context.Canceled,
+		"timeout":  fmt.Errorf("query failed: %w", context.DeadlineExceeded),
+		"decode":   syntaxErr,
+		"io":       &fs.PathError{Op: "open", Path: "sbom.json", Err: fs.ErrNotExist},
+		"other":    errors.New("failed"),
+	}
+
+	for class, err := range tests {
+		if got := ErrorClass(err); got != class {
+			t.Fatalf("expected class %q for %v, got %q", class, err, got)
+		}
+	}
+}
+
+func TestGet(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	resp, err := Get(context.Background(), "test-api", srv.URL)
+	if err != nil {
+		t.Fatalf("request failed %s", err)
+	}
+	resp.Body.Close()
+	Get(context.Background(), "test-api", "http://127.0.0.1:0")
+
+	limited := Default.Counter("external_requests_total", "", Labels{"api": "test-api", "status": "429"})
+	failed := Default.Counter("external_requests_total", "", Labels{"api": "test-api", "status": "error"})
+	latency := Default.Histogram("external_request_duration_seconds", "", Labels{"api": "test-api"}, latencyBuckets)
+	if limited.Value() != 1 || failed.Value() != 1 || latency.Count() != 2 {
+		t.Fatalf("unexpected metrics %d %d %d", limited.Value(), failed.Value(), latency.Count())
+	}
+
+	// served in the Prometheus format
+	rec := httptest.NewRecorder()
+	Default.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if !strings.Contains(rec.Body.String(), `external_requests_total{api="test-api",status="429"} 1`) {
+		t.Fatalf("unexpected metrics page\n%s", rec.Body.String())
+	}
+}
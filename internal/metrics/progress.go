@@ -0,0 +1,236 @@
+package metrics
+
+import (
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+)
+
+// LogInterval is the default interval of the progress log line
+const LogInterval = 10 * time.Second
+
+// Progress counts the items a job processed, failed, or skipped.
+// The counts are exported as metrics labeled with the job and
+// logged periodically together with the throughput and the ETA.
+// Failures are additionally counted by their ErrorClass.
+type Progress struct {
+	job         string
+	registry    *Registry
+	processed   *Counter
+	failed      *Counter
+	skipped     *Counter
+	writeFailed *Counter
+	total       *Gauge
+
+	mu     sync.Mutex
+	errors map[string]*Counter
+
+	start time.Time
+	now   func() time.Time
+
+	done chan struct{}
+	once sync.Once
+	wg   sync.WaitGroup
+}
+
+func NewProgress(job string) *Progress {
+	return newProgress(Default, job, time.Now)
+}
+
+func newProgress(r *Registry, job string, now func() time.Time) *Progress {
+	labels := Labels{"job": job}
+	return &Progress{
+		job:         job,
+		registry:    r,
+		processed:   r.Counter("items_processed_total", "Items processed successfully.", labels),
+		failed:      r.Counter("items_failed_total", "Items that failed to process.", labels),
+		skipped:     r.Counter("items_skipped_total", "Items skipped, e.g., because they are cached.", labels),
+		writeFailed: r.Counter("items_write_failed_total", "Processed items that couldn't be written to the output.", labels),
+		total:       r.Gauge("items_total", "Items to process, 0 if unknown.", labels),
+		errors:      make(map[string]*Counter),
+		start:       now(),
+		now:         now,
+		done:        make(chan struct{}),
+	}
+}
+
+// SetTotal sets the number of items to process, the ETA is
+// only known if the total is set
+func (p *Progress) SetTotal(n int64) {
+	p.total.Set(float64(n))
+}
+
+func (p *Progress) Processed(n int64) {
+	p.processed.Add(n)
+}
+
+// Fail counts an item that failed with err
+func (p *Progress) Fail(err error) {
+	p.failed.Inc()
+	p.countError(err, 1)
+}
+
+// WriteFailed counts n processed items whose output couldn't be
+// written, e.g., a failed database insert
+func (p *Progress) WriteFailed(n int64, err error) {
+	p.writeFailed.Add(n)
+	p.countError(err, n)
+}
+
+func (p *Progress) countError(err error, n int64) {
+	class := ErrorClass(err)
+	if class == "" {
+		class = "unknown"
+	}
+
+	p.mu.Lock()
+	c, ok := p.errors[class]
+	if !ok {
+		c = p.registry.Counter("items_errors_total", "Failed items by error class.", Labels{"job": p.job, "class": class})
+		p.errors[class] = c
+	}
+	p.mu.Unlock()
+
+	c.Add(n)
+}
+
+func (p *Progress) Skipped(n int64) {
+	p.skipped.Add(n)
+}
+
+type Snapshot struct {
+	Processed   int64
+	Failed      int64
+	Skipped     int64
+	WriteFailed int64
+	// failed and write failed items by error class
+	Errors  map[string]int64
+	Total   int64 // 0 if unknown
+	Elapsed time.Duration
+	// items per second over the whole run
+	Rate float64
+	// -1 if unknown
+	Eta time.Duration
+}
+
+// Done returns the number of finished items
+func (s Snapshot) Done() int64 {
+	return s.Processed + s.Failed + s.Skipped
+}
+
+func (p *Progress) Snapshot() Snapshot {
+	s := Snapshot{
+		Processed:   p.processed.Value(),
+		Failed:      p.failed.Value(),
+		Skipped:     p.skipped.Value(),
+		WriteFailed: p.writeFailed.Value(),
+		Errors:      make(map[string]int64),
+		Total:       int64(p.total.Value()),
+		Elapsed:     p.now().Sub(p.start),
+		Eta:         -1,
+	}
+
+	p.mu.Lock()
+	for class, c := range p.errors {
+		s.Errors[class] = c.Value()
+	}
+	p.mu.Unlock()
+
+	if s.Elapsed > 0 {
+		s.Rate = float64(s.Done()) / s.Elapsed.Seconds()
+	}
+	if s.Total > 0 && s.Rate > 0 {
+		remaining := max(s.Total-s.Done(), 0)
+		s.Eta = time.Duration(float64(remaining) / s.Rate * float64(time.Second))
+	}
+
+	return s
+}
+
+// Start logs the progress every interval until Stop is called
+func (p *Progress) Start(logger *slog.Logger, interval time.Duration) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				p.log(logger, "Progress")
+			case <-p.done:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the periodic log and logs the final counts
+func (p *Progress) Stop(logger *slog.Logger) {
+	p.once.Do(func() {
+		close(p.done)
+		p.wg.Wait()
+		p.log(logger, "Progress finished")
+	})
+}
+
+func (p *Progress) log(logger *slog.Logger, msg string) {
+	s := p.Snapshot()
+
+	args := []any{
+		"job", p.job,
+		"processed", s.Processed,
+		"failed", s.Failed,
+		"skipped", s.Skipped,
+		"items/s", math.Round(s.Rate*100) / 100,
+		"elapsed", s.Elapsed.Round(time.Second),
+	}
+	if s.WriteFailed > 0 {
+		args = append(args, "write failed", s.WriteFailed)
+	}
+	if s.Total > 0 {
+		args = append(args, "total", s.Total)
+	}
+	if s.Eta >= 0 {
+		args = append(args, "eta", s.Eta.Round(time.Second))
+	}
+
+	logger.Info(msg, args...)
+}
+
+// Track wraps the work function of a beehive worker and counts its
+// results. Errors are counted as failed, nil results as skipped.
+func Track[T, R any](p *Progress, work func(*T) (*R, error)) func(*T) (*R, error) {
+	return func(t *T) (*R, error) {
+		res, err := work(t)
+		switch {
+		case err != nil:
+			p.Fail(err)
+		case res == nil:
+			p.Skipped(1)
+		default:
+			p.Processed(1)
+		}
+		return res, err
+	}
+}
+
+// TrackWrite wraps the collect function of a beehive collector and
+// counts the items of batches that couldn't be written
+func TrackWrite[T any](p *Progress, collect func([]*T) error) func([]*T) error {
+	return func(t []*T) error {
+		err := collect(t)
+		if err != nil {
+			p.WriteFailed(int64(len(t)), err)
+		}
+		return err
+	}
+}
+
+// Job is the name of the job
+func (p *Progress) Job() string {
+	return p.job
+}
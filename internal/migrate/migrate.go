@@ -0,0 +1,233 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// Field is the schema version stamped on the migrated documents. It's
+// the version of the last migration that changed the document,
+// documents without it predate the versioning or were written in
+// the current shape.
+const Field = "schema_version"
+
+// Latest selects the last migration as target
+const Latest = -1
+
+type Direction string
+
+const (
+	Up   Direction = "up"
+	Down Direction = "down"
+)
+
+// Change of the documents in a single direction
+type Change struct {
+	// selects the documents in the shape before the change. changed
+	// documents must no longer match, so that running a change
+	// twice has no effect.
+	Filter bson.D
+	// update pipeline applied to the selected documents, the schema
+	// version is stamped by the runner
+	Update mongo.Pipeline
+}
+
+type Migration struct {
+	// unique over all collections, applied in ascending order
+	Version     int
+	Collection  string
+	Description string
+	Up          Change
+	Down        Change
+}
+
+// Result of a migration step, Modified is 0 for dry runs
+type Result struct {
+	Version     int       `json:"version"`
+	Collection  string    `json:"collection"`
+	Description string    `json:"description"`
+	Direction   Direction `json:"direction"`
+	Matched     int64     `json:"matched"`
+	Modified    int64     `json:"modified"`
+	DryRun      bool      `json:"dry_run"`
+}
+
+// entry of the history collection
+type applied struct {
+	Version     int       `bson:"_id"`
+	Collection  string    `bson:"collection"`
+	Description string    `bson:"description"`
+	AppliedAt   time.Time `bson:"applied_at"`
+}
+
+// validate checks that the versions are positive and ascending
+func validate(migrations []Migration) error {
+	for i, m := range migrations {
+		if m.Version <= 0 {
+			return fmt.Errorf("migration %q has invalid version %d", m.Description, m.Version)
+		}
+		if i > 0 && m.Version <= migrations[i-1].Version {
+			return fmt.Errorf("migration %d isn't ordered after %d", m.Version, migrations[i-1].Version)
+		}
+		if m.Collection == "" {
+			return fmt.Errorf("migration %d has no collection", m.Version)
+		}
+	}
+	return nil
+}
+
+// Plan returns the migrations to run in order. Up runs the migrations
+// that aren't applied up to and including target, Down reverts the
+// applied migrations above target. Latest as target runs all pending
+// migrations up or reverts the last applied migration.
+func Plan(migrations []Migration, applied map[int]bool, dir Direction, target int) []Migration {
+	var res []Migration
+	switch dir {
+	case Up:
+		for _, m := range migrations {
+			if !applied[m.Version] && (target == Latest || m.Version <= target) {
+				res = append(res, m)
+			}
+		}
+	case Down:
+		if target == Latest {
+			for _, m := range migrations {
+				if applied[m.Version] {
+					res = []Migration{m}
+				}
+			}
+			return res
+		}
+		for _, m := range slices.Backward(migrations) {
+			if applied[m.Version] && m.Version > target {
+				res = append(res, m)
+			}
+		}
+	}
+	return res
+}
+
+// previous returns the version of the last migration of the same
+// collection before m, 0 if there is none
+func previous(migrations []Migration, m Migration) int {
+	prev := 0
+	for _, o := range migrations {
+		if o.Version < m.Version && o.Collection == m.Collection {
+			prev = o.Version
+		}
+	}
+	return prev
+}
+
+// stamp returns the stage that sets the schema version after the step
+func stamp(version int) bson.D {
+	if version == 0 {
+		return bson.D{{Key: "$unset", Value: Field}}
+	}
+	return bson.D{{Key: "$set", Value: bson.D{{Key: Field, Value: version}}}}
+}
+
+// Runner applies migrations to a database and records the applied
+// versions in the history collection
+type Runner struct {
+	Database   *mongo.Database
+	Migrations []Migration
+	History    *mongo.Collection
+}
+
+// Applied returns the versions recorded in the history collection
+func (r *Runner) Applied(ctx context.Context) (map[int]bool, error) {
+	cursor, err := r.History.Find(ctx, bson.D{})
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []applied
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+
+	res := make(map[int]bool, len(entries))
+	for _, e := range entries {
+		res[e.Version] = true
+	}
+	return res, nil
+}
+
+// Run migrates in dir to target. A dry run counts the documents each
+// step would change without writing. Each step is recorded once it's
+// done, ctx is checked between the steps.
+func (r *Runner) Run(ctx context.Context, dir Direction, target int, dryRun bool, done func(Result)) error {
+	if err := validate(r.Migrations); err != nil {
+		return err
+	}
+
+	history, err := r.Applied(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range Plan(r.Migrations, history, dir, target) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		res, err := r.step(ctx, m, dir, dryRun)
+		if err != nil {
+			return fmt.Errorf("migration %d %s failed: %w", m.Version, dir, err)
+		}
+		if done != nil {
+			done(*res)
+		}
+	}
+	return nil
+}
+
+func (r *Runner) step(ctx context.Context, m Migration, dir Direction, dryRun bool) (*Result, error) {
+	coll := r.Database.Collection(m.Collection)
+	change, version := m.Up, m.Version
+	if dir == Down {
+		change, version = m.Down, previous(r.Migrations, m)
+	}
+
+	res := &Result{
+		Version:     m.Version,
+		Collection:  m.Collection,
+		Description: m.Description,
+		Direction:   dir,
+		DryRun:      dryRun,
+	}
+
+	if dryRun {
+		n, err := coll.CountDocuments(ctx, change.Filter)
+		res.Matched = n
+		return res, err
+	}
+
+	// a started step is finished, so that the history matches the documents
+	drain := context.WithoutCancel(ctx)
+
+	update := append(slices.Clone(change.Update), stamp(version))
+	updated, err := coll.UpdateMany(drain, change.Filter, update)
+	if err != nil {
+		return nil, err
+	}
+	res.Matched, res.Modified = updated.MatchedCount, updated.ModifiedCount
+
+	if dir == Up {
+		_, err = r.History.InsertOne(drain, applied{
+			Version:     m.Version,
+			Collection:  m.Collection,
+			Description: m.Description,
+			AppliedAt:   time.Now(),
+		})
+	} else {
+		_, err = r.History.DeleteOne(drain, bson.D{{Key: "_id", Value: m.Version}})
+	}
+	return res, err
+}
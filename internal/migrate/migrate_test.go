@@ -0,0 +1,89 @@
+package migrate
+
+import (
+	"slices"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func testMigrations() []Migration {
+	return []Migration{
+		{Version: 1, Collection: "a"},
+		{Version: 2, Collection: "b"},
+		{Version: 3, Collection: "a"},
+		{Version: 4, Collection: "c"},
+	}
+}
+
+func versions(ms []Migration) []int {
+	res := make([]int, len(ms))
+	for i, m := range ms {
+		res[i] = m.Version
+	}
+	return res
+}
+
+func TestPlan(t *testing.T) {
+	ms := testMigrations()
+	applied := map[int]bool{1: true, 2: true}
+
+	tests := []struct {
+		dir      Direction
+		target   int
+		expected []int
+	}{
+		{Up, Latest, []int{3, 4}},
+		{Up, 3, []int{3}},
+		{Up, 1, []int{}},
+		{Down, Latest, []int{2}},
+		{Down, 0, []int{2, 1}},
+		{Down, 1, []int{2}},
+	}
+
+	for _, test := range tests {
+		got := versions(Plan(ms, applied, test.dir, test.target))
+		if !slices.Equal(got, test.expected) {
+			t.Fatalf("%s to %d: expected %v, got %v", test.dir, test.target, test.expected, got)
+		}
+	}
+}
+
+func TestPrevious(t *testing.T) {
+	ms := testMigrations()
+	if p := previous(ms, ms[2]); p != 1 {
+		t.Fatalf("expected 1, got %d", p)
+	}
+	if p := previous(ms, ms[1]); p != 0 {
+		t.Fatalf("expected 0 for the first migration of the collection, got %d", p)
+	}
+}
+
+func TestStamp(t *testing.T) {
+	if s := stamp(0); s[0].Key != "$unset" {
+		t.Fatalf("expected the version to be removed, got %v", s)
+	}
+	s := stamp(3)
+	set, ok := s[0].Value.(bson.D)
+	if s[0].Key != "$set" || !ok || set[0].Key != Field || set[0].Value != 3 {
+		t.Fatalf("unexpected stamp %v", s)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := validate(Migrations()); err != nil {
+		t.Fatalf("built-in migrations invalid %s", err)
+	}
+
+	ms := testMigrations()
+	ms[1].Version = 1
+	if err := validate(ms); err == nil {
+		t.Fatalf("expected an error for duplicate versions")
+	}
+
+	ms = testMigrations()
+	ms[0].Collection = ""
+	if err := validate(ms); err == nil {
+		t.Fatalf("expected an error for a missing collection")
+	}
+}
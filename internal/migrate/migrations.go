@@ -0,0 +1,65 @@
+package migrate
+
+import (
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+func exists(field string, exists bool) bson.E {
+	return bson.E{Key: field, Value: bson.D{{Key: "$exists", Value: exists}}}
+}
+
+// documents stamped by the migration, used to revert changes that
+// can't be told apart from documents written in the new shape
+func stamped(version int) bson.E {
+	return bson.E{Key: Field, Value: version}
+}
+
+// cache entries written before java archives were resolved by their
+// digest were all resolved by name
+func defaultMatchMethod(version int, collection string) Migration {
+	return Migration{
+		Version:     version,
+		Collection:  collection,
+		Description: "set match_method of entries resolved before digest lookups to name",
+		Up: Change{
+			Filter: bson.D{exists("name", true), exists("match_method", false)},
+			Update: mongo.Pipeline{bson.D{{Key: "$set", Value: bson.D{{Key: "match_method", Value: "name"}}}}},
+		},
+		Down: Change{
+			Filter: bson.D{stamped(version)},
+			Update: mongo.Pipeline{bson.D{{Key: "$unset", Value: "match_method"}}},
+		},
+	}
+}
+
+// Migrations returns the built-in migrations in order. Append new
+// migrations with the next version, never change released ones.
+func Migrations() []Migration {
+	return []Migration{
+		defaultMatchMethod(1, "mvn_mirror"),
+		defaultMatchMethod(2, "multi_result"),
+		defaultMatchMethod(3, "blacklist"),
+		{
+			// the versions harvester and the maven cache share the
+			// blacklist, the harvester used id for the component id
+			Version:     4,
+			Collection:  "blacklist",
+			Description: "rename id of version lookup failures to component_id",
+			Up: Change{
+				Filter: bson.D{exists("id", true), exists("name", false)},
+				Update: mongo.Pipeline{
+					bson.D{{Key: "$set", Value: bson.D{{Key: "component_id", Value: "$id"}}}},
+					bson.D{{Key: "$unset", Value: "id"}},
+				},
+			},
+			Down: Change{
+				Filter: bson.D{exists("component_id", true), exists("name", false)},
+				Update: mongo.Pipeline{
+					bson.D{{Key: "$set", Value: bson.D{{Key: "id", Value: "$component_id"}}}},
+					bson.D{{Key: "$unset", Value: "component_id"}},
+				},
+			},
+		},
+	}
+}
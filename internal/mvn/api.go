@@ -1,19 +1,41 @@
 package mvn
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"net/url"
+
+	"sbom-processor/internal/metrics"
 )
 
-func queryApi(cName string) (*MvnSearchResponse, error) {
-	encodedName := url.QueryEscape(cName)
+const centralBasePath string = "https://search.maven.org/solrsearch/select"
+
+// solr wraps the actual search result in a response object
+type searchApiResponse struct {
+	Response MvnSearchResponse `json:"response"`
+}
+
+func queryApi(ctx context.Context, cName string) (*MvnSearchResponse, error) {
+	return search(ctx, centralBasePath, "a:"+cName)
+}
+
+// queries maven central for the artifact whose jar has the given
+// sha1 checksum. the checksum must be hex encoded.
+func queryApiBySha1(ctx context.Context, basePath string, sha1 string) (*MvnSearchResponse, error) {
+	if sha1 == "" {
+		return nil, fmt.Errorf("can't search maven central for empty checksum")
+	}
+	return search(ctx, basePath, "1:"+sha1)
+}
 
-	url := fmt.Sprintf("https://search.maven.org/solrsearch/select?q=a:%s&rows=20&wt=json", encodedName)
-	resp, err := http.Get(url)
+func search(ctx context.Context, basePath string, q string) (*MvnSearchResponse, error) {
+	url := fmt.Sprintf("%s?q=%s&rows=20&wt=json", basePath, url.QueryEscape(q))
+	resp, err := metrics.Get(ctx, "maven_central", url)
 	if err != nil {
-		fmt.Printf("Request to %s failed with %s\n", url, err.Error())
+		slog.Default().Debug("Request failed", "url", url, "err", err)
 		return nil, err
 	}
 
@@ -21,19 +43,19 @@ func queryApi(cName string) (*MvnSearchResponse, error) {
 	if resp.StatusCode != http.StatusOK {
 		if resp.StatusCode == http.StatusTooManyRequests {
 			retry := resp.Header.Get("Retry-After")
-			fmt.Printf("Failed due to too many requests. Retry-After %s\n", retry)
+			slog.Default().Warn("Too many requests to maven central", "retry-after", retry)
 		}
-		err := fmt.Errorf("request failed with status code %d", resp.StatusCode)
-		fmt.Printf("Request to %s failed with %s\n", url, err.Error())
+		err := &metrics.StatusError{Code: resp.StatusCode}
+		slog.Default().Debug("Request failed", "url", url, "err", err)
 		return nil, err
 	}
 
-	var mvnRes MvnSearchResponse
+	var mvnRes searchApiResponse
 	decoder := json.NewDecoder(resp.Body)
 	if err := decoder.Decode(&mvnRes); err != nil {
-		fmt.Printf("Decoding of response to %s failed with %s\n", url, err.Error())
+		slog.Default().Debug("Decoding of response failed", "url", url, "err", err)
 		return nil, err
 	}
 
-	return &mvnRes, nil
+	return &mvnRes.Response, nil
 }
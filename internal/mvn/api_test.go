@@ -0,0 +1,66 @@
+package mvn
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sbom-processor/internal/deps"
+	"testing"
+)
+
+func TestQueryApiBySha1(t *testing.T) {
+	srv := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Query().Get("q") != "1:35379fb6526fd019f331542b4e9ae2e566c57933" {
+					t.Errorf("unexpected query %s", r.URL.Query().Get("q"))
+				}
+				_, _ = w.Write([]byte(`{"responseHeader": {"status": 0}, "response": {"numFound": 1, "start": 0, "docs": [{"id": "com.google.inject:guice:4.0", "g": "com.google.inject", "a": "guice", "v": "4.0", "p": "jar"}]}}`))
+			},
+		))
+
+	defer srv.Close()
+
+	res, err := queryApiBySha1(context.Background(), srv.URL, "35379fb6526fd019f331542b4e9ae2e566c57933")
+	if err != nil {
+		t.Fatalf("no error expected for valid request, %s", err.Error())
+	}
+
+	if res.NumFound != 1 || len(res.Docs) != 1 {
+		t.Fatalf("exactly one result expected, got %d", res.NumFound)
+	}
+
+	if res.Docs[0].Group != "com.google.inject" ||
+		res.Docs[0].Artifact != "guice" ||
+		res.Docs[0].Version != "4.0" {
+		t.Fatalf("Unexpected values after parsing JSON %+v", res.Docs[0])
+	}
+}
+
+func TestQueryApiBySha1Empty(t *testing.T) {
+	_, err := queryApiBySha1(context.Background(), "http://localhost", "")
+	if err == nil {
+		t.Fatalf("error expected for empty checksum")
+	}
+}
+
+func TestVersionKeysToSearchResponse(t *testing.T) {
+	keys := []deps.VersionKey{
+		{System: "MAVEN", Name: "com.google.inject:guice", Version: "4.0"},
+		{System: "NPM", Name: "guice", Version: "1.0.0"},
+		{System: "MAVEN", Name: "invalid", Version: "1.0.0"},
+	}
+
+	res := versionKeysToSearchResponse(keys)
+
+	if res.NumFound != 1 {
+		t.Fatalf("only valid maven packages expected, got %d", res.NumFound)
+	}
+
+	if res.Docs[0].Group != "com.google.inject" ||
+		res.Docs[0].Artifact != "guice" ||
+		res.Docs[0].Version != "4.0" ||
+		res.Docs[0].Id != "com.google.inject:guice:4.0" {
+		t.Fatalf("Unexpected values after conversion %+v", res.Docs[0])
+	}
+}
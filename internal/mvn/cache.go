@@ -2,7 +2,13 @@ package mvn
 
 import (
 	"context"
-	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"sbom-processor/internal/catalogue"
+	"sbom-processor/internal/deps"
+	"sbom-processor/internal/metrics"
 
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
@@ -13,11 +19,41 @@ type QueryResult struct {
 	Name string `bson:"_id" json:"_id"`
 }
 
+// describes how a cache entry was resolved
+type MatchMethod string
+
+const (
+	// artifact name search on maven central
+	MatchName MatchMethod = "name"
+	// sha1 checksum search on maven central
+	MatchCentralSha1 MatchMethod = "central_sha1"
+	// sha1 hash query on deps.dev
+	MatchDepsSha1 MatchMethod = "deps_sha1"
+)
+
 type MvnCacheEntry struct {
-	Name              string `bson:"name"`
+	Name              string      `bson:"name"`
+	Sha1              string      `bson:"sha1,omitempty"`
+	MatchMethod       MatchMethod `bson:"match_method"`
 	MvnSearchResponse `bson:"mvn_search_response"`
 }
 
+// entry for the blacklist and multi result collections
+type CacheMiss struct {
+	Name        string      `bson:"name"`
+	Sha1        string      `bson:"sha1,omitempty"`
+	MatchMethod MatchMethod `bson:"match_method"`
+	// the failed lookup, empty if nothing was found
+	Error string `bson:"error,omitempty"`
+}
+
+// a java archive to resolve. Sha1 is empty if syft
+// didn't record a digest for the archive.
+type JarRequest struct {
+	Name string `bson:"name"`
+	Sha1 string `bson:"sha1"`
+}
+
 type MvnSearchResponse struct {
 	NumFound int   `json:"numFound" bson:"num_found"`
 	Start    int   `json:"start" bson:"start"`
@@ -29,6 +65,7 @@ type Doc struct {
 	Group         string `json:"g" bson:"group"`
 	Artifact      string `json:"a" bson:"artifact"`
 	LatestVersion string `json:"latestVersion" bson:"latestVersion"`
+	Version       string `json:"v" bson:"version,omitempty"` // only set for checksum searches
 	RepositoryId  string `json:"repositoryId" bson:"repositoryId"`
 	P             string `json:"p" bson:"p"`
 	TimeStamp     int    `json:"timestamp" bson:"time_stamp"`
@@ -49,7 +86,12 @@ type MvnCache struct {
 	// type java && not contained in mvn central
 	Blacklist *mongo.Collection
 
+	// stops the fill when canceled. lookups in flight and the
+	// buffered results are finished regardless.
 	Ctx context.Context
+
+	// progress of the running fill, set by run
+	progress *metrics.Progress
 }
 
 // create X channels to sent responses to
@@ -61,81 +103,181 @@ type MvnCache struct {
 // InsertMany(results) into database
 // repeat until all workers finished
 // insert remaining elements
-func componentWorker(mvnCache *MvnCache, components <-chan string, cache chan *MvnCacheEntry, blacklist, multiResult chan string) {
+func componentWorker(mvnCache *MvnCache, components <-chan string, cache chan *MvnCacheEntry, blacklist, multiResult chan *CacheMiss) {
 
 	for c := range components {
 		if mvnCache.isInCache(c) {
-			fmt.Printf("%s found in cache\n", c)
+			mvnCache.progress.Skipped(1)
 			continue
 		}
 
-		mvnRes, err := queryApi(c)
+		mvnRes, err := queryApi(mvnCache.drain(), c)
 		if err != nil {
-			blacklist <- c
+			mvnCache.progress.Fail(err)
+			blacklist <- &CacheMiss{Name: c, MatchMethod: MatchName, Error: err.Error()}
 			continue
 		}
 
-		switch {
-		case mvnRes.NumFound == 1:
-			cache <- &MvnCacheEntry{Name: c, MvnSearchResponse: *mvnRes}
-		case mvnRes.NumFound > 1:
-			multiResult <- c
-		case mvnRes.NumFound < 1:
-			blacklist <- c
+		mvnCache.progress.Processed(1)
+
+		dispatchResult(JarRequest{Name: c}, mvnRes, MatchName, cache, blacklist, multiResult)
+	}
+}
+
+// same as componentWorker, but resolves the jars by their checksum
+// and only falls back to the name search if the checksum is unknown.
+func digestWorker(mvnCache *MvnCache, jars <-chan JarRequest, cache chan *MvnCacheEntry, blacklist, multiResult chan *CacheMiss) {
+
+	for j := range jars {
+		if mvnCache.isJarInCache(j) {
+			mvnCache.progress.Skipped(1)
+			continue
+		}
+
+		mvnRes, method, err := jarLookups.resolve(mvnCache.drain(), j)
+		if err != nil {
+			mvnCache.progress.Fail(err)
+			blacklist <- &CacheMiss{Name: j.Name, Sha1: j.Sha1, MatchMethod: method, Error: err.Error()}
+			continue
 		}
+
+		mvnCache.progress.Processed(1)
+
+		dispatchResult(j, mvnRes, method, cache, blacklist, multiResult)
+	}
+}
+
+func dispatchResult(j JarRequest, mvnRes *MvnSearchResponse, method MatchMethod, cache chan *MvnCacheEntry, blacklist, multiResult chan *CacheMiss) {
+	switch {
+	case mvnRes.NumFound == 1:
+		cache <- &MvnCacheEntry{Name: j.Name, Sha1: j.Sha1, MatchMethod: method, MvnSearchResponse: *mvnRes}
+	case mvnRes.NumFound > 1:
+		multiResult <- &CacheMiss{Name: j.Name, Sha1: j.Sha1, MatchMethod: method}
+	case mvnRes.NumFound < 1:
+		blacklist <- &CacheMiss{Name: j.Name, Sha1: j.Sha1, MatchMethod: method}
+	}
+}
+
+// the apis to resolve jars with, replaced in tests
+type jarResolver struct {
+	centralSha1 func(ctx context.Context, sha1 string) (*MvnSearchResponse, error)
+	depsSha1    func(ctx context.Context, sha1 string) ([]deps.VersionKey, error)
+	name        func(ctx context.Context, name string) (*MvnSearchResponse, error)
+}
+
+var jarLookups = jarResolver{
+	centralSha1: func(ctx context.Context, sha1 string) (*MvnSearchResponse, error) {
+		return queryApiBySha1(ctx, centralBasePath, sha1)
+	},
+	depsSha1: func(ctx context.Context, sha1 string) ([]deps.VersionKey, error) {
+		return deps.QueryHash(ctx, "SHA1", sha1)
+	},
+	name: queryApi,
+}
+
+// resolution order:
+// 1. sha1 search on maven central
+// 2. sha1 hash query on deps.dev
+// 3. artifact name search on maven central
+// a failed checksum lookup is returned with its method instead of
+// falling back, otherwise an outage would record the weaker name match.
+func (r jarResolver) resolve(ctx context.Context, j JarRequest) (*MvnSearchResponse, MatchMethod, error) {
+	if j.Sha1 != "" {
+		mvnRes, err := r.centralSha1(ctx, j.Sha1)
+		if err != nil {
+			return nil, MatchCentralSha1, err
+		}
+		if mvnRes.NumFound > 0 {
+			return mvnRes, MatchCentralSha1, nil
+		}
+
+		keys, err := r.depsSha1(ctx, j.Sha1)
+		if err != nil {
+			return nil, MatchDepsSha1, err
+		}
+		if mvnRes := versionKeysToSearchResponse(keys); mvnRes.NumFound > 0 {
+			return mvnRes, MatchDepsSha1, nil
+		}
+	}
+
+	mvnRes, err := r.name(ctx, j.Name)
+	return mvnRes, MatchName, err
+}
+
+// maps the maven packages of a deps.dev hash query to the
+// format of a maven central checksum search.
+func versionKeysToSearchResponse(keys []deps.VersionKey) *MvnSearchResponse {
+	docs := []Doc{}
+	for _, k := range keys {
+		if k.System != "MAVEN" {
+			continue
+		}
+
+		// maven package names are <group>:<artifact>
+		group, artifact, found := strings.Cut(k.Name, ":")
+		if !found {
+			continue
+		}
+
+		docs = append(docs, Doc{
+			Id:       k.Name + ":" + k.Version,
+			Group:    group,
+			Artifact: artifact,
+			Version:  k.Version,
+		})
+	}
+
+	return &MvnSearchResponse{
+		NumFound: len(docs),
+		Docs:     docs,
 	}
 }
 
-func resultCollector(cache *MvnCache, mirror <-chan *MvnCacheEntry, multiResult, blacklist <-chan string, done <-chan int) {
+func resultCollector(cache *MvnCache, mirror <-chan *MvnCacheEntry, multiResult, blacklist <-chan *CacheMiss, done <-chan int) {
+	ctx := cache.drain()
 	mirrorBuffer := []MvnCacheEntry{}
-	blackListBuffer := []bson.D{}
-	multiBuffer := []bson.D{}
+	blackListBuffer := []CacheMiss{}
+	multiBuffer := []CacheMiss{}
 
 	for {
 		select {
 		case ce := <-mirror:
 			mirrorBuffer = append(mirrorBuffer, *ce)
 			if len(mirrorBuffer) > 200 {
-				_, err := cache.MvnMirror.InsertMany(cache.Ctx, mirrorBuffer)
+				_, err := cache.MvnMirror.InsertMany(ctx, mirrorBuffer)
 				if err != nil {
-					fmt.Printf("MVN Mirror insert failed with %s \n", err.Error())
+					slog.Default().Error("MVN Mirror insert failed", "err", err)
 				}
 				mirrorBuffer = []MvnCacheEntry{}
-				fmt.Println("successfully inserted 200 elements in mirror")
 			}
 		case f := <-blacklist:
-			blackListBuffer = append(blackListBuffer, bson.D{{Key: "name", Value: f}})
+			blackListBuffer = append(blackListBuffer, *f)
 			if len(blackListBuffer) > 200 {
-				_, err := cache.Blacklist.InsertMany(cache.Ctx, blackListBuffer)
+				_, err := cache.Blacklist.InsertMany(ctx, blackListBuffer)
 				if err != nil {
-					fmt.Printf("blacklist insert failed with %s \n", err.Error())
+					slog.Default().Error("Blacklist insert failed", "err", err)
 				}
-				mirrorBuffer = []MvnCacheEntry{}
-				blackListBuffer = []bson.D{}
-				fmt.Println("successfully inserted 200 elements in blacklist")
+				blackListBuffer = []CacheMiss{}
 			}
 		case m := <-multiResult:
-			multiBuffer = append(multiBuffer, bson.D{{Key: "name", Value: m}})
+			multiBuffer = append(multiBuffer, *m)
 			if len(multiBuffer) > 200 {
-				_, err := cache.MultiResult.InsertMany(cache.Ctx, multiBuffer)
+				_, err := cache.MultiResult.InsertMany(ctx, multiBuffer)
 				if err != nil {
-					fmt.Printf("multi result insert failed with %s \n", err.Error())
+					slog.Default().Error("Multi result insert failed", "err", err)
 				}
-				mirrorBuffer = []MvnCacheEntry{}
-
-				multiBuffer = []bson.D{}
-				fmt.Println("successfully inserted 200 elements in multi")
+				multiBuffer = []CacheMiss{}
 			}
 		case <-done:
 			// empty all
 			if len(multiBuffer) > 0 {
-				cache.MultiResult.InsertMany(cache.Ctx, multiBuffer)
+				cache.MultiResult.InsertMany(ctx, multiBuffer)
 			}
 			if len(blackListBuffer) > 0 {
-				cache.Blacklist.InsertMany(cache.Ctx, blackListBuffer)
+				cache.Blacklist.InsertMany(ctx, blackListBuffer)
 			}
 			if len(mirrorBuffer) > 0 {
-				cache.MvnMirror.InsertMany(cache.Ctx, mirrorBuffer)
+				cache.MvnMirror.InsertMany(ctx, mirrorBuffer)
 			}
 			return
 		}
@@ -145,7 +287,41 @@ func resultCollector(cache *MvnCache, mirror <-chan *MvnCacheEntry, multiResult,
 // batch querry all unique components from collection
 // push components into components channel to distribute them between worker
 // collect results and wait until we have enough results for InsertMany
-func (cache *MvnCache) FillCache(sboms *mongo.Collection) error {
+func (cache *MvnCache) FillCache(sboms *catalogue.Store) error {
+
+	cursor, err := sboms.Aggregate(cache.Ctx, catalogue.UniqueNames("java-archive"), options.Aggregate().SetBatchSize(1000))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(cache.drain())
+
+	components := make(chan string)
+
+	cache.run(func(mirror chan *MvnCacheEntry, blacklist, multiResult chan *CacheMiss) {
+		componentWorker(cache, components, mirror, blacklist, multiResult)
+	}, func() {
+		// iterate db results
+		for cursor.Next(cache.Ctx) {
+			var res QueryResult
+			if err := cursor.Decode(&res); err != nil {
+				cache.progress.Fail(err)
+				continue
+			}
+
+			components <- res.Name
+		}
+
+		// closing components ends the workers
+		close(components)
+	})
+
+	return cursor.Err()
+}
+
+// batch query all unique java archives together with their sha1 digest.
+// archives are resolved by their checksum first and by name only if
+// the checksum is unknown or syft didn't record one.
+func (cache *MvnCache) FillCacheByDigest(sboms *catalogue.Store) error {
 
 	// prep db query
 	pipeline := mongo.Pipeline{
@@ -156,67 +332,116 @@ func (cache *MvnCache) FillCache(sboms *mongo.Collection) error {
 			{Key: "$unwind", Value: "$components"},
 		},
 		{{Key: "$match", Value: bson.D{{Key: "components.type", Value: "java-archive"}}}},
+		{
+			{Key: "$project", Value: bson.D{
+				{Key: "name", Value: "$components.name"},
+				{Key: "sha1", Value: bson.D{{Key: "$arrayElemAt", Value: bson.A{
+					bson.D{{Key: "$map", Value: bson.D{
+						{Key: "input", Value: bson.D{{Key: "$filter", Value: bson.D{
+							{Key: "input", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$components.metadata.digest", bson.A{}}}}},
+							{Key: "cond", Value: bson.D{{Key: "$eq", Value: bson.A{"$$this.algorithm", "sha1"}}}},
+						}}}},
+						{Key: "in", Value: "$$this.value"},
+					}}},
+					0,
+				}}}},
+			}},
+		},
 		{
 			{Key: "$group", Value: bson.D{
-				{Key: "_id", Value: "$components.name"},
+				{Key: "_id", Value: bson.D{
+					{Key: "name", Value: "$name"},
+					{Key: "sha1", Value: "$sha1"},
+				}},
 			}},
 		},
 	}
 
-	cursor, err := sboms.Aggregate(context.TODO(), pipeline, options.Aggregate().SetBatchSize(1000))
+	cursor, err := sboms.Aggregate(cache.Ctx, pipeline, options.Aggregate().SetBatchSize(1000))
 	if err != nil {
 		return err
 	}
-	defer cursor.Close(cache.Ctx)
+	defer cursor.Close(cache.drain())
 
-	// setup worker
-	components := make(chan string)
-	blacklist := make(chan string)
-	multiResult := make(chan string)
+	jars := make(chan JarRequest)
+
+	cache.run(func(mirror chan *MvnCacheEntry, blacklist, multiResult chan *CacheMiss) {
+		digestWorker(cache, jars, mirror, blacklist, multiResult)
+	}, func() {
+		for cursor.Next(cache.Ctx) {
+			var res struct {
+				Id JarRequest `bson:"_id"`
+			}
+			if err := cursor.Decode(&res); err != nil {
+				cache.progress.Fail(err)
+				continue
+			}
+
+			jars <- res.Id
+		}
+
+		close(jars)
+	})
+
+	return cursor.Err()
+}
+
+// Progress returns the counts of the last fill, nil if the cache
+// wasn't filled
+func (cache *MvnCache) Progress() *metrics.Progress {
+	return cache.progress
+}
+
+// context of the lookups and inserts, they aren't canceled with Ctx
+// so that a canceled fill doesn't blacklist the components in flight
+func (cache *MvnCache) drain() context.Context {
+	return context.WithoutCancel(cache.Ctx)
+}
+
+// starts the result collector and the workers. produce pushes the
+// work items to the workers and must close their input once done.
+// returns after all workers finished and the collector stored
+// the remaining results.
+func (cache *MvnCache) run(work func(mirror chan *MvnCacheEntry, blacklist, multiResult chan *CacheMiss), produce func()) {
+	logger := slog.Default()
+	cache.progress = metrics.NewProgress("mvn_cache")
+	cache.progress.Start(logger, metrics.LogInterval)
+	defer cache.progress.Stop(logger)
+
+	blacklist := make(chan *CacheMiss)
+	multiResult := make(chan *CacheMiss)
 	mirror := make(chan *MvnCacheEntry)
 
 	done := make(chan int)
 
-	go resultCollector(cache, mirror, multiResult, blacklist, done)
+	var collectorWg sync.WaitGroup
+	collectorWg.Add(1)
+	go func() {
+		defer collectorWg.Done()
+		resultCollector(cache, mirror, multiResult, blacklist, done)
+	}()
 
+	var workerWg sync.WaitGroup
 	for i := 0; i < 7; i++ {
-		go componentWorker(cache, components, mirror, blacklist, multiResult)
+		workerWg.Add(1)
+		go func() {
+			defer workerWg.Done()
+			work(mirror, blacklist, multiResult)
+		}()
 	}
 
-	failed := 0
-	counter := 0
-
-	// iterate db results
-	for cursor.Next(cache.Ctx) {
-		var res QueryResult
-		if err := cursor.Decode(&res); err != nil {
-			fmt.Printf("decode failed\n")
-			failed += 1
-			if failed%10 == 0 {
-				fmt.Printf("failed to process %d components\n", failed)
-			}
-			continue
-		}
-
-		components <- res.Name
-		counter += 1
-		if counter%100 == 0 {
-			fmt.Printf("processed %d components\n", counter)
-		}
-	}
+	produce()
 
-	// closing components ends the workers
-	close(components)
+	workerWg.Wait()
 	done <- 0
-
-	return nil
+	collectorWg.Wait()
 }
 
 func (cache *MvnCache) isInCache(name string) bool {
 
 	var inCache = func(coll *mongo.Collection, key string) bool {
 		filter := bson.D{{Key: key, Value: name}}
-		err := coll.FindOne(cache.Ctx, filter).Err()
+		err := coll.FindOne(cache.drain(), filter).Err()
 		return err != mongo.ErrNoDocuments
 	}
 
@@ -224,3 +449,21 @@ func (cache *MvnCache) isInCache(name string) bool {
 		inCache(cache.MultiResult, "name") &&
 		inCache(cache.MvnMirror, "name")
 }
+
+// a jar is in cache if any of the cache collections contains its
+// checksum. jars without checksum are looked up by name.
+func (cache *MvnCache) isJarInCache(j JarRequest) bool {
+	filter := bson.D{{Key: "name", Value: j.Name}, {Key: "match_method", Value: MatchName}}
+	if j.Sha1 != "" {
+		filter = bson.D{{Key: "sha1", Value: j.Sha1}}
+	}
+
+	for _, coll := range []*mongo.Collection{cache.MvnMirror, cache.MultiResult, cache.Blacklist} {
+		err := coll.FindOne(cache.drain(), filter).Err()
+		if err != mongo.ErrNoDocuments {
+			return true
+		}
+	}
+
+	return false
+}
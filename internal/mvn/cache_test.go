@@ -0,0 +1,87 @@
+package mvn
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"sbom-processor/internal/deps"
+)
+
+func TestResolveJar(t *testing.T) {
+	outage := errors.New("status code 503")
+	found := &MvnSearchResponse{NumFound: 1, Docs: []Doc{{Group: "com.google.inject", Artifact: "guice"}}}
+	none := &MvnSearchResponse{}
+	guice := []deps.VersionKey{{System: "MAVEN", Name: "com.google.inject:guice", Version: "4.0"}}
+
+	tests := []struct {
+		name        string
+		jar         JarRequest
+		centralSha1 func(ctx context.Context, sha1 string) (*MvnSearchResponse, error)
+		depsSha1    func(ctx context.Context, sha1 string) ([]deps.VersionKey, error)
+		method      MatchMethod
+		err         bool
+	}{
+		{
+			name:        "central checksum",
+			jar:         JarRequest{Name: "guice", Sha1: "35379fb6526fd019f331542b4e9ae2e566c57933"},
+			centralSha1: func(context.Context, string) (*MvnSearchResponse, error) { return found, nil },
+			method:      MatchCentralSha1,
+		},
+		{
+			name:        "deps checksum",
+			jar:         JarRequest{Name: "guice", Sha1: "35379fb6526fd019f331542b4e9ae2e566c57933"},
+			centralSha1: func(context.Context, string) (*MvnSearchResponse, error) { return none, nil },
+			depsSha1:    func(context.Context, string) ([]deps.VersionKey, error) { return guice, nil },
+			method:      MatchDepsSha1,
+		},
+		{
+			name:        "unknown checksum",
+			jar:         JarRequest{Name: "guice", Sha1: "35379fb6526fd019f331542b4e9ae2e566c57933"},
+			centralSha1: func(context.Context, string) (*MvnSearchResponse, error) { return none, nil },
+			depsSha1:    func(context.Context, string) ([]deps.VersionKey, error) { return nil, nil },
+			method:      MatchName,
+		},
+		{
+			name:   "no checksum",
+			jar:    JarRequest{Name: "guice"},
+			method: MatchName,
+		},
+		{
+			name:        "central outage",
+			jar:         JarRequest{Name: "guice", Sha1: "35379fb6526fd019f331542b4e9ae2e566c57933"},
+			centralSha1: func(context.Context, string) (*MvnSearchResponse, error) { return nil, outage },
+			method:      MatchCentralSha1,
+			err:         true,
+		},
+		{
+			name:        "deps outage",
+			jar:         JarRequest{Name: "guice", Sha1: "35379fb6526fd019f331542b4e9ae2e566c57933"},
+			centralSha1: func(context.Context, string) (*MvnSearchResponse, error) { return none, nil },
+			depsSha1:    func(context.Context, string) ([]deps.VersionKey, error) { return nil, outage },
+			method:      MatchDepsSha1,
+			err:         true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := jarResolver{
+				centralSha1: tt.centralSha1,
+				depsSha1:    tt.depsSha1,
+				name:        func(context.Context, string) (*MvnSearchResponse, error) { return found, nil },
+			}
+
+			res, method, err := r.resolve(context.Background(), tt.jar)
+			if method != tt.method {
+				t.Fatalf("expected match method %s, got %s", tt.method, method)
+			}
+			if (err != nil) != tt.err {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if !tt.err && res.NumFound != 1 {
+				t.Fatalf("one result expected, got %d", res.NumFound)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package oci
+
+import (
+	"net/url"
+	"strings"
+	"time"
+)
+
+// well-known image labels, see
+// https://github.com/opencontainers/image-spec/blob/main/annotations.md
+// and the deprecated http://label-schema.org/rc1/
+const (
+	Vendor     = "org.opencontainers.image.vendor"
+	Source     = "org.opencontainers.image.source"
+	Revision   = "org.opencontainers.image.revision"
+	Created    = "org.opencontainers.image.created"
+	Licenses   = "org.opencontainers.image.licenses"
+	BaseName   = "org.opencontainers.image.base.name"
+	BaseDigest = "org.opencontainers.image.base.digest"
+
+	SchemaVendor    = "org.label-schema.vendor"
+	SchemaVcsUrl    = "org.label-schema.vcs-url"
+	SchemaVcsRef    = "org.label-schema.vcs-ref"
+	SchemaBuildDate = "org.label-schema.build-date"
+)
+
+// Labels are the typed values of the well-known labels of an image
+type Labels struct {
+	Vendor string `bson:"vendor,omitempty" json:"vendor,omitempty"`
+	// normalized url of the source repository, e.g., https://github.com/org/repo
+	Source   string     `bson:"source,omitempty" json:"source,omitempty"`
+	Revision string     `bson:"revision,omitempty" json:"revision,omitempty"`
+	Created  *time.Time `bson:"created,omitempty" json:"created,omitempty"`
+	// SPDX expression as declared by the image
+	Licenses   string `bson:"licenses,omitempty" json:"licenses,omitempty"`
+	BaseName   string `bson:"base_name,omitempty" json:"base_name,omitempty"`
+	BaseDigest string `bson:"base_digest,omitempty" json:"base_digest,omitempty"`
+}
+
+// Extract returns the typed values of the well-known labels, OCI
+// labels take precedence over label-schema ones. It returns nil if
+// none of the labels is set. Dates that aren't RFC 3339 are ignored.
+func Extract(labels map[string]string) *Labels {
+	get := func(keys ...string) string {
+		for _, k := range keys {
+			if v := strings.TrimSpace(labels[k]); v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+
+	res := Labels{
+		Vendor:     get(Vendor, SchemaVendor),
+		Source:     NormalizeSource(get(Source, SchemaVcsUrl)),
+		Revision:   get(Revision, SchemaVcsRef),
+		Licenses:   get(Licenses),
+		BaseName:   get(BaseName),
+		BaseDigest: get(BaseDigest),
+	}
+	if created, err := time.Parse(time.RFC3339, get(Created, SchemaBuildDate)); err == nil {
+		created = created.UTC()
+		res.Created = &created
+	}
+
+	if res == (Labels{}) {
+		return nil
+	}
+	return &res
+}
+
+// NormalizeSource normalizes repository urls, so that the same
+// repository has the same url, e.g., git@github.com:org/repo.git and
+// https://github.com/org/repo/ both become https://github.com/org/repo.
+// Values that aren't urls are returned unchanged.
+func NormalizeSource(s string) string {
+	s = strings.TrimPrefix(s, "git+")
+
+	// scp-like syntax of ssh urls
+	if rest, found := strings.CutPrefix(s, "git@"); found {
+		if host, path, found := strings.Cut(rest, ":"); found {
+			s = "https://" + host + "/" + path
+		}
+	}
+
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return s
+	}
+	switch u.Scheme {
+	case "http", "https", "ssh", "git":
+		u.Scheme = "https"
+	default:
+		return s
+	}
+	u.User = nil
+	u.Host = strings.ToLower(u.Host)
+	u.Path = strings.TrimSuffix(strings.TrimSuffix(u.Path, "/"), ".git")
+	u.RawQuery, u.Fragment = "", ""
+
+	return u.String()
+}
@@ -0,0 +1,57 @@
+package oci
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExtract(t *testing.T) {
+	l := Extract(map[string]string{
+		Vendor:          "Acme",
+		SchemaVendor:    "Acme Corp",
+		SchemaVcsUrl:    "git@github.com:acme/app.git",
+		SchemaVcsRef:    "abc123",
+		Created:         "2024-05-01T12:00:00+02:00",
+		Licenses:        "Apache-2.0",
+		BaseName:        "docker.io/library/debian:12",
+		"maintainer":    "someone",
+		SchemaBuildDate: "yesterday",
+	})
+	if l == nil {
+		t.Fatalf("expected labels")
+	}
+
+	expected := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	if l.Vendor != "Acme" || l.Source != "https://github.com/acme/app" || l.Revision != "abc123" ||
+		l.Licenses != "Apache-2.0" || l.BaseName != "docker.io/library/debian:12" || l.BaseDigest != "" {
+		t.Fatalf("unexpected labels %+v", l)
+	}
+	if l.Created == nil || !l.Created.Equal(expected) {
+		t.Fatalf("expected %s, got %v", expected, l.Created)
+	}
+
+	if l := Extract(map[string]string{SchemaBuildDate: "yesterday", "maintainer": "someone"}); l != nil {
+		t.Fatalf("expected no labels, got %+v", l)
+	}
+	if Extract(nil) != nil {
+		t.Fatalf("expected no labels for nil")
+	}
+}
+
+func TestNormalizeSource(t *testing.T) {
+	tests := []struct{ raw, expected string }{
+		{"https://github.com/org/repo", "https://github.com/org/repo"},
+		{"https://GitHub.com/org/repo.git/", "https://github.com/org/repo"},
+		{"git+https://github.com/org/repo.git", "https://github.com/org/repo"},
+		{"git@gitlab.com:group/sub/repo.git", "https://gitlab.com/group/sub/repo"},
+		{"ssh://git@github.com/org/repo", "https://github.com/org/repo"},
+		{"http://github.com/org/repo?tab=readme#top", "https://github.com/org/repo"},
+		{"not a url", "not a url"},
+	}
+
+	for _, test := range tests {
+		if s := NormalizeSource(test.raw); s != test.expected {
+			t.Fatalf("expected %s for %s, got %s", test.expected, test.raw, s)
+		}
+	}
+}
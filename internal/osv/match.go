@@ -0,0 +1,365 @@
+package osv
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"sbom-processor/internal/purl"
+	"sbom-processor/internal/sbom"
+	"sbom-processor/internal/semver"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Query identifies a component in the OSV data
+type Query struct {
+	Ecosystem string // OSV ecosystem without suffix, e.g., Debian
+	Release   string // distro release matched against the ecosystem suffix, e.g., 12
+	Name      string // package name as used by OSV
+	Version   string
+}
+
+// syft component types and their OSV ecosystems
+var ecosystems = map[string]string{
+	"java-archive": "Maven",
+	"npm":          "npm",
+	"python":       "PyPI",
+	"go-module":    "Go",
+	"rust-crate":   "crates.io",
+	"gem":          "RubyGems",
+	"dotnet":       "NuGet",
+	"php-composer": "Packagist",
+}
+
+// distro ids and their OSV ecosystems for os packages
+var distroEcosystems = map[string]string{
+	"debian": "Debian",
+	"ubuntu": "Ubuntu",
+	"alpine": "Alpine",
+}
+
+// QueryFor maps a component to its OSV ecosystem and name.
+// returns false if the ecosystem of the component isn't supported.
+func QueryFor(c *sbom.Component, d sbom.Distro) (Query, bool) {
+	q := Query{Name: c.Name, Version: c.Version}
+	p, _ := purl.Parse(c.Purl)
+
+	switch c.Type {
+	case "deb", "apk":
+		e, ok := distroEcosystems[d.Id]
+		if !ok {
+			return q, false
+		}
+		q.Ecosystem = e
+		q.Release = release(d)
+		// OSV uses the source package, syft stores it as upstream qualifier
+		if p != nil && p.Qualifiers["upstream"] != "" {
+			name, _, _ := strings.Cut(p.Qualifiers["upstream"], "@")
+			q.Name = strings.TrimSpace(name)
+		}
+	case "java-archive":
+		// the artifact name alone is ambiguous
+		if p == nil || p.Namespace == "" {
+			return q, false
+		}
+		q.Ecosystem = ecosystems[c.Type]
+		q.Name = p.Namespace + ":" + p.Name
+	default:
+		e, ok := ecosystems[c.Type]
+		if !ok {
+			return q, false
+		}
+		q.Ecosystem = e
+		if p != nil && p.Namespace != "" && c.Type == "npm" {
+			q.Name = p.Namespace + "/" + p.Name
+		}
+	}
+
+	return q, q.Name != "" && q.Version != ""
+}
+
+// release as used in the OSV ecosystem suffix:
+// Debian:12, Ubuntu:22.04:LTS, Alpine:v3.18
+func release(d sbom.Distro) string {
+	switch d.Id {
+	case "debian":
+		major, _, _ := strings.Cut(d.Version, ".")
+		return major
+	case "alpine":
+		parts := strings.Split(d.Version, ".")
+		if len(parts) < 2 {
+			return ""
+		}
+		return "v" + parts[0] + "." + parts[1]
+	default:
+		return d.Version
+	}
+}
+
+func (q *Query) matches(p Package) bool {
+	ecosystem, suffix := splitEcosystem(p.Ecosystem)
+	if ecosystem != q.Ecosystem || p.Name != q.Name {
+		return false
+	}
+	return suffix == "" || q.Release == "" || strings.HasPrefix(suffix, q.Release)
+}
+
+type compareFunc func(a, b string) (int, error)
+
+func compareDebian(a, b string) (int, error) {
+	return semver.CompareDebian(a, b), nil
+}
+
+func compareMaven(a, b string) (int, error) {
+	return semver.CompareMaven(a, b), nil
+}
+
+// returns the comparator for the range type and ecosystem.
+// ECOSYSTEM ranges are compared with the ordering of the ecosystem,
+// ranges of ecosystems without a known ordering aren't evaluated.
+func comparator(ecosystem string, rangeType string) (compareFunc, bool) {
+	switch rangeType {
+	case "SEMVER":
+		return semver.Compare, true
+	case "ECOSYSTEM":
+		switch ecosystem {
+		case "Debian", "Ubuntu":
+			return compareDebian, true
+		case "Alpine":
+			return semver.CompareApk, true
+		case "Maven":
+			return compareMaven, true
+		case "PyPI":
+			return semver.ComparePep440, true
+		case "npm", "crates.io", "Go", "NuGet":
+			return semver.Compare, true
+		default:
+			return nil, false
+		}
+	default:
+		// GIT ranges refer to commits, not versions
+		return nil, false
+	}
+}
+
+// Affects evaluates whether version is affected according to the
+// explicit versions and the ranges of a. Returns the first fixed
+// version after version if known.
+func Affects(a *Affected, version string) (bool, string) {
+	ecosystem, _ := splitEcosystem(a.Package.Ecosystem)
+
+	if slices.Contains(a.Versions, version) {
+		return true, ""
+	}
+
+	for _, r := range a.Ranges {
+		cmp, ok := comparator(ecosystem, r.Type)
+		if !ok {
+			continue
+		}
+
+		affected, fixed, err := inRange(version, r.Events, cmp)
+		if err == nil && affected {
+			return true, fixed
+		}
+	}
+
+	return false, ""
+}
+
+func eventVersion(e Event) string {
+	switch {
+	case e.Introduced != "":
+		return e.Introduced
+	case e.Fixed != "":
+		return e.Fixed
+	case e.LastAffected != "":
+		return e.LastAffected
+	default:
+		return e.Limit
+	}
+}
+
+// evaluates the sorted events as described in the OSV schema
+func inRange(version string, events []Event, cmp compareFunc) (bool, string, error) {
+	var sortErr error
+	events = slices.Clone(events)
+	slices.SortStableFunc(events, func(a, b Event) int {
+		va, vb := eventVersion(a), eventVersion(b)
+		switch {
+		case va == vb:
+			return 0
+		case va == "0":
+			return -1
+		case vb == "0":
+			return 1
+		}
+		c, err := cmp(va, vb)
+		if err != nil {
+			sortErr = err
+		}
+		return c
+	})
+	if sortErr != nil {
+		return false, "", sortErr
+	}
+
+	affected := false
+	fixed := ""
+	for _, e := range events {
+		v := eventVersion(e)
+		if e.Introduced == "0" {
+			affected = true
+			continue
+		}
+
+		c, err := cmp(version, v)
+		if err != nil {
+			return false, "", err
+		}
+
+		switch {
+		case e.Introduced != "":
+			if c >= 0 {
+				affected = true
+			}
+		case e.Fixed != "":
+			if c >= 0 {
+				affected = false
+			} else if affected && fixed == "" {
+				fixed = e.Fixed
+			}
+		case e.LastAffected != "":
+			if c > 0 {
+				affected = false
+			}
+		case e.Limit != "":
+			if c >= 0 {
+				affected = false
+			}
+		}
+	}
+
+	if !affected {
+		fixed = ""
+	}
+
+	return affected, fixed, nil
+}
+
+type Finding struct {
+	ComponentId string   `bson:"component_id" json:"component_id"`
+	Name        string   `bson:"name" json:"name"`
+	Version     string   `bson:"version" json:"version"`
+	Type        string   `bson:"type" json:"type"`
+	Ecosystem   string   `bson:"ecosystem" json:"ecosystem"`
+	AdvisoryId  string   `bson:"advisory_id" json:"advisory_id"`
+	Aliases     []string `bson:"aliases" json:"aliases"`
+	Summary     string   `bson:"summary" json:"summary"`
+	Fixed       string   `bson:"fixed,omitempty" json:"fixed,omitempty"` // first fixed version if known
+}
+
+type SbomFindings struct {
+	SbomId     bson.ObjectID `bson:"sbom_id" json:"sbom_id"`
+	Source     sbom.Source   `bson:"source" json:"source"`
+	MatchedAt  time.Time     `bson:"matched_at" json:"matched_at"`
+	Components int           `bson:"components" json:"components"`
+	Queried    int           `bson:"queried" json:"queried"`       // components with supported ecosystem
+	Vulnerable int           `bson:"vulnerable" json:"vulnerable"` // components with at least one finding
+	Findings   []Finding     `bson:"findings" json:"findings"`
+}
+
+// AdvisoryLookup returns all advisories affecting a package with one of the names
+type AdvisoryLookup interface {
+	Advisories(names []string) ([]Advisory, error)
+}
+
+// MatchSbom evaluates the advisories of all components of s
+func MatchSbom(s *sbom.StoredSbom, lookup AdvisoryLookup) (*SbomFindings, error) {
+	res := SbomFindings{
+		SbomId:     s.Id,
+		Source:     s.Source,
+		MatchedAt:  time.Now().UTC(),
+		Components: len(s.Components),
+		Findings:   []Finding{},
+	}
+
+	queries := make([]*Query, len(s.Components))
+	var names []string
+	for i := range s.Components {
+		q, ok := QueryFor(&s.Components[i], s.Distro)
+		if !ok {
+			continue
+		}
+		queries[i] = &q
+		res.Queried++
+		if !slices.Contains(names, q.Name) {
+			names = append(names, q.Name)
+		}
+	}
+
+	if len(names) == 0 {
+		return &res, nil
+	}
+
+	advisories, err := lookup.Advisories(names)
+	if err != nil {
+		return nil, fmt.Errorf("advisory lookup failed: %w", err)
+	}
+
+	byName := make(map[string][]*Advisory)
+	for i := range advisories {
+		a := &advisories[i]
+		if a.Withdrawn != "" {
+			continue
+		}
+		for _, af := range a.Affected {
+			if !slices.Contains(byName[af.Package.Name], a) {
+				byName[af.Package.Name] = append(byName[af.Package.Name], a)
+			}
+		}
+	}
+
+	for i, q := range queries {
+		if q == nil {
+			continue
+		}
+		c := &s.Components[i]
+
+		found := false
+		for _, a := range byName[q.Name] {
+			for _, af := range a.Affected {
+				if !q.matches(af.Package) {
+					continue
+				}
+				affected, fixed := Affects(&af, q.Version)
+				if !affected {
+					continue
+				}
+
+				found = true
+				res.Findings = append(res.Findings, Finding{
+					ComponentId: c.Id,
+					Name:        c.Name,
+					Version:     c.Version,
+					Type:        c.Type,
+					Ecosystem:   af.Package.Ecosystem,
+					AdvisoryId:  a.Id,
+					Aliases:     a.Aliases,
+					Summary:     a.Summary,
+					Fixed:       fixed,
+				})
+				// one finding per advisory and component
+				break
+			}
+		}
+
+		if found {
+			res.Vulnerable++
+		}
+	}
+
+	return &res, nil
+}
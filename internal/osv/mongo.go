@@ -0,0 +1,28 @@
+package osv
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// MongoLookup reads the advisories imported into the osv collection
+type MongoLookup struct {
+	Collection *mongo.Collection
+	Ctx        context.Context
+}
+
+func (l *MongoLookup) Advisories(names []string) ([]Advisory, error) {
+	filter := bson.D{{Key: "affected.package.name", Value: bson.D{{Key: "$in", Value: names}}}}
+	cursor, err := l.Collection.Find(l.Ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []Advisory
+	if err := cursor.All(l.Ctx, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,102 @@
+package osv
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"path/filepath"
+	"strings"
+)
+
+// Advisory is the subset of the OSV schema used for matching
+// https://ossf.github.io/osv-schema/
+type Advisory struct {
+	Id        string     `bson:"id" json:"id"`
+	Modified  string     `bson:"modified" json:"modified"`
+	Published string     `bson:"published" json:"published"`
+	Withdrawn string     `bson:"withdrawn,omitempty" json:"withdrawn,omitempty"`
+	Aliases   []string   `bson:"aliases" json:"aliases"`
+	Summary   string     `bson:"summary" json:"summary"`
+	Severity  []Severity `bson:"severity" json:"severity"`
+	Affected  []Affected `bson:"affected" json:"affected"`
+}
+
+type Severity struct {
+	Type  string `bson:"type" json:"type"`
+	Score string `bson:"score" json:"score"`
+}
+
+type Affected struct {
+	Package  Package  `bson:"package" json:"package"`
+	Ranges   []Range  `bson:"ranges" json:"ranges"`
+	Versions []string `bson:"versions" json:"versions"`
+}
+
+type Package struct {
+	Ecosystem string `bson:"ecosystem" json:"ecosystem"`
+	Name      string `bson:"name" json:"name"`
+	Purl      string `bson:"purl,omitempty" json:"purl,omitempty"`
+}
+
+type Range struct {
+	Type   string  `bson:"type" json:"type"`
+	Events []Event `bson:"events" json:"events"`
+}
+
+type Event struct {
+	Introduced   string `bson:"introduced,omitempty" json:"introduced,omitempty"`
+	Fixed        string `bson:"fixed,omitempty" json:"fixed,omitempty"`
+	LastAffected string `bson:"last_affected,omitempty" json:"last_affected,omitempty"`
+	Limit        string `bson:"limit,omitempty" json:"limit,omitempty"`
+}
+
+// ReadZip iterates all advisories of an OSV bulk export, e.g., the
+// all.zip of an ecosystem. Entries that can't be decoded are yielded
+// with their error. If the zip can't be opened the error is yielded once.
+func ReadZip(p string) iter.Seq2[*Advisory, error] {
+	return func(yield func(*Advisory, error) bool) {
+		r, err := zip.OpenReader(p)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer r.Close()
+
+		for _, f := range r.File {
+			if f.FileInfo().IsDir() || filepath.Ext(f.Name) != ".json" {
+				continue
+			}
+
+			a, err := readEntry(f)
+			if err != nil {
+				err = fmt.Errorf("%s: %w", f.Name, err)
+			}
+			if !yield(a, err) {
+				return
+			}
+		}
+	}
+}
+
+func readEntry(f *zip.File) (*Advisory, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var a Advisory
+	if err := json.NewDecoder(rc).Decode(&a); err != nil {
+		return nil, err
+	}
+
+	return &a, nil
+}
+
+// splits an OSV ecosystem into its name and suffix,
+// e.g., Debian:12 into Debian and 12
+func splitEcosystem(e string) (string, string) {
+	name, suffix, _ := strings.Cut(e, ":")
+	return name, suffix
+}
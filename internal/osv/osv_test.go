@@ -0,0 +1,241 @@
+package osv
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"sbom-processor/internal/sbom"
+)
+
+func TestReadZip(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "all.zip")
+
+	f, err := os.Create(p)
+	if err != nil {
+		t.Fatalf("unable to create test file %s", err.Error())
+	}
+
+	w := zip.NewWriter(f)
+	for name, content := range map[string]string{
+		"DSA-1.json":  `{"id": "DSA-1", "affected": [{"package": {"ecosystem": "Debian:12", "name": "openssl"}}]}`,
+		"broken.json": `{"id": `,
+		"README.txt":  "not an advisory",
+	} {
+		e, _ := w.Create(name)
+		e.Write([]byte(content))
+	}
+	w.Close()
+	f.Close()
+
+	advisories := 0
+	failed := 0
+	for a, err := range ReadZip(p) {
+		if err != nil {
+			failed++
+			continue
+		}
+		advisories++
+		if a.Id != "DSA-1" || a.Affected[0].Package.Name != "openssl" {
+			t.Fatalf("unexpected advisory %+v", a)
+		}
+	}
+
+	if advisories != 1 || failed != 1 {
+		t.Fatalf("one valid and one broken advisory expected, got %d and %d", advisories, failed)
+	}
+
+	for _, err := range ReadZip(filepath.Join(dir, "missing.zip")) {
+		if err == nil {
+			t.Fatalf("error expected for missing file")
+		}
+	}
+}
+
+func TestAffects(t *testing.T) {
+	debian := Affected{
+		Package: Package{Ecosystem: "Debian:12", Name: "openssl"},
+		Ranges: []Range{{Type: "ECOSYSTEM", Events: []Event{
+			{Fixed: "3.0.11-1~deb12u2"},
+			{Introduced: "0"},
+		}}},
+	}
+
+	tests := []struct {
+		affected *Affected
+		version  string
+		expected bool
+		fixed    string
+	}{
+		{&debian, "3.0.11-1~deb12u1", true, "3.0.11-1~deb12u2"},
+		{&debian, "3.0.9-1", true, "3.0.11-1~deb12u2"},
+		{&debian, "3.0.11-1~deb12u2", false, ""},
+		{&debian, "3.0.11-1", false, ""},
+	}
+
+	semverRange := Affected{
+		Package: Package{Ecosystem: "npm", Name: "lodash"},
+		Ranges: []Range{{Type: "SEMVER", Events: []Event{
+			{Introduced: "4.0.0"},
+			{LastAffected: "4.17.20"},
+		}}},
+		Versions: []string{"3.10.1"},
+	}
+	tests = append(tests,
+		struct {
+			affected *Affected
+			version  string
+			expected bool
+			fixed    string
+		}{&semverRange, "4.17.20", true, ""},
+		struct {
+			affected *Affected
+			version  string
+			expected bool
+			fixed    string
+		}{&semverRange, "4.17.21", false, ""},
+		struct {
+			affected *Affected
+			version  string
+			expected bool
+			fixed    string
+		}{&semverRange, "3.10.1", true, ""},
+		struct {
+			affected *Affected
+			version  string
+			expected bool
+			fixed    string
+		}{&semverRange, "3.10.2", false, ""},
+	)
+
+	alpine := Affected{
+		Package: Package{Ecosystem: "Alpine:v3.19", Name: "openssl"},
+		Ranges: []Range{{Type: "ECOSYSTEM", Events: []Event{
+			{Introduced: "0"},
+			{Fixed: "3.1.4-r1"},
+		}}},
+	}
+	maven := Affected{
+		Package: Package{Ecosystem: "Maven", Name: "org.apache.logging.log4j:log4j-core"},
+		Ranges: []Range{{Type: "ECOSYSTEM", Events: []Event{
+			{Introduced: "2.0-beta9"},
+			{Fixed: "2.15.0"},
+		}}},
+	}
+	pypi := Affected{
+		Package: Package{Ecosystem: "PyPI", Name: "django"},
+		Ranges: []Range{{Type: "ECOSYSTEM", Events: []Event{
+			{Introduced: "4.2a1"},
+			{Fixed: "4.2.post1"},
+		}}},
+	}
+	// no ordering known for the ecosystem, the range is skipped
+	rubygems := Affected{
+		Package: Package{Ecosystem: "RubyGems", Name: "rails"},
+		Ranges: []Range{{Type: "ECOSYSTEM", Events: []Event{
+			{Introduced: "0"},
+			{Fixed: "7.0.1"},
+		}}},
+	}
+	for _, test := range []struct {
+		affected *Affected
+		version  string
+		expected bool
+		fixed    string
+	}{
+		{&alpine, "3.1.4_rc1-r0", true, "3.1.4-r1"},
+		{&alpine, "3.1.4-r0", true, "3.1.4-r1"},
+		{&alpine, "3.1.4-r1", false, ""},
+		{&alpine, "3.1.4_p1-r0", false, ""},
+		{&maven, "2.0-alpha1", false, ""},
+		{&maven, "2.0-rc1", true, "2.15.0"},
+		{&maven, "2.14.1", true, "2.15.0"},
+		{&maven, "2.15.0-SNAPSHOT", true, "2.15.0"},
+		{&maven, "2.15.0", false, ""},
+		{&pypi, "4.2.dev1", false, ""},
+		{&pypi, "4.2rc1", true, "4.2.post1"},
+		{&pypi, "4.2", true, "4.2.post1"},
+		{&pypi, "4.2.post1", false, ""},
+		{&rubygems, "7.0.0", false, ""},
+	} {
+		tests = append(tests, test)
+	}
+
+	for _, test := range tests {
+		affected, fixed := Affects(test.affected, test.version)
+		if affected != test.expected || fixed != test.fixed {
+			t.Fatalf("unexpected result for %s %s: %t %s", test.affected.Package.Name, test.version, affected, fixed)
+		}
+	}
+}
+
+type sliceLookup []Advisory
+
+func (l sliceLookup) Advisories(names []string) ([]Advisory, error) {
+	return l, nil
+}
+
+func TestMatchSbom(t *testing.T) {
+	s := sbom.StoredSbom{
+		CyclonedxSbom: sbom.CyclonedxSbom{
+			Distro: sbom.Distro{Id: "debian", Version: "12"},
+			Components: []sbom.Component{
+				{Id: "1", Name: "libssl3", Type: "deb", Version: "3.0.9-1", Purl: "pkg:deb/debian/libssl3@3.0.9-1?arch=amd64&upstream=openssl&distro=debian-12"},
+				{Id: "2", Name: "guice", Type: "java-archive", Version: "4.0"},
+				{Id: "3", Name: "zlib1g", Type: "deb", Version: "1:1.2.13.dfsg-1", Purl: "pkg:deb/debian/zlib1g@1:1.2.13.dfsg-1?upstream=zlib"},
+			},
+		},
+	}
+
+	lookup := sliceLookup{
+		{
+			Id:      "DSA-1",
+			Aliases: []string{"CVE-1"},
+			Affected: []Affected{
+				{
+					Package: Package{Ecosystem: "Debian:11", Name: "openssl"},
+					Ranges:  []Range{{Type: "ECOSYSTEM", Events: []Event{{Introduced: "0"}, {Fixed: "1.1.1w-0+deb11u1"}}}},
+				},
+				{
+					Package: Package{Ecosystem: "Debian:12", Name: "openssl"},
+					Ranges:  []Range{{Type: "ECOSYSTEM", Events: []Event{{Introduced: "0"}, {Fixed: "3.0.11-1~deb12u2"}}}},
+				},
+			},
+		},
+		{
+			Id:        "DSA-2",
+			Withdrawn: "2024-01-01T00:00:00Z",
+			Affected: []Affected{{
+				Package: Package{Ecosystem: "Debian:12", Name: "openssl"},
+				Ranges:  []Range{{Type: "ECOSYSTEM", Events: []Event{{Introduced: "0"}}}},
+			}},
+		},
+		{
+			Id: "DSA-3",
+			Affected: []Affected{{
+				Package: Package{Ecosystem: "Debian:12", Name: "zlib"},
+				Ranges:  []Range{{Type: "ECOSYSTEM", Events: []Event{{Introduced: "0"}, {Fixed: "1:1.2.13.dfsg-1"}}}},
+			}},
+		},
+	}
+
+	f, err := MatchSbom(&s, lookup)
+	if err != nil {
+		t.Fatalf("no error expected %s", err)
+	}
+
+	if f.Queried != 2 {
+		t.Fatalf("java archives without purl must not be queried, got %d queries", f.Queried)
+	}
+
+	if f.Vulnerable != 1 || len(f.Findings) != 1 {
+		t.Fatalf("exactly one finding expected, got %+v", f.Findings)
+	}
+
+	finding := f.Findings[0]
+	if finding.ComponentId != "1" || finding.AdvisoryId != "DSA-1" || finding.Ecosystem != "Debian:12" || finding.Fixed != "3.0.11-1~deb12u2" {
+		t.Fatalf("unexpected finding %+v", finding)
+	}
+}
@@ -0,0 +1,92 @@
+package purl
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// PackageURL is a parsed package url as described in
+// https://github.com/package-url/purl-spec
+// pkg:type/namespace/name@version?qualifiers#subpath
+type PackageURL struct {
+	Type       string
+	Namespace  string
+	Name       string
+	Version    string
+	Qualifiers map[string]string
+	Subpath    string
+}
+
+func Parse(raw string) (*PackageURL, error) {
+	rest, found := strings.CutPrefix(raw, "pkg:")
+	if !found {
+		return nil, fmt.Errorf("invalid purl %s: missing pkg scheme", raw)
+	}
+
+	var p PackageURL
+
+	rest, subpath, _ := strings.Cut(rest, "#")
+	p.Subpath = strings.Trim(subpath, "/")
+
+	rest, qualifiers, _ := strings.Cut(rest, "?")
+	if qualifiers != "" {
+		p.Qualifiers = make(map[string]string)
+		for _, q := range strings.Split(qualifiers, "&") {
+			k, v, _ := strings.Cut(q, "=")
+			v, err := url.PathUnescape(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid purl qualifier %s: %w", q, err)
+			}
+			p.Qualifiers[strings.ToLower(k)] = v
+		}
+	}
+
+	rest = strings.TrimLeft(rest, "/")
+	typ, rest, found := strings.Cut(rest, "/")
+	if !found || typ == "" {
+		return nil, fmt.Errorf("invalid purl %s: missing type or name", raw)
+	}
+	p.Type = strings.ToLower(typ)
+
+	// the version is separated by the last @ after the last /
+	// as namespaces can contain @, e.g., npm scopes
+	lastSlash := strings.LastIndex(rest, "/")
+	if at := strings.LastIndex(rest, "@"); at > lastSlash {
+		v, err := url.PathUnescape(rest[at+1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid purl version %s: %w", raw, err)
+		}
+		p.Version = v
+		rest = rest[:at]
+		lastSlash = strings.LastIndex(rest, "/")
+	}
+
+	name, err := url.PathUnescape(strings.TrimRight(rest[lastSlash+1:], "/"))
+	if err != nil || name == "" {
+		return nil, fmt.Errorf("invalid purl %s: missing or invalid name", raw)
+	}
+	p.Name = name
+
+	if lastSlash > 0 {
+		segments := strings.Split(rest[:lastSlash], "/")
+		for i, s := range segments {
+			segments[i], err = url.PathUnescape(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid purl namespace %s: %w", raw, err)
+			}
+		}
+		p.Namespace = strings.Join(segments, "/")
+	}
+
+	return &p, nil
+}
+
+// Package identifies the package independent of its version,
+// qualifiers, and subpath: type/namespace/name
+func (p *PackageURL) Package() string {
+	if p.Namespace == "" {
+		return p.Type + "/" + p.Name
+	}
+	return p.Type + "/" + p.Namespace + "/" + p.Name
+}
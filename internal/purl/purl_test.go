@@ -0,0 +1,70 @@
+package purl
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		raw       string
+		expected  PackageURL
+		qualifier string
+		pkg       string
+	}{
+		{
+			raw:      "pkg:maven/com.google.inject/guice@4.0",
+			expected: PackageURL{Type: "maven", Namespace: "com.google.inject", Name: "guice", Version: "4.0"},
+			pkg:      "maven/com.google.inject/guice",
+		},
+		{
+			raw:       "pkg:deb/debian/libssl3@3.0.11-1~deb12u2?arch=amd64&distro=debian-12",
+			expected:  PackageURL{Type: "deb", Namespace: "debian", Name: "libssl3", Version: "3.0.11-1~deb12u2"},
+			qualifier: "debian-12",
+			pkg:       "deb/debian/libssl3",
+		},
+		{
+			raw:      "pkg:npm/%40angular/core@16.0.0",
+			expected: PackageURL{Type: "npm", Namespace: "@angular", Name: "core", Version: "16.0.0"},
+			pkg:      "npm/@angular/core",
+		},
+		{
+			raw:      "pkg:pypi/requests",
+			expected: PackageURL{Type: "pypi", Name: "requests"},
+			pkg:      "pypi/requests",
+		},
+		{
+			raw:      "pkg:golang/github.com/hashicorp/go-version@v1.7.0#sub/path",
+			expected: PackageURL{Type: "golang", Namespace: "github.com/hashicorp", Name: "go-version", Version: "v1.7.0", Subpath: "sub/path"},
+			pkg:      "golang/github.com/hashicorp/go-version",
+		},
+	}
+
+	for _, test := range tests {
+		p, err := Parse(test.raw)
+		if err != nil {
+			t.Fatalf("no error expected for %s, got %s", test.raw, err)
+		}
+
+		if p.Type != test.expected.Type ||
+			p.Namespace != test.expected.Namespace ||
+			p.Name != test.expected.Name ||
+			p.Version != test.expected.Version ||
+			p.Subpath != test.expected.Subpath {
+			t.Fatalf("unexpected result for %s: %+v", test.raw, p)
+		}
+
+		if test.qualifier != "" && p.Qualifiers["distro"] != test.qualifier {
+			t.Fatalf("unexpected qualifiers for %s: %+v", test.raw, p.Qualifiers)
+		}
+
+		if p.Package() != test.pkg {
+			t.Fatalf("unexpected package for %s: %s", test.raw, p.Package())
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, raw := range []string{"", "maven/guice@1.0", "pkg:maven", "pkg:/guice", "pkg:maven/@1.0"} {
+		if _, err := Parse(raw); err == nil {
+			t.Fatalf("error expected for %s", raw)
+		}
+	}
+}
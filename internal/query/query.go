@@ -0,0 +1,214 @@
+package query
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"sbom-processor/internal/table"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// Param of a query. Params without default are required.
+type Param struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Type        ParamType `json:"type,omitempty"` // defaults to string
+	Default     *string   `json:"default,omitempty"`
+}
+
+type ParamType string
+
+const (
+	String ParamType = "string"
+	Number ParamType = "number"
+	Bool   ParamType = "bool"
+)
+
+// Definition of a named aggregation pipeline. The pipeline is
+// written in MongoDB extended JSON and may contain {{param}}
+// placeholders that are replaced before it is parsed.
+type Definition struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Collection  string          `json:"collection,omitempty"` // defaults to the SBOM collection
+	Params      []Param         `json:"params,omitempty"`
+	Pipeline    json.RawMessage `json:"pipeline"`
+	// columns of the tabular output, inferred from the first result if empty
+	Columns table.Schema `json:"columns,omitempty"`
+}
+
+//go:embed catalogue/*.json
+var catalogueFiles embed.FS
+
+// Catalogue returns the built-in queries sorted by name
+func Catalogue() ([]Definition, error) {
+	entries, err := catalogueFiles.ReadDir("catalogue")
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]Definition, 0, len(entries))
+	for _, e := range entries {
+		data, err := catalogueFiles.ReadFile(path.Join("catalogue", e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		d, err := parse(data, strings.TrimSuffix(e.Name(), ".json"))
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, *d)
+	}
+
+	slices.SortFunc(res, func(a, b Definition) int { return strings.Compare(a.Name, b.Name) })
+	return res, nil
+}
+
+// Lookup returns the built-in query with the given name
+func Lookup(name string) (*Definition, error) {
+	catalogue, err := Catalogue()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range catalogue {
+		if catalogue[i].Name == name {
+			return &catalogue[i], nil
+		}
+	}
+
+	names := make([]string, len(catalogue))
+	for i, d := range catalogue {
+		names[i] = d.Name
+	}
+	return nil, fmt.Errorf("unknown query %s, available queries: %s", name, strings.Join(names, ", "))
+}
+
+// Load reads a user defined query from a JSON file. The file either
+// contains a definition in the catalogue format or only the pipeline
+// as JSON array, the name defaults to the file name.
+func Load(p string) (*Definition, error) {
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSuffix(path.Base(p), path.Ext(p))
+	return parse(data, name)
+}
+
+func parse(data []byte, name string) (*Definition, error) {
+	var d Definition
+	if trimmed := strings.TrimSpace(string(data)); strings.HasPrefix(trimmed, "[") {
+		d.Pipeline = json.RawMessage(trimmed)
+	} else if err := json.Unmarshal(data, &d); err != nil {
+		return nil, fmt.Errorf("invalid query definition %s: %w", name, err)
+	}
+
+	if d.Name == "" {
+		d.Name = name
+	}
+	if len(d.Pipeline) == 0 {
+		return nil, fmt.Errorf("query %s has no pipeline", d.Name)
+	}
+
+	// placeholders must refer to declared params
+	for _, m := range placeholder.FindAllStringSubmatch(string(d.Pipeline), -1) {
+		if !slices.ContainsFunc(d.Params, func(p Param) bool { return p.Name == m[1] }) {
+			d.Params = append(d.Params, Param{Name: m[1]})
+		}
+	}
+
+	return &d, nil
+}
+
+var placeholder = regexp.MustCompile(`\{\{(\w+)\}\}`)
+var stringPlaceholder = regexp.MustCompile(`"\{\{(\w+)\}\}"`)
+
+// Render replaces the placeholders with the given params and parses
+// the pipeline. A placeholder that is the whole JSON string is replaced
+// with a number or boolean if the value is one, e.g., "{{limit}}".
+func (d *Definition) Render(params map[string]string) (mongo.Pipeline, error) {
+	values := make(map[string]string, len(d.Params))
+	types := make(map[string]ParamType, len(d.Params))
+	for _, p := range d.Params {
+		v, ok := params[p.Name]
+		switch {
+		case ok:
+		case p.Default != nil:
+			v = *p.Default
+		default:
+			return nil, fmt.Errorf("missing required param %s of query %s", p.Name, d.Name)
+		}
+
+		v, err := normalize(p, v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid param %s of query %s: %w", p.Name, d.Name, err)
+		}
+		values[p.Name] = v
+		types[p.Name] = p.Type
+	}
+	for k := range params {
+		if _, ok := values[k]; !ok {
+			return nil, fmt.Errorf("unknown param %s of query %s", k, d.Name)
+		}
+	}
+
+	rendered := stringPlaceholder.ReplaceAllStringFunc(string(d.Pipeline), func(m string) string {
+		name := stringPlaceholder.FindStringSubmatch(m)[1]
+		if types[name] == Number || types[name] == Bool {
+			return values[name]
+		}
+		return quote(values[name])
+	})
+	rendered = placeholder.ReplaceAllStringFunc(rendered, func(m string) string {
+		// embedded in a string, only escape the value
+		q := quote(values[placeholder.FindStringSubmatch(m)[1]])
+		return q[1 : len(q)-1]
+	})
+
+	// extended JSON must be a document on the top level
+	var doc struct {
+		Pipeline []bson.D `bson:"pipeline"`
+	}
+	if err := bson.UnmarshalExtJSON([]byte(`{"pipeline": `+rendered+`}`), false, &doc); err != nil {
+		return nil, fmt.Errorf("invalid pipeline of query %s: %w", d.Name, err)
+	}
+
+	return mongo.Pipeline(doc.Pipeline), nil
+}
+
+// returns the value as it is inserted into the pipeline
+func normalize(p Param, v string) (string, error) {
+	switch p.Type {
+	case "", String:
+		return v, nil
+	case Number:
+		if _, err := strconv.ParseFloat(v, 64); err != nil || !json.Valid([]byte(v)) {
+			return "", fmt.Errorf("%s is not a number", v)
+		}
+		return v, nil
+	case Bool:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatBool(b), nil
+	default:
+		return "", fmt.Errorf("unknown type %s", p.Type)
+	}
+}
+
+func quote(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
@@ -0,0 +1,119 @@
+package query
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"sbom-processor/internal/table"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestCatalogue(t *testing.T) {
+	catalogue, err := Catalogue()
+	if err != nil {
+		t.Fatalf("no error expected %s", err)
+	}
+
+	if len(catalogue) != 9 || catalogue[0].Name != "components-per-distro" {
+		t.Fatalf("unexpected catalogue %+v", catalogue)
+	}
+
+	// all built-in queries must render with their defaults
+	params := map[string]map[string]string{
+		"images-per-component": {"name": "openssl"},
+	}
+	for _, d := range catalogue {
+		if d.Description == "" {
+			t.Fatalf("query %s has no description", d.Name)
+		}
+		if _, err := d.Render(params[d.Name]); err != nil {
+			t.Fatalf("no error expected for %s, got %s", d.Name, err)
+		}
+		if _, err := table.NewWriter(io.Discard, table.CSV, d.Columns); err != nil || len(d.Columns) == 0 {
+			t.Fatalf("invalid columns of %s: %v", d.Name, err)
+		}
+	}
+
+	if _, err := Lookup("unknown"); err == nil {
+		t.Fatalf("error expected for unknown query")
+	}
+}
+
+func TestRender(t *testing.T) {
+	d, err := Lookup("top-components")
+	if err != nil {
+		t.Fatalf("no error expected %s", err)
+	}
+
+	pipeline, err := d.Render(map[string]string{"limit": "5", "type": "deb"})
+	if err != nil {
+		t.Fatalf("no error expected %s", err)
+	}
+
+	limit := pipeline[len(pipeline)-1]
+	if limit[0].Key != "$limit" || limit[0].Value != int32(5) {
+		t.Fatalf("limit must be a number, got %v", limit)
+	}
+
+	if _, err := d.Render(map[string]string{"limit": "five"}); err == nil {
+		t.Fatalf("error expected for invalid number")
+	}
+	if _, err := d.Render(map[string]string{"unknown": "x"}); err == nil {
+		t.Fatalf("error expected for unknown param")
+	}
+
+	images, _ := Lookup("images-per-component")
+	if _, err := images.Render(nil); err == nil {
+		t.Fatalf("error expected for missing required param")
+	}
+
+	// string params are never converted
+	pipeline, err = images.Render(map[string]string{"name": "2048", "version": "1.0"})
+	if err != nil {
+		t.Fatalf("no error expected %s", err)
+	}
+	match := pipeline[0][0].Value.(bson.D)
+	if match[0].Value != "2048" {
+		t.Fatalf("name must stay a string, got %v", match)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	bare := filepath.Join(dir, "by-prefix.json")
+	os.WriteFile(bare, []byte(`[{"$match": {"source.name": {"$regex": "^{{prefix}}"}}}, {"$count": "images"}]`), 0644)
+
+	d, err := Load(bare)
+	if err != nil {
+		t.Fatalf("no error expected %s", err)
+	}
+	if d.Name != "by-prefix" || len(d.Params) != 1 || d.Params[0].Name != "prefix" {
+		t.Fatalf("unexpected definition %+v", d)
+	}
+
+	pipeline, err := d.Render(map[string]string{"prefix": `quay.io/"x"`})
+	if err != nil {
+		t.Fatalf("no error expected %s", err)
+	}
+	regex := pipeline[0][0].Value.(bson.D)[0].Value.(bson.D)[0].Value
+	if regex != `^quay.io/"x"` {
+		t.Fatalf("embedded params must be escaped, got %v", regex)
+	}
+
+	full := filepath.Join(dir, "full.json")
+	os.WriteFile(full, []byte(`{"name": "lags", "collection": "lag", "pipeline": [{"$limit": 1}]}`), 0644)
+	d, err = Load(full)
+	if err != nil || d.Name != "lags" || d.Collection != "lag" {
+		t.Fatalf("unexpected definition %+v %v", d, err)
+	}
+
+	invalid := filepath.Join(dir, "invalid.json")
+	os.WriteFile(invalid, []byte(`{"name": "empty"}`), 0644)
+	if _, err := Load(invalid); err == nil {
+		t.Fatalf("error expected for definition without pipeline")
+	}
+}
@@ -0,0 +1,214 @@
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log/slog"
+	"os"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"sbom-processor/internal/metrics"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// Version of the tool, set at build time with
+// -ldflags "-X sbom-processor/internal/report.Version=v1.2.3".
+// Falls back to the VCS revision of the build if empty.
+var Version = ""
+
+const (
+	StatusOk = "ok"
+	// some items failed, the run itself finished
+	StatusCompletedWithErrors = "completed_with_errors"
+	StatusFailed              = "failed"
+	// stopped by a signal, the items in flight were finished
+	StatusInterrupted = "interrupted"
+)
+
+// Job contains the counts of a metrics.Progress
+type Job struct {
+	Job         string           `bson:"job" json:"job"`
+	Total       int64            `bson:"total" json:"total"` // 0 if unknown
+	Processed   int64            `bson:"processed" json:"processed"`
+	Failed      int64            `bson:"failed" json:"failed"`
+	WriteFailed int64            `bson:"write_failed" json:"write_failed"`
+	Skipped     int64            `bson:"skipped" json:"skipped"`
+	Errors      map[string]int64 `bson:"errors" json:"errors"` // failed and write failed items by error class
+}
+
+// Report describes a single run of a command
+type Report struct {
+	Command         string            `bson:"command" json:"command"`
+	Version         string            `bson:"version" json:"version"`
+	Params          map[string]string `bson:"params" json:"params"`
+	StartedAt       time.Time         `bson:"started_at" json:"started_at"`
+	FinishedAt      time.Time         `bson:"finished_at" json:"finished_at"`
+	DurationSeconds float64           `bson:"duration_seconds" json:"duration_seconds"`
+	Status          string            `bson:"status" json:"status"`
+	Error           string            `bson:"error,omitempty" json:"error,omitempty"`
+	Jobs            []Job             `bson:"jobs" json:"jobs"`
+	// files and collections, collections are given as db.collection
+	Outputs []string `bson:"outputs" json:"outputs"`
+
+	mu       sync.Mutex
+	progress []*metrics.Progress
+	now      func() time.Time
+}
+
+// New starts the report of command. The parameters are taken
+// from the command line flags, so it must be called after flag.Parse.
+func New(command string) *Report {
+	return newReport(command, flag.CommandLine, time.Now)
+}
+
+func newReport(command string, flags *flag.FlagSet, now func() time.Time) *Report {
+	params := make(map[string]string)
+	flags.VisitAll(func(f *flag.Flag) {
+		params[f.Name] = f.Value.String()
+	})
+
+	return &Report{
+		Command:   command,
+		Version:   version(),
+		Params:    params,
+		StartedAt: now(),
+		Status:    StatusOk,
+		Jobs:      []Job{},
+		Outputs:   []string{},
+		now:       now,
+	}
+}
+
+func version() string {
+	if Version != "" {
+		return Version
+	}
+
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+
+	revision, modified := "", false
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			revision = s.Value
+		case "vcs.modified":
+			modified = s.Value == "true"
+		}
+	}
+
+	if revision == "" {
+		return info.Main.Version
+	}
+	if modified {
+		return revision + "-dirty"
+	}
+	return revision
+}
+
+// Track adds the counts of p to the report when it's published,
+// nil is ignored
+func (r *Report) Track(p *metrics.Progress) {
+	if p == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.progress = append(r.progress, p)
+}
+
+// Output records where the run wrote its results to
+func (r *Report) Output(locations ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Outputs = append(r.Outputs, locations...)
+}
+
+// Fail marks the whole run as failed
+func (r *Report) Fail(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Status = StatusFailed
+	r.Error = err.Error()
+}
+
+// Finish sets the end time and collects the counts of the tracked jobs
+func (r *Report) Finish() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.FinishedAt = r.now()
+	r.DurationSeconds = r.FinishedAt.Sub(r.StartedAt).Seconds()
+
+	r.Jobs = make([]Job, len(r.progress))
+	for i, p := range r.progress {
+		s := p.Snapshot()
+		r.Jobs[i] = Job{
+			Job:         p.Job(),
+			Total:       s.Total,
+			Processed:   s.Processed,
+			Failed:      s.Failed,
+			WriteFailed: s.WriteFailed,
+			Skipped:     s.Skipped,
+			Errors:      s.Errors,
+		}
+
+		if r.Status == StatusOk && (s.Failed > 0 || s.WriteFailed > 0) {
+			r.Status = StatusCompletedWithErrors
+		}
+	}
+}
+
+// Publish finishes the report and writes it as JSON to path, or to
+// stderr if path is empty. The report is additionally stored in
+// coll if it isn't nil. The run is reported as interrupted if ctx
+// was canceled, the report is published regardless.
+func (r *Report) Publish(ctx context.Context, path string, coll *mongo.Collection) error {
+	r.Finish()
+	if ctx.Err() != nil {
+		r.Status = StatusInterrupted
+	}
+
+	slog.Default().Info("Run finished", "command", r.Command, "status", r.Status,
+		"time elapsed", r.FinishedAt.Sub(r.StartedAt))
+
+	out := os.Stderr
+	if path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(r); err != nil {
+		return err
+	}
+
+	if coll != nil {
+		if _, err := coll.InsertOne(context.WithoutCancel(ctx), r); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Runs returns the collection to store the run reports in,
+// nil if name is empty
+func Runs(database *mongo.Database, name string) *mongo.Collection {
+	if name == "" {
+		return nil
+	}
+	return database.Collection(name)
+}
@@ -0,0 +1,106 @@
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"sbom-processor/internal/metrics"
+)
+
+func testReport(t *testing.T) (*Report, *time.Time) {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.String("db", "sbom_metadata", "")
+	flags.Int("logLevel", 0, "")
+	if err := flags.Parse([]string{"-db", "test"}); err != nil {
+		t.Fatalf("parse failed %s", err)
+	}
+
+	clock := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	return newReport("test", flags, func() time.Time { return clock }), &clock
+}
+
+func TestPublish(t *testing.T) {
+	r, clock := testReport(t)
+
+	p := metrics.NewProgress("report_test")
+	p.SetTotal(5)
+	p.Processed(2)
+	p.Skipped(1)
+	p.Fail(&metrics.StatusError{Code: 429})
+	p.Fail(errors.New("failed"))
+	r.Track(p)
+	r.Output("out/components.csv", "test.sboms")
+
+	*clock = clock.Add(90 * time.Second)
+
+	path := filepath.Join(t.TempDir(), "report.json")
+	if err := r.Publish(context.Background(), path, nil); err != nil {
+		t.Fatalf("publish failed %s", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read failed %s", err)
+	}
+
+	var published Report
+	if err := json.Unmarshal(content, &published); err != nil {
+		t.Fatalf("invalid report %s", err)
+	}
+
+	if published.Command != "test" || published.Version == "" {
+		t.Fatalf("unexpected command %s version %s", published.Command, published.Version)
+	}
+	if published.Params["db"] != "test" || published.Params["logLevel"] != "0" {
+		t.Fatalf("unexpected params %v", published.Params)
+	}
+	if published.DurationSeconds != 90 || !published.FinishedAt.Equal(*clock) {
+		t.Fatalf("unexpected duration %f", published.DurationSeconds)
+	}
+	if published.Status != StatusCompletedWithErrors {
+		t.Fatalf("unexpected status %s", published.Status)
+	}
+	if len(published.Outputs) != 2 {
+		t.Fatalf("unexpected outputs %v", published.Outputs)
+	}
+
+	if len(published.Jobs) != 1 {
+		t.Fatalf("unexpected jobs %+v", published.Jobs)
+	}
+	job := published.Jobs[0]
+	if job.Job != "report_test" || job.Total != 5 || job.Processed != 2 || job.Skipped != 1 || job.Failed != 2 {
+		t.Fatalf("unexpected job %+v", job)
+	}
+	if job.Errors["http_429"] != 1 || job.Errors["other"] != 1 {
+		t.Fatalf("unexpected error classes %v", job.Errors)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	r, _ := testReport(t)
+	r.Finish()
+	if r.Status != StatusOk {
+		t.Fatalf("expected ok, got %s", r.Status)
+	}
+
+	r.Fail(errors.New("connection refused"))
+	r.Finish()
+	if r.Status != StatusFailed || r.Error != "connection refused" {
+		t.Fatalf("unexpected status %s %s", r.Status, r.Error)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := r.Publish(ctx, filepath.Join(t.TempDir(), "report.json"), nil); err != nil {
+		t.Fatalf("publish failed %s", err)
+	}
+	if r.Status != StatusInterrupted {
+		t.Fatalf("expected interrupted, got %s", r.Status)
+	}
+}
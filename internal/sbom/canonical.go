@@ -0,0 +1,78 @@
+package sbom
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"slices"
+	"strings"
+)
+
+// prefix of the content digests
+const DigestAlgorithm = "sha256:"
+
+// Canonicalize sorts the components, their licenses and digests, and
+// the dependencies, so that equal SBOMs serialize to equal bytes.
+// Digest is set to the SHA-256 of the result.
+func (s *CyclonedxSbom) Canonicalize() error {
+	for i := range s.Components {
+		c := &s.Components[i]
+		slices.SortFunc(c.Licenses, func(a, b License) int {
+			return cmp.Or(strings.Compare(a.Value, b.Value), strings.Compare(a.SpdxExpression, b.SpdxExpression))
+		})
+		if c.Metadata != nil {
+			slices.SortFunc(c.Metadata.Digest, func(a, b Digest) int {
+				return cmp.Or(strings.Compare(a.Algorithm, b.Algorithm), strings.Compare(a.Value, b.Value))
+			})
+		}
+	}
+	slices.SortFunc(s.Components, func(a, b Component) int {
+		return cmp.Or(
+			strings.Compare(a.Id, b.Id),
+			strings.Compare(a.Name, b.Name),
+			strings.Compare(a.Version, b.Version),
+			strings.Compare(a.Type, b.Type),
+			strings.Compare(a.Purl, b.Purl),
+		)
+	})
+
+	for i := range s.Dependencies {
+		slices.SortFunc(s.Dependencies[i].DependsOn, func(a, b Target) int {
+			return cmp.Or(strings.Compare(a.Child, b.Child), strings.Compare(a.Type, b.Type))
+		})
+	}
+	slices.SortFunc(s.Dependencies, func(a, b Dependency) int {
+		return strings.Compare(a.Ref, b.Ref)
+	})
+
+	digest, err := s.ContentDigest()
+	if err != nil {
+		return err
+	}
+	s.Digest = digest
+	return nil
+}
+
+// ContentDigest returns the SHA-256 of the JSON encoding of s without
+// its digest. It's only stable for canonicalized SBOMs, compare it
+// to Digest to verify a stored SBOM.
+func (s *CyclonedxSbom) ContentDigest() (string, error) {
+	content := *s
+	content.Digest = ""
+
+	// maps, i.e., the labels, are encoded with sorted keys
+	data, err := json.Marshal(&content)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	return DigestAlgorithm + hex.EncodeToString(sum[:]), nil
+}
+
+// FileName returns the content-addressed file name of s,
+// the hex encoded digest
+func (s *CyclonedxSbom) FileName() string {
+	return strings.TrimPrefix(s.Digest, DigestAlgorithm) + ".json"
+}
@@ -0,0 +1,62 @@
+package sbom
+
+import (
+	"encoding/json"
+	"regexp"
+	"slices"
+	"testing"
+)
+
+func TestCanonicalizeIgnoresOrder(t *testing.T) {
+	a, err := testSyft().TransformWithOptions(PackageOptions())
+	if err != nil {
+		t.Fatalf("transform failed %s", err)
+	}
+
+	shuffled := testSyft()
+	slices.Reverse(shuffled.Artifacts)
+	slices.Reverse(shuffled.ArtifactRelationships)
+	b, err := shuffled.TransformWithOptions(PackageOptions())
+	if err != nil {
+		t.Fatalf("transform failed %s", err)
+	}
+
+	for _, s := range []*CyclonedxSbom{a, b} {
+		if err := s.Canonicalize(); err != nil {
+			t.Fatalf("canonicalize failed %s", err)
+		}
+	}
+	if a.Digest == "" || a.Digest != b.Digest {
+		t.Fatalf("expected equal digests, got %q and %q", a.Digest, b.Digest)
+	}
+
+	x, _ := json.Marshal(a)
+	y, _ := json.Marshal(b)
+	if string(x) != string(y) {
+		t.Fatalf("expected equal bytes\n%s\n%s", x, y)
+	}
+}
+
+func TestContentDigest(t *testing.T) {
+	s, err := testSyft().Transform()
+	if err != nil {
+		t.Fatalf("transform failed %s", err)
+	}
+	if err := s.Canonicalize(); err != nil {
+		t.Fatalf("canonicalize failed %s", err)
+	}
+
+	// the stored digest isn't part of the content
+	if d, err := s.ContentDigest(); err != nil || d != s.Digest {
+		t.Fatalf("expected %s, got %s %v", s.Digest, d, err)
+	}
+
+	s.Components[0].Version = "changed"
+	if d, _ := s.ContentDigest(); d == s.Digest {
+		t.Fatalf("expected the digest to change with the content")
+	}
+
+	if !regexp.MustCompile(`^[0-9a-f]{64}\.json$`).MatchString(s.FileName()) {
+		t.Fatalf("unexpected file name %s", s.FileName())
+	}
+}
@@ -4,8 +4,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
+// a CyclonedxSbom as stored in the database
+type StoredSbom struct {
+	Id            bson.ObjectID `bson:"_id" json:"id"`
+	CyclonedxSbom `bson:",inline"`
+}
+
 func ReadCyclonedx(p string) (*CyclonedxSbom, error) {
 	var sbom CyclonedxSbom
 	file, err := os.Open(p)
@@ -0,0 +1,282 @@
+package sbom
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"sbom-processor/internal/purl"
+	"sbom-processor/internal/semver"
+)
+
+// defines how components of two SBOMs are matched
+type MatchBy string
+
+const (
+	// match by component name and type
+	MatchByName MatchBy = "name"
+	// match by purl without version and qualifiers. components
+	// without valid purl are matched by name and type.
+	MatchByPurl MatchBy = "purl"
+)
+
+type DiffOptions struct {
+	MatchBy MatchBy
+}
+
+type ComponentChange struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Purl string `json:"purl,omitempty"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// dependency edge between two components identified by their match key
+type EdgeChange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Type string `json:"type"`
+}
+
+type SbomDiff struct {
+	Old          Source            `json:"old"`
+	New          Source            `json:"new"`
+	MatchBy      MatchBy           `json:"matchBy"`
+	Added        []Component       `json:"added"`
+	Removed      []Component       `json:"removed"`
+	Upgraded     []ComponentChange `json:"upgraded"`
+	Downgraded   []ComponentChange `json:"downgraded"`
+	Changed      []ComponentChange `json:"changed"` // versions differ but aren't comparable
+	Unchanged    int               `json:"unchanged"`
+	AddedEdges   []EdgeChange      `json:"addedEdges"`
+	RemovedEdges []EdgeChange      `json:"removedEdges"`
+}
+
+// Diff compares the components and dependency edges of two scans.
+// Components with the same key are paired by their versions. Equal
+// versions are unchanged, remaining versions are paired in ascending
+// order and classified as up- or downgrade. Components without
+// counterpart are added or removed.
+func Diff(old, new *CyclonedxSbom, opts DiffOptions) *SbomDiff {
+	if opts.MatchBy == "" {
+		opts.MatchBy = MatchByName
+	}
+
+	d := SbomDiff{
+		Old:          old.Source,
+		New:          new.Source,
+		MatchBy:      opts.MatchBy,
+		Added:        []Component{},
+		Removed:      []Component{},
+		Upgraded:     []ComponentChange{},
+		Downgraded:   []ComponentChange{},
+		Changed:      []ComponentChange{},
+		AddedEdges:   []EdgeChange{},
+		RemovedEdges: []EdgeChange{},
+	}
+
+	oldByKey, oldKeys := groupByKey(old.Components, opts.MatchBy)
+	newByKey, newKeys := groupByKey(new.Components, opts.MatchBy)
+
+	for _, k := range oldKeys {
+		d.compare(oldByKey[k], newByKey[k])
+	}
+	for _, k := range newKeys {
+		if _, ok := oldByKey[k]; !ok {
+			d.Added = append(d.Added, newByKey[k]...)
+		}
+	}
+
+	oldEdges, oldEdgeSet := edges(old, opts.MatchBy)
+	newEdges, newEdgeSet := edges(new, opts.MatchBy)
+	for _, e := range oldEdges {
+		if !newEdgeSet[e] {
+			d.RemovedEdges = append(d.RemovedEdges, e)
+		}
+	}
+	for _, e := range newEdges {
+		if !oldEdgeSet[e] {
+			d.AddedEdges = append(d.AddedEdges, e)
+		}
+	}
+
+	return &d
+}
+
+func (d *SbomDiff) compare(old, new []Component) {
+	old = slices.Clone(old)
+	new = slices.Clone(new)
+
+	// drop all versions contained in both
+	for i := 0; i < len(old); {
+		j := slices.IndexFunc(new, func(c Component) bool { return c.Version == old[i].Version })
+		if j < 0 {
+			i++
+			continue
+		}
+		d.Unchanged++
+		old = slices.Delete(old, i, i+1)
+		new = slices.Delete(new, j, j+1)
+	}
+
+	if len(old) == 0 || len(new) == 0 {
+		d.Removed = append(d.Removed, old...)
+		d.Added = append(d.Added, new...)
+		return
+	}
+
+	// components with the same key share their type, os packages
+	// aren't versioned with semver
+	compare := semver.ForComponentType(new[0].Type)
+	sortByVersion(old, compare)
+	sortByVersion(new, compare)
+
+	for i := 0; i < min(len(old), len(new)); i++ {
+		change := ComponentChange{
+			Name: new[i].Name,
+			Type: new[i].Type,
+			Purl: new[i].Purl,
+			From: old[i].Version,
+			To:   new[i].Version,
+		}
+
+		cmp, err := compare(old[i].Version, new[i].Version)
+		switch {
+		case err != nil || cmp == 0:
+			d.Changed = append(d.Changed, change)
+		case cmp < 0:
+			d.Upgraded = append(d.Upgraded, change)
+		default:
+			d.Downgraded = append(d.Downgraded, change)
+		}
+	}
+
+	if len(old) > len(new) {
+		d.Removed = append(d.Removed, old[len(new):]...)
+	}
+	if len(new) > len(old) {
+		d.Added = append(d.Added, new[len(old):]...)
+	}
+}
+
+// sorts by version. versions that can't be parsed follow all valid
+// versions and are ordered lexicographically, so that the order stays
+// total.
+func sortByVersion(components []Component, compare semver.CompareFunc) {
+	slices.SortStableFunc(components, func(a, b Component) int {
+		_, errA := compare(a.Version, a.Version)
+		_, errB := compare(b.Version, b.Version)
+		switch {
+		case errA == nil && errB == nil:
+			cmp, _ := compare(a.Version, b.Version)
+			return cmp
+		case errA == nil:
+			return -1
+		case errB == nil:
+			return 1
+		default:
+			return strings.Compare(a.Version, b.Version)
+		}
+	})
+}
+
+// returns the key used to match c with components of another SBOM
+func matchKey(c *Component, matchBy MatchBy) string {
+	if matchBy == MatchByPurl && c.Purl != "" {
+		if p, err := purl.Parse(c.Purl); err == nil {
+			return p.Package()
+		}
+	}
+	return c.Type + "/" + c.Name
+}
+
+// groups components by key. keys are returned in order of appearance.
+func groupByKey(components []Component, matchBy MatchBy) (map[string][]Component, []string) {
+	byKey := make(map[string][]Component)
+	var keys []string
+	for i := range components {
+		k := matchKey(&components[i], matchBy)
+		if _, ok := byKey[k]; !ok {
+			keys = append(keys, k)
+		}
+		byKey[k] = append(byKey[k], components[i])
+	}
+	return byKey, keys
+}
+
+// component ids differ between scans, edges are therefore identified
+// by the match keys of their components. refs that aren't components,
+// e.g., files, are used as is.
+func edges(s *CyclonedxSbom, matchBy MatchBy) ([]EdgeChange, map[EdgeChange]bool) {
+	keys := make(map[string]string, len(s.Components))
+	for i := range s.Components {
+		keys[s.Components[i].Id] = matchKey(&s.Components[i], matchBy)
+	}
+
+	key := func(ref string) string {
+		if k, ok := keys[ref]; ok {
+			return k
+		}
+		return ref
+	}
+
+	var res []EdgeChange
+	seen := map[EdgeChange]bool{}
+	for _, d := range s.Dependencies {
+		for _, t := range d.DependsOn {
+			e := EdgeChange{From: key(d.Ref), To: key(t.Child), Type: t.Type}
+			if !seen[e] {
+				seen[e] = true
+				res = append(res, e)
+			}
+		}
+	}
+
+	return res, seen
+}
+
+// Summary returns a human readable summary of the diff
+func (d *SbomDiff) Summary() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "old: %s (%s)\n", d.Old.Name, d.Old.Version)
+	fmt.Fprintf(&b, "new: %s (%s)\n", d.New.Name, d.New.Version)
+	fmt.Fprintf(&b, "components: %d added, %d removed, %d upgraded, %d downgraded, %d changed, %d unchanged\n",
+		len(d.Added), len(d.Removed), len(d.Upgraded), len(d.Downgraded), len(d.Changed), d.Unchanged)
+	fmt.Fprintf(&b, "dependency edges: %d added, %d removed\n", len(d.AddedEdges), len(d.RemovedEdges))
+
+	section := func(title string, lines []string) {
+		if len(lines) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "\n%s:\n", title)
+		for _, l := range lines {
+			fmt.Fprintf(&b, "  %s\n", l)
+		}
+	}
+
+	components := func(prefix string, cs []Component) []string {
+		lines := make([]string, len(cs))
+		for i, c := range cs {
+			lines[i] = fmt.Sprintf("%s %s %s (%s)", prefix, c.Name, c.Version, c.Type)
+		}
+		return lines
+	}
+
+	changes := func(prefix string, cs []ComponentChange) []string {
+		lines := make([]string, len(cs))
+		for i, c := range cs {
+			lines[i] = fmt.Sprintf("%s %s %s -> %s (%s)", prefix, c.Name, c.From, c.To, c.Type)
+		}
+		return lines
+	}
+
+	section("added", components("+", d.Added))
+	section("removed", components("-", d.Removed))
+	section("upgraded", changes("^", d.Upgraded))
+	section("downgraded", changes("v", d.Downgraded))
+	section("changed", changes("~", d.Changed))
+
+	return b.String()
+}
@@ -0,0 +1,147 @@
+package sbom
+
+import (
+	"strings"
+	"testing"
+
+	"sbom-processor/internal/semver"
+)
+
+func TestDiff(t *testing.T) {
+	old := CyclonedxSbom{
+		Source: Source{Name: "nginx:1.24"},
+		Components: []Component{
+			{Id: "o1", Name: "openssl", Type: "deb", Version: "3.0.9"},
+			{Id: "o2", Name: "zlib", Type: "deb", Version: "1.2.13"},
+			{Id: "o3", Name: "curl", Type: "deb", Version: "8.0.0"},
+			{Id: "o4", Name: "guice", Type: "java-archive", Version: "5.0"},
+			{Id: "o5", Name: "bash", Type: "deb", Version: "5.2"},
+			{Id: "o6", Name: "tzdata", Type: "deb", Version: "stable"},
+		},
+		Dependencies: []Dependency{
+			{Ref: "o3", DependsOn: []Target{{Child: "o1", Type: "dependency-of"}, {Child: "o2", Type: "dependency-of"}}},
+		},
+	}
+
+	new := CyclonedxSbom{
+		Source: Source{Name: "nginx:1.25"},
+		Components: []Component{
+			{Id: "n1", Name: "openssl", Type: "deb", Version: "3.0.11"},
+			{Id: "n2", Name: "zlib", Type: "deb", Version: "1.2.13"},
+			{Id: "n4", Name: "guice", Type: "java-archive", Version: "4.0"},
+			{Id: "n5", Name: "bash", Type: "deb", Version: "5.2"},
+			{Id: "n6", Name: "bash", Type: "deb", Version: "5.3"},
+			{Id: "n7", Name: "tzdata", Type: "deb", Version: "testing"},
+		},
+		Dependencies: []Dependency{
+			{Ref: "n1", DependsOn: []Target{{Child: "n2", Type: "dependency-of"}}},
+		},
+	}
+
+	d := Diff(&old, &new, DiffOptions{})
+
+	if d.Unchanged != 2 {
+		t.Fatalf("two unchanged components expected, got %d", d.Unchanged)
+	}
+
+	if len(d.Upgraded) != 1 || d.Upgraded[0].Name != "openssl" || d.Upgraded[0].From != "3.0.9" || d.Upgraded[0].To != "3.0.11" {
+		t.Fatalf("unexpected upgrades %+v", d.Upgraded)
+	}
+
+	if len(d.Downgraded) != 1 || d.Downgraded[0].Name != "guice" {
+		t.Fatalf("unexpected downgrades %+v", d.Downgraded)
+	}
+
+	if len(d.Changed) != 1 || d.Changed[0].Name != "tzdata" {
+		t.Fatalf("unexpected changes %+v", d.Changed)
+	}
+
+	if len(d.Removed) != 1 || d.Removed[0].Name != "curl" {
+		t.Fatalf("unexpected removed components %+v", d.Removed)
+	}
+
+	if len(d.Added) != 1 || d.Added[0].Name != "bash" || d.Added[0].Version != "5.3" {
+		t.Fatalf("unexpected added components %+v", d.Added)
+	}
+
+	if len(d.RemovedEdges) != 2 || len(d.AddedEdges) != 1 {
+		t.Fatalf("unexpected edge changes %+v %+v", d.RemovedEdges, d.AddedEdges)
+	}
+
+	if d.AddedEdges[0] != (EdgeChange{From: "deb/openssl", To: "deb/zlib", Type: "dependency-of"}) {
+		t.Fatalf("unexpected added edge %+v", d.AddedEdges[0])
+	}
+
+	summary := d.Summary()
+	if !strings.Contains(summary, "1 added, 1 removed, 1 upgraded, 1 downgraded, 1 changed, 2 unchanged") ||
+		!strings.Contains(summary, "^ openssl 3.0.9 -> 3.0.11 (deb)") {
+		t.Fatalf("unexpected summary\n%s", summary)
+	}
+}
+
+func TestDiffMatchByPurl(t *testing.T) {
+	old := CyclonedxSbom{
+		Components: []Component{
+			{Id: "o1", Name: "guice", Type: "java-archive", Version: "4.0", Purl: "pkg:maven/com.google.inject/guice@4.0"},
+		},
+	}
+	new := CyclonedxSbom{
+		Components: []Component{
+			{Id: "n1", Name: "guice-renamed", Type: "java-archive", Version: "4.1", Purl: "pkg:maven/com.google.inject/guice@4.1"},
+		},
+	}
+
+	d := Diff(&old, &new, DiffOptions{MatchBy: MatchByName})
+	if len(d.Added) != 1 || len(d.Removed) != 1 {
+		t.Fatalf("components with different names must not match by name")
+	}
+
+	d = Diff(&old, &new, DiffOptions{MatchBy: MatchByPurl})
+	if len(d.Upgraded) != 1 || len(d.Added) != 0 || len(d.Removed) != 0 {
+		t.Fatalf("components with same purl must match, got %+v", d)
+	}
+}
+
+func TestDiffDebianVersions(t *testing.T) {
+	old := CyclonedxSbom{
+		Components: []Component{
+			{Id: "o1", Name: "libc6", Type: "deb", Version: "2.36-9+deb12u3"},
+			{Id: "o2", Name: "openssh-client", Type: "deb", Version: "1:9.2p1-2+deb12u3"},
+			{Id: "o3", Name: "musl", Type: "apk", Version: "1.2.4-r2"},
+		},
+	}
+	new := CyclonedxSbom{
+		Components: []Component{
+			{Id: "n1", Name: "libc6", Type: "deb", Version: "2.36-9+deb12u4"},
+			{Id: "n2", Name: "openssh-client", Type: "deb", Version: "1:9.2p1-2"},
+			{Id: "n3", Name: "musl", Type: "apk", Version: "1.2.4_git20230717-r4"},
+		},
+	}
+
+	d := Diff(&old, &new, DiffOptions{})
+
+	if len(d.Upgraded) != 2 || d.Upgraded[0].Name != "libc6" || d.Upgraded[1].Name != "musl" {
+		t.Fatalf("unexpected upgrades %+v", d.Upgraded)
+	}
+	if len(d.Downgraded) != 1 || d.Downgraded[0].Name != "openssh-client" {
+		t.Fatalf("unexpected downgrades %+v", d.Downgraded)
+	}
+	if len(d.Changed) != 0 {
+		t.Fatalf("unexpected changes %+v", d.Changed)
+	}
+}
+
+func TestSortByVersion(t *testing.T) {
+	components := []Component{
+		{Version: "unstable"}, {Version: "1:1.0-1"}, {Version: "2.0-1"}, {Version: "2.0-1~bpo1"}, {Version: "stable"},
+	}
+
+	sortByVersion(components, semver.ForComponentType("deb"))
+
+	expected := []string{"2.0-1~bpo1", "2.0-1", "1:1.0-1", "stable", "unstable"}
+	for i, c := range components {
+		if c.Version != expected[i] {
+			t.Fatalf("unexpected order at %d. Expected %s, got %s", i, expected[i], c.Version)
+		}
+	}
+}
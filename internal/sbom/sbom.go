@@ -8,9 +8,6 @@ import (
 	"net/url"
 	"sbom-processor/internal/semver"
 	"strings"
-
-	"go.mongodb.org/mongo-driver/v2/bson"
-	"go.mongodb.org/mongo-driver/v2/mongo"
 )
 
 type Target struct {
@@ -28,6 +25,8 @@ type CyclonedxSbom struct {
 	Dependencies []Dependency `json:"dependencies"`
 	Source       Source       `json:"source"`
 	Distro       Distro       `json:"distro"`
+	// SHA-256 of the canonical JSON encoding, set by Canonicalize
+	Digest string `json:"digest,omitempty" bson:"digest,omitempty"`
 }
 
 type DebVersionResponse struct {
@@ -42,31 +41,15 @@ type Version struct {
 const deb string = "deb"
 const debBasePath string = "https://snapshot.debian.org/mr/package/"
 
-func (c *Component) IsInCache(cache, blackList *mongo.Collection) bool {
-	// check if versions are in db before continue
-	err := blackList.FindOne(context.TODO(), bson.D{{Key: "id", Value: c.Id}}).Err()
-	if err == nil || err != mongo.ErrNoDocuments {
-		fmt.Printf("Versions for %+v in blacklist db\n", c)
-		return true
-	}
-
-	filter := bson.D{{Key: "component_id", Value: c.Id}}
-	err = cache.FindOne(context.TODO(), filter).Err()
-	if err == nil || err != mongo.ErrNoDocuments {
-		fmt.Printf("Versions for %+v already in db\n", c)
-		return true
-	}
-
-	return false
-}
-
-func (c *Component) GetVersions() (*semver.ComponentVersions, error) {
+// GetVersions queries the known versions of the component with client,
+// e.g., a client recording the requests in the metrics
+func (c *Component) GetVersions(ctx context.Context, client *http.Client) (*semver.ComponentVersions, error) {
 	var raw []string
 	var err error
 
 	switch c.Type {
 	case deb:
-		raw, err = getDebVersions(debBasePath, c.Name)
+		raw, err = getDebVersions(ctx, client, debBasePath, c.Name)
 	default:
 		raw = nil
 		err = fmt.Errorf("unkown component type")
@@ -91,7 +74,7 @@ func (c *Component) GetVersions() (*semver.ComponentVersions, error) {
 	return &compVers, nil
 }
 
-func getDebVersions(basePath string, n string) ([]string, error) {
+func getDebVersions(ctx context.Context, client *http.Client, basePath string, n string) ([]string, error) {
 
 	if n == "" {
 		return nil, fmt.Errorf("can't get version information for empty package name")
@@ -106,7 +89,12 @@ func getDebVersions(basePath string, n string) ([]string, error) {
 
 	url := basePath + encodedName
 
-	resp, err := http.Get(url)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -1,6 +1,7 @@
 package sbom
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -18,7 +19,7 @@ func TestGetDebVersions(t *testing.T) {
 
 	defer srv.Close()
 
-	v, err := getDebVersions(srv.URL, "rand")
+	v, err := getDebVersions(context.Background(), srv.Client(), srv.URL, "rand")
 	if err != nil {
 		t.Fatalf("no error expected for valid request, %s", err.Error())
 	}
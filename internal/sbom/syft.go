@@ -4,6 +4,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
+
+	"sbom-processor/internal/imageref"
+	"sbom-processor/internal/oci"
 )
 
 type SyftSbom struct {
@@ -17,7 +21,47 @@ type Source struct {
 	Id       string   `json:"id"`
 	Name     string   `json:"name"`
 	Version  string   `json:"version"` // in our current example a SHA256
+	Type     string   `json:"type,omitempty" bson:"type,omitempty"`
 	Metadata Metadata `json:"metadata"`
+	// parsed from the name, set by the transformation
+	Image *imageref.Reference `json:"image,omitempty" bson:"image,omitempty"`
+	// extracted from the labels, set by the transformation
+	Oci *oci.Labels `json:"oci,omitempty" bson:"oci,omitempty"`
+}
+
+// syft source type of container images
+const SourceTypeImage = "image"
+
+// ImageReference returns the stored image reference or parses it
+// from the name, nil for sources that aren't images. The digest is
+// taken from the version, i.e., the manifest digest, if the name
+// doesn't contain one.
+func (s *Source) ImageReference() *imageref.Reference {
+	if s.Image != nil {
+		return s.Image
+	}
+	// older SBOMs don't have a type
+	if s.Type != "" && s.Type != SourceTypeImage {
+		return nil
+	}
+
+	r, err := imageref.Parse(s.Name)
+	if err != nil {
+		return nil
+	}
+	if r.Digest == "" && strings.HasPrefix(s.Version, "sha256:") {
+		r.Digest = s.Version
+	}
+	return r
+}
+
+// OciLabels returns the stored well-known labels or extracts them
+// from the metadata, nil if the image has none
+func (s *Source) OciLabels() *oci.Labels {
+	if s.Oci != nil {
+		return s.Oci
+	}
+	return oci.Extract(s.Metadata.Labels)
 }
 
 type Metadata struct {
@@ -37,11 +81,77 @@ type ArtifactRelationship struct {
 }
 
 type Component struct {
-	Name     string `json:"name"`
-	Type     string `json:"type"`
-	Id       string `json:"id"`
-	Language string `json:"language"`
-	Version  string `json:"version"`
+	Name     string             `json:"name"`
+	Type     string             `json:"type"`
+	Id       string             `json:"id"`
+	Language string             `json:"language"`
+	Version  string             `json:"version"`
+	Purl     string             `json:"purl,omitempty" bson:"purl,omitempty"`
+	Metadata *ComponentMetadata `json:"metadata,omitempty" bson:"metadata,omitempty"`
+	Licenses []License          `json:"licenses,omitempty" bson:"licenses,omitempty"`
+}
+
+// License as declared by the package. Older syft versions store
+// licenses as plain strings, newer ones as objects with the raw
+// value and the SPDX expression if syft was able to derive one.
+type License struct {
+	Value          string `json:"value" bson:"value"`
+	SpdxExpression string `json:"spdxExpression,omitempty" bson:"spdxExpression,omitempty"`
+}
+
+func (l *License) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err == nil {
+		l.Value = value
+		return nil
+	}
+
+	// alias type to avoid calling UnmarshalJSON recursively
+	type license License
+	return json.Unmarshal(data, (*license)(l))
+}
+
+// syft stores type specific metadata for each artifact.
+// we only keep the parts we use, e.g., the digests syft
+// calculates for java archives.
+type ComponentMetadata struct {
+	Digest []Digest `json:"digest,omitempty" bson:"digest,omitempty"`
+}
+
+type Digest struct {
+	Algorithm string `json:"algorithm" bson:"algorithm"`
+	Value     string `json:"value" bson:"value"`
+}
+
+// returns the value of the first digest calculated with the given
+// algorithm or an empty string if there is none.
+func (c *Component) Digest(algorithm string) string {
+	if c.Metadata == nil {
+		return ""
+	}
+
+	for _, d := range c.Metadata.Digest {
+		if strings.EqualFold(d.Algorithm, algorithm) {
+			return d.Value
+		}
+	}
+
+	return ""
+}
+
+// returns the declared licenses, preferring the SPDX expression
+// derived by syft over the raw value.
+func (c *Component) DeclaredLicenses() []string {
+	var res []string
+	for _, l := range c.Licenses {
+		switch {
+		case l.SpdxExpression != "":
+			res = append(res, l.SpdxExpression)
+		case l.Value != "":
+			res = append(res, l.Value)
+		}
+	}
+	return res
 }
 
 func ReadSyft(p *string) (*SyftSbom, error) {
@@ -67,34 +177,8 @@ func ReadSyft(p *string) (*SyftSbom, error) {
 	return &sbom, nil
 }
 
+// Transform copies all artifact relationships verbatim, see
+// TransformWithOptions to drop or collapse relationship types
 func (s *SyftSbom) Transform() (*CyclonedxSbom, error) {
-
-	// transform syft to cyclonedx
-	parentChild := make(map[string][]Target, len(s.Artifacts))
-	for _, r := range s.ArtifactRelationships {
-		child := Target{
-			Child: r.Child,
-			Type:  r.Type,
-		}
-
-		// Append directly to the map
-		parentChild[r.Parent] = append(parentChild[r.Parent], child)
-	}
-
-	var dependencies []Dependency
-
-	for key, value := range parentChild {
-		dependencies = append(dependencies, Dependency{
-			Ref:       key,
-			DependsOn: value,
-		})
-	}
-
-	return &CyclonedxSbom{
-		Components:   s.Artifacts,
-		Dependencies: dependencies,
-		Source:       s.Source,
-		Distro:       s.Distro,
-	}, nil
-
+	return s.TransformWithOptions(TransformOptions{})
 }
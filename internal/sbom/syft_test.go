@@ -184,3 +184,60 @@ func TestTransformValidSbom(t *testing.T) {
 		}
 	}
 }
+
+func TestReadSyftDigest(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "syftTest.json")
+
+	f, err := os.Create(p)
+	if err != nil {
+		t.Fatalf("unable to create test file %s", err.Error())
+	}
+
+	defer f.Close()
+
+	f.WriteString("{\"artifacts\" : [{\"name\": \"guice\", \"id\": \"myId\", \"type\": \"java-archive\", \"metadata\": {\"virtualPath\": \"/app/guice.jar\", \"digest\": [{\"algorithm\": \"sha1\", \"value\": \"35379fb6526fd019f331542b4e9ae2e566c57933\"}]}}, {\"name\": \"deb\", \"id\": \"debId\", \"type\": \"deb\"}], \"artifactRelationships\": []}")
+	s, err := ReadSyft(&p)
+	if err != nil {
+		t.Fatalf("no error expected for valid Json")
+	}
+
+	if s.Artifacts[0].Digest("sha1") != "35379fb6526fd019f331542b4e9ae2e566c57933" {
+		t.Fatalf("unexpected sha1 digest %+v", s.Artifacts[0].Metadata)
+	}
+
+	if s.Artifacts[0].Digest("sha256") != "" {
+		t.Fatalf("no sha256 digest expected")
+	}
+
+	if s.Artifacts[1].Digest("sha1") != "" {
+		t.Fatalf("no digest expected for component without metadata")
+	}
+}
+
+func TestReadSyftLicenses(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "syftTest.json")
+
+	f, err := os.Create(p)
+	if err != nil {
+		t.Fatalf("unable to create test file %s", err.Error())
+	}
+
+	defer f.Close()
+
+	f.WriteString("{\"artifacts\" : [{\"name\": \"old\", \"id\": \"oldId\", \"licenses\": [\"MIT\"]}, {\"name\": \"new\", \"id\": \"newId\", \"licenses\": [{\"value\": \"GPL-2+\", \"spdxExpression\": \"\", \"type\": \"declared\"}, {\"value\": \"Apache 2\", \"spdxExpression\": \"Apache-2.0\", \"type\": \"declared\"}]}], \"artifactRelationships\": []}")
+	s, err := ReadSyft(&p)
+	if err != nil {
+		t.Fatalf("no error expected for valid Json %s", err)
+	}
+
+	if len(s.Artifacts[0].Licenses) != 1 || s.Artifacts[0].Licenses[0].Value != "MIT" {
+		t.Fatalf("string licenses must be parsed %+v", s.Artifacts[0].Licenses)
+	}
+
+	declared := s.Artifacts[1].DeclaredLicenses()
+	if len(declared) != 2 || declared[0] != "GPL-2+" || declared[1] != "Apache-2.0" {
+		t.Fatalf("unexpected declared licenses %v", declared)
+	}
+}
@@ -0,0 +1,242 @@
+package sbom
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// relationship types used by syft
+const (
+	// package to the files it owns
+	RelationshipContains = "contains"
+	// package to the files it was found through
+	RelationshipEvidentBy = "evident-by"
+	// dependency to its dependent
+	RelationshipDependencyOf = "dependency-of"
+)
+
+// type of the root component added for the image
+const ImageType = "image"
+
+// RelationshipMode defines how a relationship type is transformed
+type RelationshipMode string
+
+const (
+	// copy the relationships verbatim
+	Keep RelationshipMode = "keep"
+	// remove the relationships
+	Drop RelationshipMode = "drop"
+	// replace relationships through nodes that aren't artifacts,
+	// i.e., syft files, by contains relationships between the
+	// artifacts. relationships between artifacts are kept.
+	Collapse RelationshipMode = "collapse"
+)
+
+type TransformOptions struct {
+	// mode per relationship type, types without mode are kept
+	Relationships map[string]RelationshipMode
+	// fail if a relationship refers to an id that isn't a component
+	ValidateRefs bool
+	// add the image as root component that contains all
+	// components without incoming relationship
+	ImageRoot bool
+}
+
+// PackageOptions transform to a graph of packages only. File
+// relationships are collapsed and the image is added as root.
+func PackageOptions() TransformOptions {
+	return TransformOptions{
+		Relationships: map[string]RelationshipMode{
+			RelationshipContains:  Collapse,
+			RelationshipEvidentBy: Collapse,
+		},
+		ValidateRefs: true,
+		ImageRoot:    true,
+	}
+}
+
+func (o *TransformOptions) mode(relationshipType string) RelationshipMode {
+	if m, ok := o.Relationships[relationshipType]; ok {
+		return m
+	}
+	return Keep
+}
+
+// ParseRelationships parses modes given as type=mode pairs separated
+// by commas, e.g., contains=collapse,evident-by=drop
+func ParseRelationships(s string) (map[string]RelationshipMode, error) {
+	res := make(map[string]RelationshipMode)
+	if strings.TrimSpace(s) == "" {
+		return res, nil
+	}
+
+	for _, pair := range strings.Split(s, ",") {
+		t, m, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || t == "" {
+			return nil, fmt.Errorf("invalid relationship mode %q, expected type=mode", pair)
+		}
+		mode := RelationshipMode(m)
+		if mode != Keep && mode != Drop && mode != Collapse {
+			return nil, fmt.Errorf("unknown mode %q for %s, choose keep, drop, or collapse", m, t)
+		}
+		res[t] = mode
+	}
+	return res, nil
+}
+
+// TransformWithOptions transforms syft to cyclonedx and applies opts
+// to the artifact relationships
+func (s *SyftSbom) TransformWithOptions(opts TransformOptions) (*CyclonedxSbom, error) {
+	components := s.Artifacts
+	artifacts := make(map[string]bool, len(s.Artifacts)+1)
+	for _, a := range s.Artifacts {
+		artifacts[a.Id] = true
+	}
+
+	var relationships, collapsed []ArtifactRelationship
+	for _, r := range s.ArtifactRelationships {
+		switch opts.mode(r.Type) {
+		case Drop:
+			continue
+		case Collapse:
+			if !artifacts[r.Parent] || !artifacts[r.Child] {
+				collapsed = append(collapsed, r)
+				continue
+			}
+		}
+		relationships = append(relationships, r)
+	}
+	relationships = append(relationships, collapse(collapsed, artifacts)...)
+
+	if opts.ImageRoot {
+		if s.Source.Id == "" {
+			return nil, fmt.Errorf("image root requires the source id")
+		}
+		root := Component{
+			Name:    s.Source.Name,
+			Type:    ImageType,
+			Id:      s.Source.Id,
+			Version: s.Source.Version,
+		}
+		relationships = append(relationships, rootRelationships(root.Id, s.Artifacts, relationships)...)
+		components = append([]Component{root}, s.Artifacts...)
+		artifacts[root.Id] = true
+	}
+
+	if opts.ValidateRefs {
+		if err := validateRefs(relationships, artifacts); err != nil {
+			return nil, err
+		}
+	}
+
+	source := s.Source
+	source.Image = source.ImageReference()
+	source.Oci = source.OciLabels()
+
+	return &CyclonedxSbom{
+		Components:   components,
+		Dependencies: dependencies(relationships),
+		Source:       source,
+		Distro:       s.Distro,
+	}, nil
+}
+
+// collapse replaces the relationships through non-artifact nodes.
+// an artifact that contains a file through which another artifact
+// is evident, contains this artifact.
+func collapse(relationships []ArtifactRelationship, artifacts map[string]bool) []ArtifactRelationship {
+	containers := make(map[string][]string)
+	evidenced := make(map[string][]string)
+	var order []string
+
+	node := func(id string) {
+		if _, ok := containers[id]; !ok {
+			containers[id] = nil
+			order = append(order, id)
+		}
+	}
+
+	for _, r := range relationships {
+		switch {
+		case artifacts[r.Parent] && !artifacts[r.Child]:
+			node(r.Child)
+			if r.Type == RelationshipEvidentBy {
+				evidenced[r.Child] = append(evidenced[r.Child], r.Parent)
+			} else {
+				containers[r.Child] = append(containers[r.Child], r.Parent)
+			}
+		case !artifacts[r.Parent] && artifacts[r.Child]:
+			node(r.Parent)
+			evidenced[r.Parent] = append(evidenced[r.Parent], r.Child)
+		}
+	}
+
+	var res []ArtifactRelationship
+	for _, id := range order {
+		for _, c := range containers[id] {
+			for _, p := range evidenced[id] {
+				r := ArtifactRelationship{Parent: c, Child: p, Type: RelationshipContains}
+				if c != p && !slices.Contains(res, r) {
+					res = append(res, r)
+				}
+			}
+		}
+	}
+	return res
+}
+
+// rootRelationships connects the root with all artifacts that
+// aren't the target of a relationship. dependency-of points from the
+// dependency to the dependent, so its parent is the target.
+func rootRelationships(root string, artifacts []Component, relationships []ArtifactRelationship) []ArtifactRelationship {
+	targets := make(map[string]bool)
+	for _, r := range relationships {
+		if r.Type == RelationshipDependencyOf {
+			targets[r.Parent] = true
+		} else {
+			targets[r.Child] = true
+		}
+	}
+
+	var res []ArtifactRelationship
+	for _, a := range artifacts {
+		if !targets[a.Id] && a.Id != root {
+			res = append(res, ArtifactRelationship{Parent: root, Child: a.Id, Type: RelationshipContains})
+		}
+	}
+	return res
+}
+
+func validateRefs(relationships []ArtifactRelationship, artifacts map[string]bool) error {
+	var unresolved []string
+	for _, r := range relationships {
+		for _, id := range []string{r.Parent, r.Child} {
+			if !artifacts[id] && !slices.Contains(unresolved, id) {
+				unresolved = append(unresolved, id)
+			}
+		}
+	}
+
+	if len(unresolved) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d refs don't resolve to a component, e.g., %s", len(unresolved), unresolved[0])
+}
+
+// groups the relationships by their parent in order of appearance,
+// nil if there are none
+func dependencies(relationships []ArtifactRelationship) []Dependency {
+	var res []Dependency
+	index := make(map[string]int)
+	for _, r := range relationships {
+		i, ok := index[r.Parent]
+		if !ok {
+			i = len(res)
+			index[r.Parent] = i
+			res = append(res, Dependency{Ref: r.Parent})
+		}
+		res[i].DependsOn = append(res[i].DependsOn, Target{Child: r.Child, Type: r.Type})
+	}
+	return res
+}
@@ -0,0 +1,139 @@
+package sbom
+
+import (
+	"slices"
+	"testing"
+)
+
+// two debs, one owning the file through which the jar was found
+func testSyft() *SyftSbom {
+	return &SyftSbom{
+		Artifacts: []Component{
+			{Name: "openjdk", Id: "jdk", Type: "deb"},
+			{Name: "libc", Id: "libc", Type: "deb"},
+			{Name: "guava", Id: "guava", Type: "java-archive"},
+		},
+		ArtifactRelationships: []ArtifactRelationship{
+			{Parent: "jdk", Child: "file-1", Type: RelationshipContains},
+			{Parent: "guava", Child: "file-1", Type: RelationshipEvidentBy},
+			{Parent: "libc", Child: "jdk", Type: RelationshipDependencyOf},
+		},
+		Source: Source{Id: "sha256:image", Name: "app", Version: "sha256:image"},
+	}
+}
+
+func relationships(c *CyclonedxSbom) []ArtifactRelationship {
+	var res []ArtifactRelationship
+	for _, d := range c.Dependencies {
+		for _, t := range d.DependsOn {
+			res = append(res, ArtifactRelationship{Parent: d.Ref, Child: t.Child, Type: t.Type})
+		}
+	}
+	return res
+}
+
+func TestTransformKeepsPhantomRefs(t *testing.T) {
+	c, err := testSyft().Transform()
+	if err != nil {
+		t.Fatalf("transform failed %s", err)
+	}
+	if len(relationships(c)) != 3 {
+		t.Fatalf("expected all relationships, got %+v", relationships(c))
+	}
+
+	if _, err := testSyft().TransformWithOptions(TransformOptions{ValidateRefs: true}); err == nil {
+		t.Fatalf("expected the file refs to fail the validation")
+	}
+}
+
+func TestTransformDrop(t *testing.T) {
+	c, err := testSyft().TransformWithOptions(TransformOptions{
+		Relationships: map[string]RelationshipMode{RelationshipContains: Drop, RelationshipEvidentBy: Drop},
+		ValidateRefs:  true,
+	})
+	if err != nil {
+		t.Fatalf("transform failed %s", err)
+	}
+
+	expected := []ArtifactRelationship{{Parent: "libc", Child: "jdk", Type: RelationshipDependencyOf}}
+	if !slices.Equal(relationships(c), expected) {
+		t.Fatalf("expected %+v, got %+v", expected, relationships(c))
+	}
+}
+
+func TestTransformPackageOptions(t *testing.T) {
+	c, err := testSyft().TransformWithOptions(PackageOptions())
+	if err != nil {
+		t.Fatalf("transform failed %s", err)
+	}
+
+	if len(c.Components) != 4 || c.Components[0].Id != "sha256:image" || c.Components[0].Type != ImageType {
+		t.Fatalf("expected the image as first component, got %+v", c.Components)
+	}
+
+	got := relationships(c)
+	expected := []ArtifactRelationship{
+		{Parent: "libc", Child: "jdk", Type: RelationshipDependencyOf},
+		// collapsed through file-1
+		{Parent: "jdk", Child: "guava", Type: RelationshipContains},
+		// jdk is the only artifact that isn't a target, libc is the
+		// dependency of jdk and guava is contained in jdk
+		{Parent: "sha256:image", Child: "jdk", Type: RelationshipContains},
+	}
+	if !slices.Equal(got, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, got)
+	}
+}
+
+func TestTransformImageRootRequiresSource(t *testing.T) {
+	s := testSyft()
+	s.Source.Id = ""
+	if _, err := s.TransformWithOptions(TransformOptions{ImageRoot: true}); err == nil {
+		t.Fatalf("expected an error without source id")
+	}
+}
+
+func TestParseRelationships(t *testing.T) {
+	modes, err := ParseRelationships("contains=collapse, evident-by=drop")
+	if err != nil {
+		t.Fatalf("parse failed %s", err)
+	}
+	if modes[RelationshipContains] != Collapse || modes[RelationshipEvidentBy] != Drop || len(modes) != 2 {
+		t.Fatalf("unexpected modes %v", modes)
+	}
+
+	if modes, err := ParseRelationships(""); err != nil || len(modes) != 0 {
+		t.Fatalf("expected no modes, got %v %v", modes, err)
+	}
+
+	for _, invalid := range []string{"contains", "contains=merge", "=drop"} {
+		if _, err := ParseRelationships(invalid); err == nil {
+			t.Fatalf("expected an error for %q", invalid)
+		}
+	}
+}
+
+func TestTransformImageReference(t *testing.T) {
+	s := testSyft()
+	s.Source.Name = "localhost:5000/org/app:1.0"
+	c, err := s.Transform()
+	if err != nil {
+		t.Fatalf("transform failed %s", err)
+	}
+	r := c.Source.Image
+	if r == nil || r.Registry != "localhost:5000" || r.Repository != "org/app" || r.Tag != "1.0" || r.Digest != "sha256:image" {
+		t.Fatalf("unexpected image reference %+v", r)
+	}
+
+	if c.Source.Oci != nil {
+		t.Fatalf("expected no labels, got %+v", c.Source.Oci)
+	}
+
+	s.Source.Metadata.Labels = map[string]string{"org.label-schema.vendor": "Acme"}
+	s.Source.Type = "directory"
+	if c, _ := s.Transform(); c.Source.Image != nil {
+		t.Fatalf("expected no image reference for directories, got %+v", c.Source.Image)
+	} else if c.Source.Oci == nil || c.Source.Oci.Vendor != "Acme" {
+		t.Fatalf("expected the vendor label, got %+v", c.Source.Oci)
+	}
+}
@@ -0,0 +1,165 @@
+package segment
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// suffix of segments that are still written
+const PartialSuffix = ".partial"
+
+// RecordWriter encodes records to a single segment, e.g., a JSON
+// array or a parquet file. Close must complete the encoding.
+type RecordWriter[T any] interface {
+	Write(record T) error
+	Close() error
+}
+
+type Config[T any] struct {
+	Dir  string
+	Name string // file name without extension
+	Ext  string // file extension including the dot, e.g., .json
+	// a new segment is started once a segment exceeds MaxBytes.
+	// segments are named <Name>-<index><Ext>. 0 writes a single
+	// file named <Name><Ext>.
+	MaxBytes int64
+	// creates the record writer of a new segment
+	New func(w io.Writer) (RecordWriter[T], error)
+}
+
+// Writer writes records to rolling file segments. Segments are written
+// to a file with the .partial suffix which is renamed once the segment
+// is complete. Completed segments are always valid files.
+//
+// Close completes the last segment, also with MaxBytes 0, so a run
+// stopped by the first signal (see shutdown.Context) finishes the
+// items in flight and leaves no .partial file. Only a process that is
+// killed, e.g., by the second signal, leaves a .partial file, which
+// is incomplete and must not be read.
+type Writer[T any] struct {
+	cfg   Config[T]
+	index int
+	files []string
+
+	file    *os.File
+	buf     *bufio.Writer
+	counter *countingWriter
+	records RecordWriter[T]
+}
+
+func NewWriter[T any](cfg Config[T]) *Writer[T] {
+	return &Writer[T]{cfg: cfg}
+}
+
+func (w *Writer[T]) path() string {
+	if w.cfg.MaxBytes <= 0 {
+		return filepath.Join(w.cfg.Dir, w.cfg.Name+w.cfg.Ext)
+	}
+	return filepath.Join(w.cfg.Dir, fmt.Sprintf("%s-%05d%s", w.cfg.Name, w.index, w.cfg.Ext))
+}
+
+func (w *Writer[T]) open() error {
+	f, err := os.Create(w.path() + PartialSuffix)
+	if err != nil {
+		return err
+	}
+
+	// bytes are counted before buffering, record writers with their
+	// own buffer, e.g., parquet, exceed MaxBytes by their buffer size
+	buf := bufio.NewWriter(f)
+	counter := &countingWriter{w: buf}
+	records, err := w.cfg.New(counter)
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+
+	w.file = f
+	w.buf = buf
+	w.counter = counter
+	w.records = records
+	return nil
+}
+
+// Write appends record to the current segment and completes the
+// segment if it exceeds the configured size afterwards.
+func (w *Writer[T]) Write(record T) error {
+	if w.records == nil {
+		if err := w.open(); err != nil {
+			return err
+		}
+	}
+
+	if err := w.records.Write(record); err != nil {
+		return err
+	}
+
+	if w.cfg.MaxBytes > 0 && w.counter.n >= w.cfg.MaxBytes {
+		return w.complete()
+	}
+	return nil
+}
+
+// completes the current segment and renames it to its final name
+func (w *Writer[T]) complete() error {
+	if err := w.records.Close(); err != nil {
+		w.file.Close()
+		return err
+	}
+	if err := w.buf.Flush(); err != nil {
+		w.file.Close()
+		return err
+	}
+	if err := w.file.Sync(); err != nil {
+		w.file.Close()
+		return err
+	}
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+
+	final := w.path()
+	if err := os.Rename(w.file.Name(), final); err != nil {
+		return err
+	}
+
+	w.files = append(w.files, final)
+	w.records = nil
+	w.file = nil
+	w.index++
+	return nil
+}
+
+// Close completes the current segment. If no record was written,
+// an empty segment is created so that consumers find the output.
+func (w *Writer[T]) Close() error {
+	if w.records == nil && len(w.files) == 0 {
+		if err := w.open(); err != nil {
+			return err
+		}
+	}
+	if w.records == nil {
+		return nil
+	}
+	return w.complete()
+}
+
+// Files returns the completed segments
+func (w *Writer[T]) Files() []string {
+	return w.files
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
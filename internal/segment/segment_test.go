@@ -0,0 +1,172 @@
+package segment
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	sbomjson "sbom-processor/internal/json"
+)
+
+func jsonConfig(dir string, maxBytes int64) Config[string] {
+	return Config[string]{
+		Dir:      dir,
+		Name:     "names",
+		Ext:      ".json",
+		MaxBytes: maxBytes,
+		New: func(w io.Writer) (RecordWriter[string], error) {
+			return sbomjson.NewArrayWriter[string](w)
+		},
+	}
+}
+
+func readArray(t *testing.T, p string) []string {
+	data, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatalf("unable to read %s: %s", p, err)
+	}
+	var res []string
+	if err := json.Unmarshal(data, &res); err != nil {
+		t.Fatalf("invalid segment %s: %s", p, err)
+	}
+	return res
+}
+
+func TestSingleFile(t *testing.T) {
+	dir := t.TempDir()
+	w := NewWriter(jsonConfig(dir, 0))
+
+	for _, n := range []string{"a", "b", "c"} {
+		if err := w.Write(n); err != nil {
+			t.Fatalf("no error expected %s", err)
+		}
+	}
+
+	// the file isn't visible before it is complete
+	if _, err := os.Stat(filepath.Join(dir, "names.json")); err == nil {
+		t.Fatalf("incomplete segment must not have the final name")
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("no error expected %s", err)
+	}
+
+	if len(w.Files()) != 1 || len(readArray(t, filepath.Join(dir, "names.json"))) != 3 {
+		t.Fatalf("unexpected files %v", w.Files())
+	}
+}
+
+func TestRollingSegments(t *testing.T) {
+	dir := t.TempDir()
+	// every record exceeds the limit
+	w := NewWriter(jsonConfig(dir, 2))
+
+	for _, n := range []string{"a", "b", "c"} {
+		if err := w.Write(n); err != nil {
+			t.Fatalf("no error expected %s", err)
+		}
+	}
+
+	// all segments are completed, close must not create another one
+	if err := w.Close(); err != nil {
+		t.Fatalf("no error expected %s", err)
+	}
+
+	files := w.Files()
+	if len(files) != 3 || !strings.HasSuffix(files[2], "names-00002.json") {
+		t.Fatalf("unexpected segments %v", files)
+	}
+	if readArray(t, files[1])[0] != "b" {
+		t.Fatalf("unexpected content of %s", files[1])
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 3 {
+		t.Fatalf("no partial segment expected, got %v", entries)
+	}
+}
+
+func TestInterrupted(t *testing.T) {
+	dir := t.TempDir()
+	w := NewWriter(jsonConfig(dir, 20))
+
+	for _, n := range []string{"aaaaaaaaaaaaaaaaaaaa", "b"} {
+		w.Write(n)
+	}
+
+	// process stops without Close
+	entries, _ := os.ReadDir(dir)
+	names := make([]string, len(entries))
+	for i, e := range entries {
+		names[i] = e.Name()
+	}
+
+	if len(names) != 2 || names[0] != "names-00000.json" || names[1] != "names-00001.json"+PartialSuffix {
+		t.Fatalf("one complete and one partial segment expected, got %v", names)
+	}
+	readArray(t, filepath.Join(dir, names[0]))
+}
+
+func TestEmpty(t *testing.T) {
+	dir := t.TempDir()
+	w := NewWriter(jsonConfig(dir, 0))
+	if err := w.Close(); err != nil {
+		t.Fatalf("no error expected %s", err)
+	}
+	if len(readArray(t, filepath.Join(dir, "names.json"))) != 0 {
+		t.Fatalf("empty array expected")
+	}
+}
+
+// writes records until the run is stopped
+type stoppingWriter struct {
+	w io.Writer
+}
+
+func (s *stoppingWriter) Write(record string) error {
+	if record == "stop" {
+		return io.ErrClosedPipe
+	}
+	_, err := io.WriteString(s.w, record+"\n")
+	return err
+}
+
+func (s *stoppingWriter) Close() error {
+	return nil
+}
+
+func TestCloseAfterInterruption(t *testing.T) {
+	dir := t.TempDir()
+	w := NewWriter(Config[string]{
+		Dir:  dir,
+		Name: "names",
+		Ext:  ".txt",
+		New: func(w io.Writer) (RecordWriter[string], error) {
+			return &stoppingWriter{w: w}, nil
+		},
+	})
+
+	for _, n := range []string{"a", "b", "stop"} {
+		if err := w.Write(n); err != nil {
+			break
+		}
+	}
+
+	// the items in flight are written, the single segment is completed
+	if err := w.Close(); err != nil {
+		t.Fatalf("no error expected %s", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "names.txt"))
+	if err != nil || string(data) != "a\nb\n" {
+		t.Fatalf("unexpected content %q %v", data, err)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Fatalf("no partial segment expected, got %v", entries)
+	}
+}
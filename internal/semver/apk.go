@@ -0,0 +1,186 @@
+package semver
+
+import (
+	"fmt"
+	"strings"
+)
+
+// token types of apk versions in the order used by apk-tools,
+// for different types at the same position the lower type is
+// the greater version
+type apkTokenType int
+
+const (
+	apkDigit apkTokenType = iota
+	apkLetter
+	apkSuffix
+	apkSuffixNo
+	apkRevisionNo
+	apkEnd
+)
+
+type apkToken struct {
+	typ apkTokenType
+	// numbers, the letter, or the rank of the suffix
+	value int
+}
+
+// suffixes before the release have a negative rank
+var apkSuffixes = map[string]int{
+	"alpha": -4,
+	"beta":  -3,
+	"pre":   -2,
+	"rc":    -1,
+	"cvs":   1,
+	"svn":   2,
+	"git":   3,
+	"hg":    4,
+	"p":     5,
+}
+
+// number{.number}[letter]{_suffix[number]}[~hash][-rN]
+func parseApk(raw string) ([]apkToken, error) {
+	var tokens []apkToken
+	s := raw
+	invalid := func() ([]apkToken, error) {
+		return nil, fmt.Errorf("invalid apk version %q", raw)
+	}
+
+	digits := func() (string, bool) {
+		i := 0
+		for i < len(s) && isDigit(s[i]) {
+			i++
+		}
+		d := s[:i]
+		s = s[i:]
+		return d, i > 0
+	}
+
+	// numbers are bounded by the length check
+	number := func(d string) int {
+		n := 0
+		for _, c := range []byte(d) {
+			n = n*10 + int(c-'0')
+		}
+		return n
+	}
+
+	d, ok := digits()
+	if !ok || len(d) > 18 {
+		return invalid()
+	}
+	tokens = append(tokens, apkToken{apkDigit, number(d)})
+
+	for len(s) > 1 && s[0] == '.' && isDigit(s[1]) {
+		s = s[1:]
+		// leading zeros are compared as fraction, i.e., 1.01 < 1.1
+		zeros := 0
+		for zeros < len(s) && s[zeros] == '0' {
+			zeros++
+		}
+		if zeros > 0 {
+			tokens = append(tokens, apkToken{apkDigit, -zeros})
+			s = s[zeros:]
+		}
+		if d, ok := digits(); ok {
+			if len(d) > 18 {
+				return invalid()
+			}
+			tokens = append(tokens, apkToken{apkDigit, number(d)})
+		}
+	}
+
+	if len(s) > 0 && s[0] >= 'a' && s[0] <= 'z' {
+		tokens = append(tokens, apkToken{apkLetter, int(s[0])})
+		s = s[1:]
+	}
+
+	for len(s) > 0 && s[0] == '_' {
+		s = s[1:]
+		i := 0
+		for i < len(s) && s[i] >= 'a' && s[i] <= 'z' {
+			i++
+		}
+		rank, ok := apkSuffixes[s[:i]]
+		if !ok {
+			return invalid()
+		}
+		s = s[i:]
+		tokens = append(tokens, apkToken{apkSuffix, rank})
+		if d, ok := digits(); ok {
+			if len(d) > 18 {
+				return invalid()
+			}
+			tokens = append(tokens, apkToken{apkSuffixNo, number(d)})
+		}
+	}
+
+	// commit hashes don't take part in the comparison
+	if len(s) > 0 && s[0] == '~' {
+		i := 1
+		for i < len(s) && (isDigit(s[i]) || (s[i] >= 'a' && s[i] <= 'f')) {
+			i++
+		}
+		s = s[i:]
+	}
+
+	if rest, found := strings.CutPrefix(s, "-r"); found {
+		s = rest
+		d, ok := digits()
+		if !ok || len(d) > 18 {
+			return invalid()
+		}
+		tokens = append(tokens, apkToken{apkRevisionNo, number(d)})
+	}
+
+	if s != "" {
+		return invalid()
+	}
+	return tokens, nil
+}
+
+// CompareApk compares two alpine package versions with the algorithm
+// used by apk-tools, e.g., 1.0_rc1 < 1.0 < 1.0_p1 < 1.0-r1.
+// returns -1 if a < b, 0 if a == b, and 1 if a > b.
+func CompareApk(a, b string) (int, error) {
+	ta, err := parseApk(a)
+	if err != nil {
+		return 0, err
+	}
+	tb, err := parseApk(b)
+	if err != nil {
+		return 0, err
+	}
+
+	at := func(tokens []apkToken, i int) apkToken {
+		if i < len(tokens) {
+			return tokens[i]
+		}
+		return apkToken{typ: apkEnd}
+	}
+
+	for i := 0; i < max(len(ta), len(tb)); i++ {
+		x, y := at(ta, i), at(tb, i)
+		if x.typ == y.typ {
+			if x.value != y.value {
+				return sign(x.value - y.value), nil
+			}
+			continue
+		}
+
+		// the version that continues is greater unless it
+		// continues with a pre-release suffix
+		if x.typ == apkSuffix && x.value < 0 {
+			return -1, nil
+		}
+		if y.typ == apkSuffix && y.value < 0 {
+			return 1, nil
+		}
+		if x.typ > y.typ {
+			return -1, nil
+		}
+		return 1, nil
+	}
+
+	return 0, nil
+}
@@ -0,0 +1,52 @@
+package semver
+
+import "testing"
+
+func TestCompareApk(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"1.0", "1.0", 0},
+		{"1.0_rc1", "1.0", -1},
+		{"1.0_alpha", "1.0_beta", -1},
+		{"1.0_beta2", "1.0_pre1", -1},
+		{"1.0_pre1", "1.0_rc1", -1},
+		{"1.0_rc9", "1.0_rc10", -1},
+		{"1.0", "1.0_p1", -1},
+		{"1.0_p1", "1.0_p1-r1", -1},
+		{"1.0", "1.0-r1", -1},
+		{"1.0-r1", "1.0-r2", -1},
+		{"1.0-r9", "1.0-r10", -1},
+		{"1.0-r5", "1.0.1-r0", -1},
+		{"1.0", "1.0a", -1},
+		{"1.0a", "1.0b", -1},
+		{"1.0a", "1.0.1", -1},
+		{"1.01", "1.1", -1},
+		{"1.001", "1.01", -1},
+		{"1.9", "1.10", -1},
+		{"3.1.4-r5", "3.1.4_git20230101-r0", -1},
+		{"3.0.8-r0", "3.0.10-r0", -1},
+		{"2.36-r9", "2.36.1-r0", -1},
+		{"1.0_rc1-r3", "1.0-r0", -1},
+		{"1.2.3~abc123-r0", "1.2.3-r0", 0},
+	}
+
+	for _, test := range tests {
+		cmp, err := CompareApk(test.a, test.b)
+		if err != nil || cmp != test.expected {
+			t.Fatalf("unexpected comparison of %s and %s. Expected %d, got %d %v", test.a, test.b, test.expected, cmp, err)
+		}
+		if cmp, _ := CompareApk(test.b, test.a); cmp != -test.expected {
+			t.Fatalf("comparison of %s and %s isn't symmetric", test.b, test.a)
+		}
+	}
+}
+
+func TestCompareApkInvalid(t *testing.T) {
+	for _, invalid := range []string{"", "a1.0", "1.0_foo", "1.0-1", "1.0-r", "1.0 "} {
+		if _, err := CompareApk(invalid, "1.0"); err == nil {
+			t.Fatalf("expected an error for %q", invalid)
+		}
+	}
+}
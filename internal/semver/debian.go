@@ -0,0 +1,141 @@
+package semver
+
+import (
+	"strconv"
+	"strings"
+)
+
+type debianVersion struct {
+	epoch    int
+	upstream string
+	revision string
+}
+
+// [epoch:]upstream_version[-debian_revision]
+func parseDebian(raw string) debianVersion {
+	v := debianVersion{}
+
+	if e, rest, found := strings.Cut(raw, ":"); found {
+		epoch, err := strconv.Atoi(e)
+		if err == nil {
+			v.epoch = epoch
+			raw = rest
+		}
+	}
+
+	if i := strings.LastIndex(raw, "-"); i >= 0 {
+		v.upstream = raw[:i]
+		v.revision = raw[i+1:]
+	} else {
+		v.upstream = raw
+	}
+
+	return v
+}
+
+// CompareDebian compares two debian package versions with the
+// algorithm used by dpkg. returns -1 if a < b, 0 if a == b, and 1 if a > b.
+func CompareDebian(a, b string) int {
+	va := parseDebian(a)
+	vb := parseDebian(b)
+
+	if va.epoch != vb.epoch {
+		return sign(va.epoch - vb.epoch)
+	}
+
+	if cmp := verrevcmp(va.upstream, vb.upstream); cmp != 0 {
+		return sign(cmp)
+	}
+
+	return sign(verrevcmp(va.revision, vb.revision))
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isAlpha(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// letters sort before non letters and ~ sorts before everything,
+// even the end of the version
+func order(c byte) int {
+	switch {
+	case isDigit(c):
+		return 0
+	case isAlpha(c):
+		return int(c)
+	case c == '~':
+		return -1
+	default:
+		return int(c) + 256
+	}
+}
+
+func verrevcmp(a, b string) int {
+	i, j := 0, 0
+	at := func(s string, i int) byte {
+		if i < len(s) {
+			return s[i]
+		}
+		return 0
+	}
+
+	for i < len(a) || j < len(b) {
+		firstDiff := 0
+
+		for (i < len(a) && !isDigit(a[i])) || (j < len(b) && !isDigit(b[j])) {
+			ac, bc := 0, 0
+			if i < len(a) {
+				ac = order(a[i])
+			}
+			if j < len(b) {
+				bc = order(b[j])
+			}
+			if ac != bc {
+				return ac - bc
+			}
+			i++
+			j++
+		}
+
+		for at(a, i) == '0' {
+			i++
+		}
+		for at(b, j) == '0' {
+			j++
+		}
+
+		for isDigit(at(a, i)) && isDigit(at(b, j)) {
+			if firstDiff == 0 {
+				firstDiff = int(a[i]) - int(b[j])
+			}
+			i++
+			j++
+		}
+
+		if isDigit(at(a, i)) {
+			return 1
+		}
+		if isDigit(at(b, j)) {
+			return -1
+		}
+		if firstDiff != 0 {
+			return firstDiff
+		}
+	}
+
+	return 0
+}
+
+func sign(i int) int {
+	switch {
+	case i < 0:
+		return -1
+	case i > 0:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,32 @@
+package semver
+
+import "testing"
+
+func TestCompareDebian(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"1.0", "1.0", 0},
+		{"1.0-1", "1.0-2", -1},
+		{"1:1.0", "2.0", 1},
+		{"1.0~rc1", "1.0", -1},
+		{"1.0", "1.0+deb12u1", -1},
+		{"3.0.11-1~deb12u2", "3.0.11-1", -1},
+		{"3.0.9-1", "3.0.11-1", -1},
+		{"2.36-9+deb12u4", "2.36-9+deb12u10", -1},
+		{"1.2.3a", "1.2.3", 1},
+		{"1.001", "1.1", 0},
+		{"7.88.1-10+deb12u5", "7.74.0-1.3+deb11u11", 1},
+		{"0", "0.0.1", -1},
+	}
+
+	for _, test := range tests {
+		if cmp := CompareDebian(test.a, test.b); cmp != test.expected {
+			t.Fatalf("unexpected comparison of %s and %s. Expected %d, got %d", test.a, test.b, test.expected, cmp)
+		}
+		if cmp := CompareDebian(test.b, test.a); cmp != -test.expected {
+			t.Fatalf("comparison of %s and %s isn't symmetric", test.b, test.a)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package semver
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// CompareFunc compares two versions.
+// returns -1 if a < b, 0 if a == b, and 1 if a > b.
+type CompareFunc func(a, b string) (int, error)
+
+// ForComponentType returns the version order of the packages of the
+// component type. os packages have their own order, all other types
+// are compared with the relaxed semver comparison.
+func ForComponentType(componentType string) CompareFunc {
+	switch componentType {
+	case "deb":
+		return compareDebian
+	case "apk":
+		return CompareApk
+	default:
+		return Compare
+	}
+}
+
+// GetVersionDistanceFor calculates the distance of usedVersion to the
+// latest of versions with the version order of the component type.
+func GetVersionDistanceFor(componentType string, usedVersion string, versions []string) (*VersionDistance, error) {
+	switch componentType {
+	case "deb", "apk":
+		return getVersionDistance(usedVersion, versions, ForComponentType(componentType))
+	default:
+		return GetVersionDistance(usedVersion, versions)
+	}
+}
+
+// dpkg accepts almost anything, so the version must at least start
+// with a number after the optional epoch
+func compareDebian(a, b string) (int, error) {
+	for _, v := range []string{a, b} {
+		if u := parseDebian(v).upstream; u == "" || !isDigit(u[0]) {
+			return 0, fmt.Errorf("invalid debian version %q", v)
+		}
+	}
+	return CompareDebian(a, b), nil
+}
+
+// distance with an arbitrary version order. the major, minor, and
+// patch numbers are taken from the leading numbers of the versions,
+// e.g., 2.36 of 2.36-9+deb12u4 or 3.1.4 of 3.1.4_rc1-r0.
+func getVersionDistance(usedVersion string, versions []string, compare CompareFunc) (*VersionDistance, error) {
+	if _, err := compare(usedVersion, usedVersion); err != nil {
+		return nil, err
+	}
+
+	valid := []string{usedVersion}
+	for _, v := range versions {
+		if _, err := compare(v, v); err != nil {
+			continue
+		}
+		valid = append(valid, v)
+	}
+
+	// the versions are valid, so the comparison can't fail
+	order := func(a, b string) int {
+		res, _ := compare(a, b)
+		return res
+	}
+	slices.SortFunc(valid, order)
+	valid = slices.CompactFunc(valid, func(a, b string) bool {
+		return order(a, b) == 0
+	})
+
+	i := slices.IndexFunc(valid, func(v string) bool {
+		return order(v, usedVersion) == 0
+	})
+	used := leadingNumbers(usedVersion)
+	latest := leadingNumbers(valid[len(valid)-1])
+
+	return &VersionDistance{
+		MissedReleases: int64(len(valid) - 1 - i),
+		MissedMajor:    latest[0] - used[0],
+		MissedMinor:    latest[1] - used[1],
+		MissedPatch:    latest[2] - used[2],
+	}, nil
+}
+
+// major, minor, and patch number of a version without epoch
+func leadingNumbers(v string) [3]int64 {
+	if _, rest, found := strings.Cut(v, ":"); found {
+		v = rest
+	}
+
+	var res [3]int64
+	for i := range res {
+		end := 0
+		for end < len(v) && isDigit(v[end]) {
+			end++
+		}
+		if end == 0 {
+			break
+		}
+		res[i], _ = strconv.ParseInt(v[:end], 10, 64)
+		if end == len(v) || v[end] != '.' {
+			break
+		}
+		v = v[end+1:]
+	}
+	return res
+}
@@ -0,0 +1,239 @@
+package semver
+
+import (
+	"strings"
+)
+
+// item of a maven version as parsed by ComparableVersion, either an
+// integer, a qualifier, or a list of items
+type mavenItem interface {
+	isNull() bool
+	// compares the item to other, other is nil if it's missing
+	compare(other mavenItem) int
+}
+
+// integers are kept as strings without leading zeros, so that
+// arbitrary lengths can be compared
+type mavenInt string
+
+type mavenString string
+
+type mavenList []mavenItem
+
+// qualifiers in their order, all unknown qualifiers follow
+var mavenQualifiers = []string{"alpha", "beta", "milestone", "rc", "snapshot", "", "sp"}
+
+var mavenAliases = map[string]string{
+	"ga":      "",
+	"final":   "",
+	"release": "",
+	"cr":      "rc",
+}
+
+func newMavenInt(s string) mavenInt {
+	s = strings.TrimLeft(s, "0")
+	if s == "" {
+		s = "0"
+	}
+	return mavenInt(s)
+}
+
+func (i mavenInt) isNull() bool {
+	return i == "0"
+}
+
+func (i mavenInt) compare(other mavenItem) int {
+	switch o := other.(type) {
+	case nil:
+		if i.isNull() {
+			return 0
+		}
+		return 1
+	case mavenInt:
+		if len(i) != len(o) {
+			return sign(len(i) - len(o))
+		}
+		return strings.Compare(string(i), string(o))
+	default:
+		// integers are greater than qualifiers and lists
+		return 1
+	}
+}
+
+// single letters followed by a number are short forms, e.g., 1.0a1
+func newMavenString(s string, followedByDigit bool) mavenString {
+	if followedByDigit && len(s) == 1 {
+		switch s {
+		case "a":
+			s = "alpha"
+		case "b":
+			s = "beta"
+		case "m":
+			s = "milestone"
+		}
+	}
+	if alias, ok := mavenAliases[s]; ok {
+		s = alias
+	}
+	return mavenString(s)
+}
+
+// comparable form of a qualifier, the index of known qualifiers
+// and the qualifier prefixed by the number of known ones otherwise
+func (s mavenString) comparable() string {
+	for i, q := range mavenQualifiers {
+		if string(s) == q {
+			return string(rune('0' + i))
+		}
+	}
+	return string(rune('0'+len(mavenQualifiers))) + "-" + string(s)
+}
+
+func (s mavenString) isNull() bool {
+	return s == ""
+}
+
+func (s mavenString) compare(other mavenItem) int {
+	switch o := other.(type) {
+	case nil:
+		// compared to the release, i.e., the empty qualifier
+		return strings.Compare(s.comparable(), mavenString("").comparable())
+	case mavenString:
+		return strings.Compare(s.comparable(), o.comparable())
+	default:
+		return -1
+	}
+}
+
+func (l mavenList) isNull() bool {
+	return len(l) == 0
+}
+
+func (l mavenList) compare(other mavenItem) int {
+	switch o := other.(type) {
+	case nil:
+		if len(l) == 0 {
+			return 0
+		}
+		return l[0].compare(nil)
+	case mavenInt:
+		return -1
+	case mavenString:
+		return 1
+	case mavenList:
+		for i := 0; i < max(len(l), len(o)); i++ {
+			var left, right mavenItem
+			if i < len(l) {
+				left = l[i]
+			}
+			if i < len(o) {
+				right = o[i]
+			}
+
+			var res int
+			switch {
+			case left == nil && right == nil:
+				res = 0
+			case left == nil:
+				res = -right.compare(nil)
+			default:
+				res = left.compare(right)
+			}
+			if res != 0 {
+				return res
+			}
+		}
+		return 0
+	}
+	return 0
+}
+
+// removes trailing null items, stops at the last item that isn't
+// a list
+func (l mavenList) normalize() mavenList {
+	for i := len(l) - 1; i >= 0; i-- {
+		if l[i].isNull() {
+			l = append(l[:i], l[i+1:]...)
+		} else if _, ok := l[i].(mavenList); !ok {
+			break
+		}
+	}
+	return l
+}
+
+// parseMaven parses a version like maven's ComparableVersion. dots
+// separate items, dashes and transitions between digits and letters
+// start a new sub list.
+func parseMaven(raw string) mavenList {
+	v := strings.ToLower(raw)
+
+	// lists are built bottom up, each list is appended to its
+	// parent once it's complete
+	stack := []mavenList{{}}
+	push := func() {
+		stack = append(stack, mavenList{})
+	}
+	add := func(item mavenItem) {
+		stack[len(stack)-1] = append(stack[len(stack)-1], item)
+	}
+	item := func(digit bool, s string, followedByDigit bool) mavenItem {
+		if digit {
+			return newMavenInt(s)
+		}
+		return newMavenString(s, followedByDigit)
+	}
+
+	digit := false
+	start := 0
+	for i := 0; i < len(v); i++ {
+		c := v[i]
+		switch {
+		case c == '.':
+			if i == start {
+				add(mavenInt("0"))
+			} else {
+				add(item(digit, v[start:i], false))
+			}
+			start = i + 1
+		case c == '-':
+			if i == start {
+				add(mavenInt("0"))
+			} else {
+				add(item(digit, v[start:i], false))
+			}
+			start = i + 1
+			push()
+		case isDigit(c):
+			if !digit && i > start {
+				add(newMavenString(v[start:i], true))
+				start = i
+				push()
+			}
+			digit = true
+		default:
+			if digit && i > start {
+				add(newMavenInt(v[start:i]))
+				start = i
+				push()
+			}
+			digit = false
+		}
+	}
+	if len(v) > start {
+		add(item(digit, v[start:], false))
+	}
+
+	for len(stack) > 1 {
+		l := stack[len(stack)-1].normalize()
+		stack = stack[:len(stack)-1]
+		add(l)
+	}
+	return stack[0].normalize()
+}
+
+// CompareMaven compares two maven versions like maven's
+// ComparableVersion, e.g., 1.0-alpha1 < 1.0-rc1 < 1.0-SNAPSHOT < 1.0 = 1.0.0 < 1.0-sp1.
+// returns -1 if a < b, 0 if a == b, and 1 if a > b.
+func CompareMaven(a, b string) int {
+	return sign(parseMaven(a).compare(parseMaven(b)))
+}
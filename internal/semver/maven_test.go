@@ -0,0 +1,48 @@
+package semver
+
+import "testing"
+
+// ordered versions from the tests of maven's ComparableVersion
+func TestCompareMavenOrder(t *testing.T) {
+	versions := []string{
+		"1-alpha2snapshot", "1-alpha2", "1-alpha-123", "1-beta-2", "1-beta123", "1-m2", "1-m11", "1-rc", "1-cr2",
+		"1-rc123", "1-SNAPSHOT", "1", "1-sp", "1-sp2", "1-sp123", "1-abc", "1-def", "1-pom-1", "1-1-snapshot",
+		"1-1", "1-2", "1-123",
+	}
+	numbers := []string{
+		"2.0", "2-1", "2.0.a", "2.0.0.a", "2.0.2", "2.0.123", "2.1.0", "2.1-a", "2.1b", "2.1-c", "2.1-1", "2.1.0.1", "2.2",
+		"2.123", "11.a2", "11.a11", "11.b2", "11.b11", "11.m2", "11.m11", "11", "11.a", "11b", "11c", "11m",
+	}
+
+	for _, ordered := range [][]string{versions, numbers} {
+		for i := 1; i < len(ordered); i++ {
+			a, b := ordered[i-1], ordered[i]
+			if CompareMaven(a, b) != -1 || CompareMaven(b, a) != 1 {
+				t.Fatalf("expected %s < %s", a, b)
+			}
+		}
+	}
+}
+
+func TestCompareMavenEqual(t *testing.T) {
+	tests := [][2]string{
+		{"1", "1.0.0"},
+		{"1-0", "1"},
+		{"1-ga", "1"},
+		{"1-final", "1.0"},
+		{"1-release", "1"},
+		{"1a1", "1-alpha-1"},
+		{"1b2", "1-beta-2"},
+		{"1m3", "1-milestone-3"},
+		{"1X", "1x"},
+		{"1.0RC1", "1.0-cr-1"},
+		{"2.0.0.RELEASE", "2"},
+		{"1.0.0-0001", "1-1"},
+	}
+
+	for _, test := range tests {
+		if CompareMaven(test[0], test[1]) != 0 {
+			t.Fatalf("expected %s == %s", test[0], test[1])
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package semver
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// version pattern of PEP 440 including the permitted alternative
+// spellings, as used by pypa/packaging
+var pep440Pattern = regexp.MustCompile(`(?i)^\s*v?` +
+	`(?:(?P<epoch>[0-9]+)!)?` +
+	`(?P<release>[0-9]+(?:\.[0-9]+)*)` +
+	`(?:[-_.]?(?P<pre_l>alpha|a|beta|b|preview|pre|c|rc)[-_.]?(?P<pre_n>[0-9]+)?)?` +
+	`(?:-(?P<post_n1>[0-9]+)|[-_.]?(?P<post_l>post|rev|r)[-_.]?(?P<post_n2>[0-9]+)?)?` +
+	`(?:[-_.]?(?P<dev_l>dev)[-_.]?(?P<dev_n>[0-9]+)?)?` +
+	`(?:\+(?P<local>[a-z0-9]+(?:[-_.][a-z0-9]+)*))?\s*$`)
+
+// missing parts sort before or after all present ones
+const (
+	pep440Before = math.MinInt
+	pep440After  = math.MaxInt
+)
+
+type pep440Version struct {
+	epoch   int
+	release []int
+	// phase of the pre-release, 0 for a, 1 for b, and 2 for rc
+	prePhase  int
+	preNumber int
+	post      int
+	dev       int
+	// nil if there is no local version
+	local []string
+}
+
+func parsePep440(raw string) (*pep440Version, error) {
+	m := pep440Pattern.FindStringSubmatch(raw)
+	if m == nil {
+		return nil, fmt.Errorf("invalid PEP 440 version %q", raw)
+	}
+	group := func(name string) string {
+		return m[pep440Pattern.SubexpIndex(name)]
+	}
+	var err error
+	number := func(s string) int {
+		if s == "" {
+			return 0
+		}
+		n, e := strconv.Atoi(s)
+		if e != nil {
+			err = e
+		}
+		return n
+	}
+
+	v := pep440Version{
+		epoch:     number(group("epoch")),
+		prePhase:  pep440After,
+		preNumber: pep440After,
+		post:      pep440Before,
+		dev:       pep440After,
+	}
+	for _, r := range strings.Split(group("release"), ".") {
+		v.release = append(v.release, number(r))
+	}
+	// trailing zeros don't change the version, 1.0 == 1
+	for len(v.release) > 1 && v.release[len(v.release)-1] == 0 {
+		v.release = v.release[:len(v.release)-1]
+	}
+
+	switch strings.ToLower(group("pre_l")) {
+	case "":
+	case "a", "alpha":
+		v.prePhase, v.preNumber = 0, number(group("pre_n"))
+	case "b", "beta":
+		v.prePhase, v.preNumber = 1, number(group("pre_n"))
+	default:
+		v.prePhase, v.preNumber = 2, number(group("pre_n"))
+	}
+
+	switch {
+	case group("post_n1") != "":
+		v.post = number(group("post_n1"))
+	case group("post_l") != "":
+		v.post = number(group("post_n2"))
+	}
+
+	if group("dev_l") != "" {
+		v.dev = number(group("dev_n"))
+		// a dev release of the release itself sorts before its
+		// pre-releases, 1.0.dev1 < 1.0a1
+		if v.prePhase == pep440After && v.post == pep440Before {
+			v.prePhase, v.preNumber = pep440Before, pep440Before
+		}
+	}
+
+	if local := group("local"); local != "" {
+		v.local = strings.FieldsFunc(strings.ToLower(local), func(r rune) bool {
+			return r == '-' || r == '_' || r == '.'
+		})
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid PEP 440 version %q: %w", raw, err)
+	}
+	return &v, nil
+}
+
+// numeric local segments are greater than alphanumeric ones,
+// which are compared lexicographically
+func compareLocalSegment(a, b string) int {
+	na, errA := strconv.Atoi(a)
+	nb, errB := strconv.Atoi(b)
+	switch {
+	case errA == nil && errB == nil:
+		return cmp.Compare(na, nb)
+	case errA == nil:
+		return 1
+	case errB == nil:
+		return -1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+// ComparePep440 compares two python package versions as defined by
+// PEP 440, e.g., 1.0.dev1 < 1.0a1 < 1.0rc1 < 1.0 < 1.0+local < 1.0.post1.
+// returns -1 if a < b, 0 if a == b, and 1 if a > b.
+func ComparePep440(a, b string) (int, error) {
+	va, err := parsePep440(a)
+	if err != nil {
+		return 0, err
+	}
+	vb, err := parsePep440(b)
+	if err != nil {
+		return 0, err
+	}
+
+	res := cmp.Or(
+		cmp.Compare(va.epoch, vb.epoch),
+		slices.Compare(va.release, vb.release),
+		cmp.Compare(va.prePhase, vb.prePhase),
+		cmp.Compare(va.preNumber, vb.preNumber),
+		cmp.Compare(va.post, vb.post),
+		cmp.Compare(va.dev, vb.dev),
+	)
+	if res != 0 {
+		return res, nil
+	}
+
+	// versions without local version sort first
+	switch {
+	case va.local == nil && vb.local == nil:
+		return 0, nil
+	case va.local == nil:
+		return -1, nil
+	case vb.local == nil:
+		return 1, nil
+	}
+	return sign(slices.CompareFunc(va.local, vb.local, compareLocalSegment)), nil
+}
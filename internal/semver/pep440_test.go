@@ -0,0 +1,56 @@
+package semver
+
+import "testing"
+
+func TestComparePep440Order(t *testing.T) {
+	ordered := []string{
+		"1.0.dev456", "1.0a1", "1.0a2.dev456", "1.0a12.dev456", "1.0a12", "1.0b1.dev456", "1.0b2",
+		"1.0b2.post345.dev456", "1.0b2.post345", "1.0rc1.dev456", "1.0rc1", "1.0", "1.0+abc.5", "1.0+abc.7",
+		"1.0+5", "1.0.post456.dev34", "1.0.post456", "1.0.15", "1.1.dev1", "2.0", "1!0.1",
+	}
+
+	for i := 1; i < len(ordered); i++ {
+		a, b := ordered[i-1], ordered[i]
+		res, err := ComparePep440(a, b)
+		if err != nil || res != -1 {
+			t.Fatalf("expected %s < %s, got %d %v", a, b, res, err)
+		}
+		if res, _ := ComparePep440(b, a); res != 1 {
+			t.Fatalf("expected %s > %s", b, a)
+		}
+	}
+}
+
+func TestComparePep440Equal(t *testing.T) {
+	tests := [][2]string{
+		{"1.0", "1.0.0"},
+		{"1", "1.0"},
+		{"v1.0", "1.0"},
+		{"0!1.0", "1.0"},
+		{"1.0-1", "1.0.post1"},
+		{"1.0-r1", "1.0.post1"},
+		{"1.0alpha1", "1.0a1"},
+		{"1.0-beta.2", "1.0b2"},
+		{"1.0c1", "1.0rc1"},
+		{"1.0pre1", "1.0rc1"},
+		{"1.0RC1", "1.0rc1"},
+		{"1.0a", "1.0a0"},
+		{"1.0.dev", "1.0.dev0"},
+		{"1.0+Ubuntu-1", "1.0+ubuntu.1"},
+	}
+
+	for _, test := range tests {
+		res, err := ComparePep440(test[0], test[1])
+		if err != nil || res != 0 {
+			t.Fatalf("expected %s == %s, got %d %v", test[0], test[1], res, err)
+		}
+	}
+}
+
+func TestComparePep440Invalid(t *testing.T) {
+	for _, invalid := range []string{"", "a1.0", "1.0-foo", "1.0+", "1..0"} {
+		if _, err := ComparePep440(invalid, "1.0"); err == nil {
+			t.Fatalf("expected an error for %q", invalid)
+		}
+	}
+}
@@ -10,20 +10,20 @@ import (
 )
 
 type VersionDistance struct {
-	MissedReleases int64
-	MissedMajor    int64
-	MissedMinor    int64
-	MissedPatch    int64
+	MissedReleases int64 `bson:"missed_releases" json:"missed_releases"`
+	MissedMajor    int64 `bson:"missed_major" json:"missed_major"`
+	MissedMinor    int64 `bson:"missed_minor" json:"missed_minor"`
+	MissedPatch    int64 `bson:"missed_patch" json:"missed_patch"`
 }
 
 type ComponentVersions struct {
-	ComponentId string             `bson:"component_id"`
-	Versions    []ComponentVersion `bson:"versions"`
+	ComponentId string             `bson:"component_id" json:"component_id"`
+	Versions    []ComponentVersion `bson:"versions" json:"versions"`
 }
 
 type ComponentVersion struct {
-	Version     string `bson:"version"`
-	ReleaseDate string `bson:"release_date"`
+	Version     string `bson:"version" json:"version"`
+	ReleaseDate string `bson:"release_date" json:"release_date"`
 	// Vulnerabilitites []string `bson:"vulnerabilitites,omitempty"` // this is a list of vulnerability IDs
 }
 
@@ -59,6 +59,22 @@ func newRelaxedSemver(raw string) (*version.Version, error) {
 	return v, nil
 }
 
+// Compare compares two versions with the relaxed semver parsing.
+// returns -1 if a < b, 0 if a == b, and 1 if a > b.
+func Compare(a, b string) (int, error) {
+	va, err := newRelaxedSemver(a)
+	if err != nil {
+		return 0, err
+	}
+
+	vb, err := newRelaxedSemver(b)
+	if err != nil {
+		return 0, err
+	}
+
+	return va.Compare(vb), nil
+}
+
 func GetVersionDistance(usedVersion string, versions []string) (*VersionDistance, error) {
 
 	usedSemver, err := newRelaxedSemver(usedVersion)
@@ -104,3 +104,31 @@ func TestVersionParsing(t *testing.T) {
 
 	}
 }
+
+func TestVersionDistanceFor(t *testing.T) {
+	tests := []struct {
+		componentType string
+		used          string
+		versions      []string
+		expected      VersionDistance
+	}{
+		{"deb", "1:2.3-4", []string{"2.3-4", "1:2.3-4", "1:2.3-5", "1:2.4-1", "1:3.0-1"}, VersionDistance{3, 1, -3, 0}},
+		{"deb", "2.36-9+deb12u4", []string{"2.36-9+deb12u10", "2.36-9+deb12u4", "2.36-9+deb12u9"}, VersionDistance{2, 0, 0, 0}},
+		{"apk", "3.1.4_rc1-r0", []string{"3.1.4-r0", "3.1.4-r1", "3.1.3-r0", "invalid"}, VersionDistance{2, 0, 0, 0}},
+		{"npm", "1.0.0", []string{"1.0.0", "1.1.0"}, VersionDistance{1, 0, 1, 0}},
+	}
+
+	for _, test := range tests {
+		d, err := GetVersionDistanceFor(test.componentType, test.used, test.versions)
+		if err != nil {
+			t.Fatalf("no error expected for %s %s, got %v", test.componentType, test.used, err)
+		}
+		if *d != test.expected {
+			t.Fatalf("unexpected distance of %s %s. Expected %+v, got %+v", test.componentType, test.used, test.expected, *d)
+		}
+	}
+
+	if _, err := GetVersionDistanceFor("deb", "invalid", []string{"1.0-1"}); err == nil {
+		t.Fatalf("expected an error for an invalid debian version")
+	}
+}
@@ -0,0 +1,71 @@
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// ExitCode is the exit status of runs stopped by SIGINT or SIGTERM,
+// 128 + SIGINT as reported by shells
+const ExitCode = 130
+
+// ErrInterrupted is the cause of contexts canceled by a signal
+var ErrInterrupted = errors.New("interrupted by signal")
+
+// Context returns a context that is canceled on the first SIGINT or
+// SIGTERM. Commands stop their intake once it's canceled and finish
+// the items in flight, a second signal terminates the process
+// immediately.
+//
+// done releases the signal handler and exits with ExitCode if the
+// run was interrupted. Defer it first, so it runs after all other
+// deferred calls, e.g., the database disconnect.
+func Context() (ctx context.Context, done func()) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case sig := <-signals:
+			slog.Default().Warn("Stopping after the items in flight, repeat to exit immediately", "signal", sig.String())
+			// restore the default handling for the second signal
+			signal.Stop(signals)
+			cancel(ErrInterrupted)
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(signals)
+		interrupted := Interrupted(ctx)
+		cancel(nil)
+		if interrupted {
+			os.Exit(ExitCode)
+		}
+	}
+}
+
+// Interrupted reports whether ctx was canceled by a signal
+func Interrupted(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), ErrInterrupted)
+}
+
+// Seq stops the iteration of seq once ctx is canceled. It's used to
+// stop the intake of a beehive.Dispatcher, the elements already
+// handed to the workers are still processed and collected.
+func Seq[T any](ctx context.Context, seq iter.Seq[T]) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for e := range seq {
+			if ctx.Err() != nil || !yield(e) {
+				return
+			}
+		}
+	}
+}
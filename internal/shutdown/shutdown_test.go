@@ -0,0 +1,49 @@
+package shutdown
+
+import (
+	"context"
+	"slices"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestSeq(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var got []int
+	for i := range Seq(ctx, slices.Values([]int{1, 2, 3, 4})) {
+		got = append(got, i)
+		if i == 2 {
+			cancel()
+		}
+	}
+
+	if !slices.Equal(got, []int{1, 2}) {
+		t.Fatalf("expected the iteration to stop after the cancel, got %v", got)
+	}
+}
+
+func TestInterrupted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if Interrupted(ctx) {
+		t.Fatalf("plain cancel reported as interrupt")
+	}
+
+	ctx, _ = Context()
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatalf("unable to send signal %s", err)
+	}
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("context not canceled by the signal")
+	}
+
+	if !Interrupted(ctx) {
+		t.Fatalf("expected interrupt, got %v", context.Cause(ctx))
+	}
+}
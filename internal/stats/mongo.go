@@ -0,0 +1,28 @@
+package stats
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Store replaces the statistics of the SBOMs in coll, so that
+// repeated runs keep a single document per SBOM
+func Store(ctx context.Context, coll *mongo.Collection, s []*SbomStats) error {
+	if len(s) == 0 {
+		return nil
+	}
+
+	models := make([]mongo.WriteModel, len(s))
+	for i, doc := range s {
+		models[i] = mongo.NewReplaceOneModel().
+			SetFilter(bson.D{{Key: "sbom_id", Value: doc.SbomId}}).
+			SetReplacement(doc).
+			SetUpsert(true)
+	}
+
+	_, err := coll.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	return err
+}
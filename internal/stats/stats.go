@@ -0,0 +1,201 @@
+package stats
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"sbom-processor/internal/graph"
+	"sbom-processor/internal/purl"
+	"sbom-processor/internal/sbom"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Count of a key, e.g., the number of components of a type
+type Count struct {
+	Key   string `bson:"key" json:"key"`
+	Count int    `bson:"count" json:"count"`
+}
+
+// SbomStats are the component statistics of a single SBOM
+type SbomStats struct {
+	SbomId     bson.ObjectID `bson:"sbom_id" json:"sbom_id"`
+	Source     sbom.Source   `bson:"source" json:"source"`
+	Distro     sbom.Distro   `bson:"distro" json:"distro"`
+	ComputedAt time.Time     `bson:"computed_at" json:"computed_at"`
+	Components int           `bson:"components" json:"components"`
+	// counts are sorted, most frequent first
+	ByType        []Count `bson:"by_type" json:"by_type"`
+	ByLanguage    []Count `bson:"by_language" json:"by_language"`
+	Relationships []Count `bson:"relationships" json:"relationships"`
+	// number of components per depth, the index is the depth
+	Depths   []int `bson:"depths" json:"depths"`
+	MaxDepth int   `bson:"max_depth" json:"max_depth"`
+	// components that aren't reachable from the root
+	Unreachable int `bson:"unreachable" json:"unreachable"`
+	// distinct purl types, components without purl have none
+	Ecosystems []string `bson:"ecosystems" json:"ecosystems"`
+}
+
+// Corpus are the statistics over all SBOMs
+type Corpus struct {
+	Sboms         int     `json:"sboms"`
+	Components    int     `json:"components"`
+	ByType        []Count `json:"by_type"`
+	ByLanguage    []Count `json:"by_language"`
+	Relationships []Count `json:"relationships"`
+	// number of components per depth over all SBOMs
+	Depths []int `json:"depths"`
+	// number of SBOMs per max depth
+	MaxDepths   []int `json:"max_depths"`
+	Unreachable int   `json:"unreachable"`
+	// number of SBOMs containing an ecosystem
+	Ecosystems []Count `json:"ecosystems"`
+	// number of SBOMs per distro id and version
+	Distros []Count `json:"distros"`
+}
+
+// Compute the statistics of s
+func Compute(s *sbom.StoredSbom) *SbomStats {
+	res := &SbomStats{
+		SbomId:     s.Id,
+		Source:     s.Source,
+		Distro:     s.Distro,
+		ComputedAt: time.Now().UTC(),
+		Components: len(s.Components),
+		Depths:     []int{},
+		Ecosystems: []string{},
+	}
+
+	types := make(map[string]int)
+	languages := make(map[string]int)
+	for _, c := range s.Components {
+		types[c.Type]++
+		if c.Language != "" {
+			languages[c.Language]++
+		}
+		if p, err := purl.Parse(c.Purl); err == nil && !slices.Contains(res.Ecosystems, p.Type) {
+			res.Ecosystems = append(res.Ecosystems, p.Type)
+		}
+	}
+	slices.Sort(res.Ecosystems)
+	res.ByType = counts(types)
+	res.ByLanguage = counts(languages)
+
+	relationships := make(map[string]int)
+	for _, d := range s.Dependencies {
+		for _, t := range d.DependsOn {
+			relationships[t.Type]++
+		}
+	}
+	res.Relationships = counts(relationships)
+
+	depths := graph.New(&s.CyclonedxSbom).Depths()
+	for _, c := range s.Components {
+		d, ok := depths[c.Id]
+		if !ok {
+			res.Unreachable++
+			continue
+		}
+		res.Depths = increment(res.Depths, d, 1)
+		res.MaxDepth = max(res.MaxDepth, d)
+	}
+
+	return res
+}
+
+// Aggregator sums up the statistics of single SBOMs, it isn't safe
+// for concurrent use
+type Aggregator struct {
+	corpus        Corpus
+	types         map[string]int
+	languages     map[string]int
+	relationships map[string]int
+	ecosystems    map[string]int
+	distros       map[string]int
+}
+
+func NewAggregator() *Aggregator {
+	return &Aggregator{
+		corpus:        Corpus{Depths: []int{}, MaxDepths: []int{}},
+		types:         make(map[string]int),
+		languages:     make(map[string]int),
+		relationships: make(map[string]int),
+		ecosystems:    make(map[string]int),
+		distros:       make(map[string]int),
+	}
+}
+
+func (a *Aggregator) Add(s *SbomStats) {
+	a.corpus.Sboms++
+	a.corpus.Components += s.Components
+	a.corpus.Unreachable += s.Unreachable
+
+	add := func(m map[string]int, counts []Count) {
+		for _, c := range counts {
+			m[c.Key] += c.Count
+		}
+	}
+	add(a.types, s.ByType)
+	add(a.languages, s.ByLanguage)
+	add(a.relationships, s.Relationships)
+
+	for _, e := range s.Ecosystems {
+		a.ecosystems[e]++
+	}
+	a.distros[distro(s.Distro)]++
+
+	for d, n := range s.Depths {
+		a.corpus.Depths = increment(a.corpus.Depths, d, n)
+	}
+	// SBOMs without reachable components don't have a depth
+	if len(s.Depths) > 0 {
+		a.corpus.MaxDepths = increment(a.corpus.MaxDepths, s.MaxDepth, 1)
+	}
+}
+
+// Corpus returns the statistics of all SBOMs added so far
+func (a *Aggregator) Corpus() *Corpus {
+	res := a.corpus
+	res.Depths = slices.Clone(a.corpus.Depths)
+	res.MaxDepths = slices.Clone(a.corpus.MaxDepths)
+	res.ByType = counts(a.types)
+	res.ByLanguage = counts(a.languages)
+	res.Relationships = counts(a.relationships)
+	res.Ecosystems = counts(a.ecosystems)
+	res.Distros = counts(a.distros)
+	return &res
+}
+
+// the distro as id and version, unknown if syft didn't detect it
+func distro(d sbom.Distro) string {
+	if d.Id == "" {
+		return "unknown"
+	}
+	if d.Version == "" {
+		return d.Id
+	}
+	return d.Id + " " + d.Version
+}
+
+// adds n at index i, growing s if needed
+func increment(s []int, i int, n int) []int {
+	if i >= len(s) {
+		s = append(s, make([]int, i-len(s)+1)...)
+	}
+	s[i] += n
+	return s
+}
+
+// counts sorted by count, most frequent first, and key
+func counts(m map[string]int) []Count {
+	res := make([]Count, 0, len(m))
+	for k, v := range m {
+		res = append(res, Count{Key: k, Count: v})
+	}
+	slices.SortFunc(res, func(a, b Count) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Key, b.Key))
+	})
+	return res
+}
@@ -0,0 +1,79 @@
+package stats
+
+import (
+	"slices"
+	"testing"
+
+	"sbom-processor/internal/sbom"
+)
+
+// image -> openssl -> libc, guava isn't reachable
+func testSbom() *sbom.StoredSbom {
+	return &sbom.StoredSbom{CyclonedxSbom: sbom.CyclonedxSbom{
+		Components: []sbom.Component{
+			{Id: "sha256:image", Name: "app", Type: sbom.ImageType},
+			{Id: "openssl", Name: "openssl", Type: "deb", Purl: "pkg:deb/debian/openssl@3.0.11"},
+			{Id: "libc", Name: "libc6", Type: "deb", Purl: "pkg:deb/debian/libc6@2.36"},
+			{Id: "guava", Name: "guava", Type: "java-archive", Language: "java", Purl: "pkg:maven/com.google.guava/guava@32.0.0"},
+		},
+		Dependencies: []sbom.Dependency{
+			{Ref: "sha256:image", DependsOn: []sbom.Target{{Child: "openssl", Type: sbom.RelationshipContains}}},
+			{Ref: "libc", DependsOn: []sbom.Target{{Child: "openssl", Type: sbom.RelationshipDependencyOf}}},
+			{Ref: "guava", DependsOn: []sbom.Target{{Child: "guava", Type: sbom.RelationshipDependencyOf}}},
+		},
+		Source: sbom.Source{Id: "sha256:image", Name: "app"},
+		Distro: sbom.Distro{Id: "debian", Version: "12"},
+	}}
+}
+
+func TestCompute(t *testing.T) {
+	s := Compute(testSbom())
+
+	if s.Components != 4 {
+		t.Fatalf("expected 4 components, got %d", s.Components)
+	}
+	expected := []Count{{"deb", 2}, {"image", 1}, {"java-archive", 1}}
+	if !slices.Equal(s.ByType, expected) {
+		t.Fatalf("expected %v, got %v", expected, s.ByType)
+	}
+	if !slices.Equal(s.ByLanguage, []Count{{"java", 1}}) {
+		t.Fatalf("unexpected languages %v", s.ByLanguage)
+	}
+	if !slices.Equal(s.Relationships, []Count{{"dependency-of", 2}, {"contains", 1}}) {
+		t.Fatalf("unexpected relationships %v", s.Relationships)
+	}
+	if !slices.Equal(s.Depths, []int{1, 1, 1}) || s.MaxDepth != 2 || s.Unreachable != 1 {
+		t.Fatalf("unexpected depths %v, max %d, unreachable %d", s.Depths, s.MaxDepth, s.Unreachable)
+	}
+	if !slices.Equal(s.Ecosystems, []string{"deb", "maven"}) {
+		t.Fatalf("unexpected ecosystems %v", s.Ecosystems)
+	}
+}
+
+func TestAggregator(t *testing.T) {
+	a := NewAggregator()
+	a.Add(Compute(testSbom()))
+
+	other := testSbom()
+	other.Components = other.Components[:2]
+	other.Dependencies = other.Dependencies[:1]
+	other.Distro = sbom.Distro{}
+	a.Add(Compute(other))
+
+	c := a.Corpus()
+	if c.Sboms != 2 || c.Components != 6 || c.Unreachable != 1 {
+		t.Fatalf("unexpected totals %+v", c)
+	}
+	if !slices.Equal(c.ByType, []Count{{"deb", 3}, {"image", 2}, {"java-archive", 1}}) {
+		t.Fatalf("unexpected types %v", c.ByType)
+	}
+	if !slices.Equal(c.Depths, []int{2, 2, 1}) || !slices.Equal(c.MaxDepths, []int{0, 1, 1}) {
+		t.Fatalf("unexpected depths %v, max depths %v", c.Depths, c.MaxDepths)
+	}
+	if !slices.Equal(c.Ecosystems, []Count{{"deb", 2}, {"maven", 1}}) {
+		t.Fatalf("unexpected ecosystems %v", c.Ecosystems)
+	}
+	if !slices.Equal(c.Distros, []Count{{"debian 12", 1}, {"unknown", 1}}) {
+		t.Fatalf("unexpected distros %v", c.Distros)
+	}
+}
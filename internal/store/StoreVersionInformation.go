@@ -2,122 +2,279 @@ package store
 
 import (
 	"context"
-	"fmt"
-	"log"
+	"log/slog"
+	"net/http"
 	"runtime"
+	"sync/atomic"
 
+	"sbom-processor/internal/catalogue"
 	"sbom-processor/internal/db"
+	"sbom-processor/internal/metrics"
 	"sbom-processor/internal/sbom"
 	"sbom-processor/internal/semver"
 
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
 	"golang.org/x/sync/semaphore"
 )
 
-func StoreVersionInformation(client *mongo.Client) {
+type Config struct {
+	Db                   string
+	Collection           string   // collection containing the SBOMs
+	ComponentsCollection string   // component catalogue of normalized SBOMs, empty if all SBOMs are embedded
+	VersionsCollection   string   // collection to store the versions in
+	BlacklistCollection  string   // collection to store failed lookups in
+	ComponentTypes       []string // only components of these types are processed
+	BatchSize            int      // max number of documents per insert and cache check
+	// counts the processed SBOMs, created if nil
+	Progress *metrics.Progress
+}
+
+type Totals struct {
+	Sboms      int64 `json:"sboms"`
+	Components int64 `json:"components"`
+	Cached     int64 `json:"cached"` // versions or blacklist entry already in db
+	Stored     int64 `json:"stored"`
+	Failed     int64 `json:"failed"` // version lookup failed, added to blacklist
+}
+
+type counters struct {
+	sboms      atomic.Int64
+	components atomic.Int64
+	cached     atomic.Int64
+	stored     atomic.Int64
+	failed     atomic.Int64
+}
+
+func (c *counters) totals() Totals {
+	return Totals{
+		Sboms:      c.sboms.Load(),
+		Components: c.components.Load(),
+		Cached:     c.cached.Load(),
+		Stored:     c.stored.Load(),
+		Failed:     c.failed.Load(),
+	}
+}
+
+type harvester struct {
+	versions  *mongo.Collection
+	blacklist *mongo.Collection
+	types     map[string]bool
+	batchSize int
+	counters  counters
+	progress  *metrics.Progress
+	// records the version lookups in the metrics
+	client *http.Client
+	logger *slog.Logger
+	// lookups and inserts of the SBOMs in flight, not canceled
+	ctx context.Context
+}
+
+// StoreVersionInformation queries the versions of all components of the
+// configured types and stores them in the versions collection. Components
+// whose versions or blacklist entry are already stored are skipped.
+// Canceling ctx stops reading SBOMs, the SBOMs in flight are finished.
+func StoreVersionInformation(ctx context.Context, client *mongo.Client, cfg Config) (*Totals, error) {
+
+	database := client.Database(cfg.Db)
+	sboms := &catalogue.Store{
+		Sboms:      database.Collection(cfg.Collection),
+		Components: catalogue.Collection(database, cfg.ComponentsCollection),
+	}
+	if err := sboms.Verify(ctx); err != nil {
+		return nil, err
+	}
 
-	sboms := client.Database("sbom_metadata").Collection("sboms")
-	versions := client.Database("sbom_metadata").Collection("versions")
-	blackList := client.Database("sbom_metadata").Collection("blacklist")
+	h := harvester{
+		versions:  database.Collection(cfg.VersionsCollection),
+		blacklist: database.Collection(cfg.BlacklistCollection),
+		types:     make(map[string]bool, len(cfg.ComponentTypes)),
+		batchSize: max(cfg.BatchSize, 1),
+		progress:  cfg.Progress,
+		client:    metrics.Client("debian_snapshot"),
+		logger:    slog.Default(),
+		ctx:       context.WithoutCancel(ctx),
+	}
+	if h.progress == nil {
+		h.progress = metrics.NewProgress("versions")
+	}
+	for _, t := range cfg.ComponentTypes {
+		h.types[t] = true
+	}
 
-	err := db.CreateIdx(versions, "component_id")
+	err := db.Verify(ctx, h.versions, db.Spec("versions"))
 	if err != nil {
-		fmt.Printf("Index creation failed with %s\n", err)
+		h.logger.Warn("Index verification failed", "err", err)
 	}
 
-	err = db.CreateIdx(blackList, "id")
+	err = db.Verify(ctx, h.blacklist, db.Spec("blacklist"))
 	if err != nil {
-		fmt.Printf("Index creation failed with %s\n", err)
+		h.logger.Warn("Index verification failed", "err", err)
 	}
 
 	// ASYNC ITERATION OF SBOMs AND STORE VERSIONS IN DB
 	var (
 		maxWorkers = runtime.GOMAXPROCS(0) // 0 = default = maxNumProc
 		sem        = semaphore.NewWeighted(int64(maxWorkers))
-		ctx        = context.TODO()
 	)
 
-	fmt.Printf("Starting up to %d workers\n", maxWorkers)
+	h.logger.Info("Starting workers", "max workers", maxWorkers, "component types", cfg.ComponentTypes)
+
+	filter := bson.D{{Key: "components.type", Value: bson.D{{Key: "$in", Value: cfg.ComponentTypes}}}}
 
-	cursor, err := sboms.Find(ctx, bson.D{})
+	// normalized SBOMs can only be filtered after the rehydration,
+	// they are all counted for the ETA
+	countFilter := filter
+	if sboms.Components != nil {
+		countFilter = bson.D{{Key: "$or", Value: bson.A{filter, bson.D{{Key: "refs", Value: bson.D{{Key: "$exists", Value: true}}}}}}}
+	}
+	total, err := sboms.Sboms.CountDocuments(ctx, countFilter)
 	if err != nil {
-		panic(err)
+		h.logger.Warn("Counting SBOMs failed, no ETA available", "err", err)
 	}
-	defer cursor.Close(ctx)
+	h.progress.SetTotal(total)
+	h.progress.Start(h.logger, metrics.LogInterval)
+	defer h.progress.Stop(h.logger)
 
-	for cursor.Next(ctx) {
-		fmt.Print("Cursor next call\n")
-		var sbom sbom.CyclonedxSbom
-		if err = cursor.Decode(&sbom); err != nil {
-			continue
-		}
-		fmt.Printf("Retrieved %s\n", sbom.Source.Name)
+	cursor, err := sboms.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$project", Value: bson.D{{Key: "components", Value: 1}, {Key: "source", Value: 1}}}},
+	}, options.Aggregate().SetBatchSize(1000))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(h.ctx)
 
+	for s := range db.MongodbIterator[sbom.CyclonedxSbom](ctx, cursor) {
 		// When maxWorkers goroutines are in flight, Acquire blocks until one of the
 		// workers finishes.
 		if err := sem.Acquire(ctx, 1); err != nil {
-			log.Printf("Failed to acquire semaphore: %v", err)
+			// only fails if ctx is canceled
 			break
 		}
 
-		go StoreVersions(sbom, sem, versions, blackList)
+		go func() {
+			defer sem.Release(1)
+			h.storeVersions(s)
 
+			h.counters.sboms.Add(1)
+			h.progress.Processed(1)
+		}()
 	}
 
 	// Acquire all of the tokens to wait for any remaining workers to finish.
-	if err := sem.Acquire(ctx, int64(maxWorkers)); err != nil {
-		log.Printf("Failed to acquire semaphore: %v", err)
+	if err := sem.Acquire(h.ctx, int64(maxWorkers)); err != nil {
+		h.logger.Error("Failed to acquire semaphore", "err", err)
 	}
 
+	totals := h.counters.totals()
+	return &totals, cursor.Err()
 }
 
-func StoreVersions(s sbom.CyclonedxSbom, sem *semaphore.Weighted, versionsColl, blackListColl *mongo.Collection) {
-	defer sem.Release(1)
-	fmt.Printf("Store versions for %s\n", s.Source.Name)
+func (h *harvester) storeVersions(s sbom.CyclonedxSbom) {
+	h.logger.Debug("Store versions", "source", s.Source.Name)
 
-	var (
-		cacheCounter = 0
-		errCounter   = 0
-	)
-
-	versions := make([]*semver.ComponentVersions, 202)
-
-	// GET ALL VERSIONS FOR EACH COMPONENT AND INSERT TO DB
+	// dedupe components, the same package can occur multiple times
+	seen := make(map[string]bool)
+	var components []sbom.Component
 	for _, c := range s.Components {
-
-		if len(versions) > 200 {
-			_, err := versionsColl.InsertMany(context.TODO(), versions)
-			if err != nil {
-				fmt.Printf("db store failed with %s\n", err)
-			}
-			versions = make([]*semver.ComponentVersions, 202)
+		if !h.types[c.Type] || seen[c.Id] {
+			continue
 		}
+		seen[c.Id] = true
+		components = append(components, c)
+	}
+	h.counters.components.Add(int64(len(components)))
 
-		// check if versions are in db before continue
-		if c.IsInCache(versionsColl, blackListColl) {
-			fmt.Printf("Versions for %+v in cache or blacklist db\n", c)
-			cacheCounter += 1
+	versions := make([]*semver.ComponentVersions, 0, h.batchSize)
+	blacklist := make([]bson.M, 0, h.batchSize)
+
+	for start := 0; start < len(components); start += h.batchSize {
+		batch := components[start:min(start+h.batchSize, len(components))]
+
+		cached, err := h.inCache(batch)
+		if err != nil {
+			h.logger.Error("Cache lookup failed", "source", s.Source.Name, "err", err)
 			continue
 		}
 
-		ver, err := c.GetVersions()
-		if err != nil {
-			errCounter += 1
-			fmt.Printf("query for %+v failed with %s\n", c, err)
-			_, err = blackListColl.InsertOne(context.TODO(), bson.M{"id": c.Id})
+		// GET ALL VERSIONS FOR EACH COMPONENT AND INSERT TO DB
+		for _, c := range batch {
+			if cached[c.Id] {
+				h.counters.cached.Add(1)
+				continue
+			}
+
+			ver, err := c.GetVersions(h.ctx, h.client)
 			if err != nil {
-				fmt.Printf("db store failed with %s\n", err)
+				h.logger.Debug("Version query failed", "component", c.Name, "err", err)
+				h.counters.failed.Add(1)
+				blacklist = append(blacklist, bson.M{"component_id": c.Id})
+				if len(blacklist) >= h.batchSize {
+					h.flushBlacklist(blacklist)
+					blacklist = blacklist[:0]
+				}
+				continue
+			}
+
+			versions = append(versions, ver)
+			if len(versions) >= h.batchSize {
+				h.flushVersions(versions)
+				versions = versions[:0]
 			}
-			continue
 		}
+	}
 
-		versions = append(versions, ver)
+	h.flushVersions(versions)
+	h.flushBlacklist(blacklist)
 
+	h.logger.Debug("Finished SBOM processing", "source", s.Source.Name)
+}
+
+// returns the ids of all components with versions or blacklist entry in the db
+func (h *harvester) inCache(components []sbom.Component) (map[string]bool, error) {
+	ids := make([]string, len(components))
+	for i, c := range components {
+		ids[i] = c.Id
 	}
 
-	fmt.Printf("%d of %d querries found in cash\n", cacheCounter, len(s.Components))
-	fmt.Printf("%d of %d querries failed \n", errCounter, len(s.Components))
+	cached := make(map[string]bool, len(ids))
 
-	fmt.Printf("Finished SBOM processing %s\n", s.Source.Name)
+	for _, q := range []struct {
+		coll *mongo.Collection
+		key  string
+	}{
+		{h.versions, "component_id"},
+		{h.blacklist, "component_id"},
+	} {
+		filter := bson.D{{Key: q.key, Value: bson.D{{Key: "$in", Value: ids}}}}
+		res := q.coll.Distinct(h.ctx, q.key, filter)
+		var found []string
+		if err := res.Decode(&found); err != nil {
+			return nil, err
+		}
+		for _, id := range found {
+			cached[id] = true
+		}
+	}
+
+	return cached, nil
+}
+
+// workers storing SBOMs with the same components can miss the cache
+// concurrently, the unique component_id index keeps the first insert
+func (h *harvester) flushVersions(versions []*semver.ComponentVersions) {
+	stored, err := db.InsertNew(h.ctx, h.versions, versions)
+	if err != nil {
+		h.logger.Error("Versions insert failed", "err", err)
+		return
+	}
+	h.counters.stored.Add(int64(stored))
+}
+
+func (h *harvester) flushBlacklist(blacklist []bson.M) {
+	if _, err := db.InsertNew(h.ctx, h.blacklist, blacklist); err != nil {
+		h.logger.Error("Blacklist insert failed", "err", err)
+	}
 }
@@ -0,0 +1,62 @@
+package table
+
+import (
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Flatten returns the fields of d with nested documents flattened
+// to dotted keys, e.g., {_id: {name: a}} becomes {_id.name: a}.
+// arrays are kept as values.
+func Flatten(d bson.D) bson.D {
+	var res bson.D
+	for _, e := range d {
+		if nested, ok := e.Value.(bson.D); ok {
+			for _, n := range Flatten(nested) {
+				res = append(res, bson.E{Key: e.Key + "." + n.Key, Value: n.Value})
+			}
+			continue
+		}
+		res = append(res, e)
+	}
+	return res
+}
+
+// InferSchema derives the schema from the flattened fields of a
+// document. Values that aren't numbers or booleans are strings.
+func InferSchema(d bson.D) Schema {
+	flat := Flatten(d)
+	schema := make(Schema, len(flat))
+	for i, e := range flat {
+		schema[i] = Column{Name: e.Key, Type: inferType(e.Value)}
+	}
+	return schema
+}
+
+func inferType(v any) ColumnType {
+	switch v.(type) {
+	case int, int32, int64:
+		return Int
+	case float32, float64:
+		return Float
+	case bool:
+		return Bool
+	default:
+		return String
+	}
+}
+
+// Row returns the values of the schema columns in the flattened d.
+// missing fields are nil.
+func Row(schema Schema, d bson.D) []any {
+	flat := Flatten(d)
+	values := make(map[string]any, len(flat))
+	for _, e := range flat {
+		values[e.Key] = e.Value
+	}
+
+	row := make([]any, len(schema))
+	for i, c := range schema {
+		row[i] = values[c.Name]
+	}
+	return row
+}
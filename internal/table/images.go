@@ -0,0 +1,63 @@
+package table
+
+import (
+	"sbom-processor/internal/sbom"
+)
+
+// ImageComponentSchema is the flattened image × component table,
+// one row per component of every image
+var ImageComponentSchema = Schema{
+	{Name: "sbom_id", Type: String},
+	{Name: "image", Type: String},
+	{Name: "source_digest", Type: String},
+	{Name: "image_id", Type: String},
+	{Name: "distro_id", Type: String},
+	{Name: "distro_version", Type: String},
+	{Name: "component_id", Type: String},
+	{Name: "name", Type: String},
+	{Name: "version", Type: String},
+	{Name: "type", Type: String},
+	{Name: "language", Type: String},
+	{Name: "purl", Type: String},
+	{Name: "vendor", Type: String},
+	{Name: "source_repository", Type: String},
+	{Name: "revision", Type: String},
+}
+
+// ImageComponentRows returns the rows of s in the ImageComponentSchema
+func ImageComponentRows(s *sbom.StoredSbom) [][]any {
+	// nil values are written as null
+	var vendor, repository, revision any
+	if l := s.Source.OciLabels(); l != nil {
+		vendor, repository, revision = nullable(l.Vendor), nullable(l.Source), nullable(l.Revision)
+	}
+
+	rows := make([][]any, len(s.Components))
+	for i, c := range s.Components {
+		rows[i] = []any{
+			s.Id.Hex(),
+			s.Source.Name,
+			s.Source.Version,
+			s.Source.Metadata.ImageId,
+			s.Distro.Id,
+			s.Distro.Version,
+			c.Id,
+			c.Name,
+			c.Version,
+			c.Type,
+			c.Language,
+			c.Purl,
+			vendor,
+			repository,
+			revision,
+		}
+	}
+	return rows
+}
+
+func nullable(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,337 @@
+package table
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/parquet-go/parquet-go"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type Format string
+
+const (
+	CSV     Format = "csv"
+	NDJSON  Format = "ndjson"
+	Parquet Format = "parquet"
+)
+
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case CSV, NDJSON, Parquet:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unknown format %s, choose csv, ndjson, or parquet", s)
+	}
+}
+
+// file extension of the format
+func (f Format) Ext() string {
+	return "." + string(f)
+}
+
+type ColumnType string
+
+const (
+	String ColumnType = "string"
+	Int    ColumnType = "int"
+	Float  ColumnType = "float"
+	Bool   ColumnType = "bool"
+)
+
+type Column struct {
+	Name string     `json:"name"`
+	Type ColumnType `json:"type"`
+}
+
+// Schema declares the columns of a table. Rows contain one value per
+// column in the order of the schema, nil values are written as null.
+type Schema []Column
+
+// Writer writes rows of a declared schema. Close must be called to
+// flush buffered rows, it doesn't close the underlying writer.
+type Writer interface {
+	Write(row []any) error
+	Close() error
+}
+
+func NewWriter(w io.Writer, format Format, schema Schema) (Writer, error) {
+	names := make(map[string]bool, len(schema))
+	for _, c := range schema {
+		switch c.Type {
+		case String, Int, Float, Bool:
+		default:
+			return nil, fmt.Errorf("unknown type %s of column %s", c.Type, c.Name)
+		}
+		if names[c.Name] {
+			return nil, fmt.Errorf("duplicate column %s", c.Name)
+		}
+		names[c.Name] = true
+	}
+
+	switch format {
+	case CSV:
+		return newCsvWriter(w, schema)
+	case NDJSON:
+		return &ndjsonWriter{w: bufio.NewWriter(w), schema: schema}, nil
+	case Parquet:
+		return newParquetWriter(w, schema), nil
+	default:
+		return nil, fmt.Errorf("unknown format %s", format)
+	}
+}
+
+func checkRow(schema Schema, row []any) error {
+	if len(row) != len(schema) {
+		return fmt.Errorf("row has %d values, schema has %d columns", len(row), len(schema))
+	}
+	return nil
+}
+
+// converts v to the go type of the column. values of other types are
+// converted if possible, e.g., an int32 from the database to int64.
+func convert(v any, t ColumnType) (any, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	switch t {
+	case String:
+		switch x := v.(type) {
+		case string:
+			return x, nil
+		case bson.ObjectID:
+			return x.Hex(), nil
+		case fmt.Stringer:
+			return x.String(), nil
+		case bson.D, bson.A, map[string]any, []any:
+			b, err := json.Marshal(x)
+			return string(b), err
+		default:
+			return fmt.Sprint(x), nil
+		}
+	case Int:
+		switch x := v.(type) {
+		case int:
+			return int64(x), nil
+		case int32:
+			return int64(x), nil
+		case int64:
+			return x, nil
+		case float64:
+			return int64(x), nil
+		case string:
+			return strconv.ParseInt(x, 10, 64)
+		}
+	case Float:
+		switch x := v.(type) {
+		case float64:
+			return x, nil
+		case float32:
+			return float64(x), nil
+		case int:
+			return float64(x), nil
+		case int32:
+			return float64(x), nil
+		case int64:
+			return float64(x), nil
+		case string:
+			return strconv.ParseFloat(x, 64)
+		}
+	case Bool:
+		switch x := v.(type) {
+		case bool:
+			return x, nil
+		case string:
+			return strconv.ParseBool(x)
+		}
+	}
+
+	return nil, fmt.Errorf("unable to convert %T to %s", v, t)
+}
+
+type csvWriter struct {
+	w      *csv.Writer
+	schema Schema
+}
+
+func newCsvWriter(w io.Writer, schema Schema) (*csvWriter, error) {
+	cw := csvWriter{w: csv.NewWriter(w), schema: schema}
+
+	header := make([]string, len(schema))
+	for i, c := range schema {
+		header[i] = c.Name
+	}
+	if err := cw.w.Write(header); err != nil {
+		return nil, err
+	}
+
+	return &cw, nil
+}
+
+func (w *csvWriter) Write(row []any) error {
+	if err := checkRow(w.schema, row); err != nil {
+		return err
+	}
+
+	record := make([]string, len(row))
+	for i, v := range row {
+		c, err := convert(v, w.schema[i].Type)
+		if err != nil {
+			return fmt.Errorf("column %s: %w", w.schema[i].Name, err)
+		}
+		if c != nil {
+			record[i] = fmt.Sprint(c)
+		}
+	}
+
+	return w.w.Write(record)
+}
+
+func (w *csvWriter) Close() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+type ndjsonWriter struct {
+	w      *bufio.Writer
+	schema Schema
+}
+
+// writes one JSON object per row, keys are written in schema order
+func (w *ndjsonWriter) Write(row []any) error {
+	if err := checkRow(w.schema, row); err != nil {
+		return err
+	}
+
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, v := range row {
+		c, err := convert(v, w.schema[i].Type)
+		if err != nil {
+			return fmt.Errorf("column %s: %w", w.schema[i].Name, err)
+		}
+
+		key, _ := json.Marshal(w.schema[i].Name)
+		value, err := json.Marshal(c)
+		if err != nil {
+			return fmt.Errorf("column %s: %w", w.schema[i].Name, err)
+		}
+
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteString("}\n")
+
+	_, err := w.w.Write(b.Bytes())
+	return err
+}
+
+func (w *ndjsonWriter) Close() error {
+	return w.w.Flush()
+}
+
+type parquetWriter struct {
+	w      *parquet.Writer
+	schema Schema
+	// parquet orders the columns of a group by name, index of the
+	// parquet column for each column of the schema
+	index []int
+}
+
+func newParquetWriter(w io.Writer, schema Schema) *parquetWriter {
+	group := parquet.Group{}
+	for _, c := range schema {
+		var node parquet.Node
+		switch c.Type {
+		case String:
+			node = parquet.String()
+		case Int:
+			node = parquet.Int(64)
+		case Float:
+			node = parquet.Leaf(parquet.DoubleType)
+		case Bool:
+			node = parquet.Leaf(parquet.BooleanType)
+		}
+		group[c.Name] = parquet.Optional(node)
+	}
+
+	s := parquet.NewSchema("table", group)
+
+	index := make([]int, len(schema))
+	for i, c := range schema {
+		leaf, _ := s.Lookup(c.Name)
+		index[i] = leaf.ColumnIndex
+	}
+
+	return &parquetWriter{
+		w:      parquet.NewWriter(w, s, parquet.Compression(&parquet.Snappy)),
+		schema: schema,
+		index:  index,
+	}
+}
+
+func (w *parquetWriter) Write(row []any) error {
+	if err := checkRow(w.schema, row); err != nil {
+		return err
+	}
+
+	values := make(parquet.Row, len(row))
+	for i, v := range row {
+		c, err := convert(v, w.schema[i].Type)
+		if err != nil {
+			return fmt.Errorf("column %s: %w", w.schema[i].Name, err)
+		}
+
+		var value parquet.Value
+		switch x := c.(type) {
+		case nil:
+			values[w.index[i]] = parquet.NullValue().Level(0, 0, w.index[i])
+			continue
+		case string:
+			value = parquet.ByteArrayValue([]byte(x))
+		case int64:
+			value = parquet.Int64Value(x)
+		case float64:
+			value = parquet.DoubleValue(x)
+		case bool:
+			value = parquet.BooleanValue(x)
+		}
+		// optional columns are defined on level 1
+		values[w.index[i]] = value.Level(0, 1, w.index[i])
+	}
+
+	_, err := w.w.WriteRows([]parquet.Row{values})
+	return err
+}
+
+func (w *parquetWriter) Close() error {
+	return w.w.Close()
+}
+
+// RecordWriter writes records of type T as rows of a Writer
+type RecordWriter[T any] struct {
+	w   Writer
+	row func(T) []any
+}
+
+func NewRecordWriter[T any](w Writer, row func(T) []any) *RecordWriter[T] {
+	return &RecordWriter[T]{w: w, row: row}
+}
+
+func (r *RecordWriter[T]) Write(record T) error {
+	return r.w.Write(r.row(record))
+}
+
+func (r *RecordWriter[T]) Close() error {
+	return r.w.Close()
+}
@@ -0,0 +1,159 @@
+package table
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"sbom-processor/internal/sbom"
+
+	"github.com/parquet-go/parquet-go"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+var testSchema = Schema{
+	{Name: "name", Type: String},
+	{Name: "count", Type: Int},
+	{Name: "avg", Type: Float},
+	{Name: "ok", Type: Bool},
+}
+
+var testRows = [][]any{
+	{"openssl", int32(3), 1.5, true},
+	{"zlib, \"1g\"", nil, int64(2), false},
+}
+
+func write(t *testing.T, format Format) []byte {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, format, testSchema)
+	if err != nil {
+		t.Fatalf("no error expected %s", err)
+	}
+	for _, r := range testRows {
+		if err := w.Write(r); err != nil {
+			t.Fatalf("no error expected %s", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("no error expected %s", err)
+	}
+	return buf.Bytes()
+}
+
+func TestCsvWriter(t *testing.T) {
+	expected := "name,count,avg,ok\nopenssl,3,1.5,true\n\"zlib, \"\"1g\"\"\",,2,false\n"
+	if out := string(write(t, CSV)); out != expected {
+		t.Fatalf("unexpected csv\n%s", out)
+	}
+}
+
+func TestNdjsonWriter(t *testing.T) {
+	expected := `{"name":"openssl","count":3,"avg":1.5,"ok":true}` + "\n" +
+		`{"name":"zlib, \"1g\"","count":null,"avg":2,"ok":false}` + "\n"
+	if out := string(write(t, NDJSON)); out != expected {
+		t.Fatalf("unexpected ndjson\n%s", out)
+	}
+}
+
+func TestParquetWriter(t *testing.T) {
+	type record struct {
+		Name  *string  `parquet:"name,optional"`
+		Count *int64   `parquet:"count,optional"`
+		Avg   *float64 `parquet:"avg,optional"`
+		Ok    *bool    `parquet:"ok,optional"`
+	}
+
+	out := write(t, Parquet)
+	records, err := parquet.Read[record](bytes.NewReader(out), int64(len(out)))
+	if err != nil {
+		t.Fatalf("invalid parquet file %s", err)
+	}
+
+	if len(records) != 2 {
+		t.Fatalf("two records expected, got %d", len(records))
+	}
+
+	if *records[0].Name != "openssl" || *records[0].Count != 3 || *records[0].Avg != 1.5 || !*records[0].Ok {
+		t.Fatalf("unexpected record %+v", records[0])
+	}
+	if records[1].Count != nil || *records[1].Avg != 2 {
+		t.Fatalf("unexpected record %+v", records[1])
+	}
+}
+
+func TestWriterErrors(t *testing.T) {
+	var buf bytes.Buffer
+	if _, err := NewWriter(&buf, CSV, Schema{{Name: "a", Type: "date"}}); err == nil {
+		t.Fatalf("error expected for unknown column type")
+	}
+	if _, err := NewWriter(&buf, CSV, Schema{{Name: "a", Type: String}, {Name: "a", Type: Int}}); err == nil {
+		t.Fatalf("error expected for duplicate column")
+	}
+	if _, err := ParseFormat("xlsx"); err == nil {
+		t.Fatalf("error expected for unknown format")
+	}
+
+	w, _ := NewWriter(&buf, NDJSON, testSchema)
+	if err := w.Write([]any{"a"}); err == nil {
+		t.Fatalf("error expected for incomplete row")
+	}
+	if err := w.Write([]any{"a", "three", nil, nil}); err == nil {
+		t.Fatalf("error expected for invalid int")
+	}
+}
+
+func TestInferSchema(t *testing.T) {
+	doc := bson.D{
+		{Key: "_id", Value: bson.D{{Key: "name", Value: "openssl"}, {Key: "type", Value: "deb"}}},
+		{Key: "images", Value: int32(4)},
+		{Key: "versions", Value: bson.A{"1", "2"}},
+	}
+
+	schema := InferSchema(doc)
+	expected := Schema{{"_id.name", String}, {"_id.type", String}, {"images", Int}, {"versions", String}}
+	if len(schema) != len(expected) {
+		t.Fatalf("unexpected schema %v", schema)
+	}
+	for i := range expected {
+		if schema[i] != expected[i] {
+			t.Fatalf("unexpected schema %v", schema)
+		}
+	}
+
+	row := Row(append(schema, Column{Name: "missing", Type: String}), doc)
+	if row[0] != "openssl" || row[2] != int32(4) || row[4] != nil {
+		t.Fatalf("unexpected row %v", row)
+	}
+
+	var buf bytes.Buffer
+	w, _ := NewWriter(&buf, CSV, schema)
+	w.Write(Row(schema, doc))
+	w.Close()
+	if !strings.Contains(buf.String(), `openssl,deb,4,"[""1"",""2""]"`) {
+		t.Fatalf("unexpected csv %s", buf.String())
+	}
+}
+
+func TestImageComponentRows(t *testing.T) {
+	s := sbom.StoredSbom{
+		Id: bson.NewObjectID(),
+		CyclonedxSbom: sbom.CyclonedxSbom{
+			Source: sbom.Source{Name: "nginx:1.25", Version: "sha256:abc",
+				Metadata: sbom.Metadata{Labels: map[string]string{"org.opencontainers.image.vendor": "NGINX Inc."}}},
+			Distro:     sbom.Distro{Id: "debian", Version: "12"},
+			Components: []sbom.Component{{Id: "1", Name: "openssl", Version: "3.0.11", Type: "deb"}},
+		},
+	}
+
+	rows := ImageComponentRows(&s)
+	if len(rows) != 1 || len(rows[0]) != len(ImageComponentSchema) {
+		t.Fatalf("one row per component expected %v", rows)
+	}
+
+	if rows[0][0] != s.Id.Hex() || rows[0][1] != "nginx:1.25" || rows[0][2] != "sha256:abc" || rows[0][4] != "debian" || rows[0][7] != "openssl" {
+		t.Fatalf("unexpected row %v", rows[0])
+	}
+	if rows[0][12] != "NGINX Inc." || rows[0][13] != nil {
+		t.Fatalf("expected the vendor and no source repository, got %v", rows[0][12:])
+	}
+}
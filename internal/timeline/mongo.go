@@ -0,0 +1,44 @@
+package timeline
+
+import (
+	"context"
+
+	"sbom-processor/internal/catalogue"
+	"sbom-processor/internal/lag"
+	"sbom-processor/internal/sbom"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// MongoLoader loads the SBOMs by their id and the latest
+// technical lag calculated for them.
+type MongoLoader struct {
+	Sboms *catalogue.Store
+	Lags  *mongo.Collection
+	Ctx   context.Context
+}
+
+func (l *MongoLoader) Sbom(e Entry) (*sbom.CyclonedxSbom, error) {
+	s, err := l.Sboms.Sbom(l.Ctx, e.SbomId)
+	if err != nil {
+		return nil, err
+	}
+	return &s.CyclonedxSbom, nil
+}
+
+func (l *MongoLoader) Lag(e Entry) (*lag.Aggregate, error) {
+	var res lag.SbomLag
+	err := l.Lags.FindOne(l.Ctx,
+		bson.D{{Key: "sbom_id", Value: e.SbomId}},
+		options.FindOne().SetSort(bson.D{{Key: "computed_at", Value: -1}}),
+	).Decode(&res)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &res.Aggregate, nil
+}
@@ -0,0 +1,194 @@
+package timeline
+
+import (
+	"slices"
+	"time"
+
+	"sbom-processor/internal/lag"
+	"sbom-processor/internal/sbom"
+	"sbom-processor/internal/semver"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// defines the order of the scans in a timeline
+type OrderBy string
+
+const (
+	// order by the version semantics of the tag, falls back to scan time
+	OrderByTag OrderBy = "tag"
+	// order by the time the scan was stored
+	OrderByTime OrderBy = "time"
+)
+
+// a single scan of a repository
+type Entry struct {
+	SbomId     bson.ObjectID `bson:"sbom_id" json:"sbom_id"`
+	Name       string        `bson:"name" json:"name"`
+	Repository string        `bson:"repository" json:"repository"`
+	Tag        string        `bson:"tag" json:"tag"`
+	Digest     string        `bson:"digest" json:"digest"`
+	ScannedAt  time.Time     `bson:"scanned_at" json:"scanned_at"`
+}
+
+type Churn struct {
+	Added      int `bson:"added" json:"added"`
+	Removed    int `bson:"removed" json:"removed"`
+	Upgraded   int `bson:"upgraded" json:"upgraded"`
+	Downgraded int `bson:"downgraded" json:"downgraded"`
+	Changed    int `bson:"changed" json:"changed"`
+	Unchanged  int `bson:"unchanged" json:"unchanged"`
+}
+
+type Step struct {
+	Entry      `bson:",inline"`
+	Components int `bson:"components" json:"components"`
+	// churn compared to the previous step, nil for the first step
+	Churn *Churn `bson:"churn,omitempty" json:"churn,omitempty"`
+	// nil if the technical lag wasn't calculated for the scan
+	Lag *lag.Aggregate `bson:"lag,omitempty" json:"lag,omitempty"`
+	// change of the mean missed releases compared to the previous
+	// step. nil if the lag of either step is unknown.
+	MeanMissedReleasesDelta *float64 `bson:"mean_missed_releases_delta,omitempty" json:"mean_missed_releases_delta,omitempty"`
+}
+
+type Timeline struct {
+	Repository string    `bson:"repository" json:"repository"`
+	OrderBy    OrderBy   `bson:"order_by" json:"order_by"`
+	BuiltAt    time.Time `bson:"built_at" json:"built_at"`
+	Steps      []Step    `bson:"steps" json:"steps"`
+}
+
+// NewEntry takes repository and tag from the image reference of the
+// source. Docker Hub names are normalized, e.g., docker.io/library/nginx:1.25
+// and nginx:1.25 both belong to the repository nginx. Sources that
+// aren't image references are grouped by their name. The time the
+// SBOM was inserted is used as scan time.
+func NewEntry(id bson.ObjectID, source sbom.Source) Entry {
+	e := Entry{
+		SbomId:     id,
+		Name:       source.Name,
+		Repository: source.Name,
+		Digest:     source.Version,
+		ScannedAt:  id.Timestamp().UTC(),
+	}
+	if r := source.ImageReference(); r != nil {
+		e.Repository = r.Familiar()
+		e.Tag = r.Tag
+	}
+	return e
+}
+
+// Group groups the entries by repository. The repositories are
+// returned in order of appearance.
+func Group(entries []Entry) (map[string][]Entry, []string) {
+	groups := make(map[string][]Entry)
+	var repos []string
+	for _, e := range entries {
+		if _, ok := groups[e.Repository]; !ok {
+			repos = append(repos, e.Repository)
+		}
+		groups[e.Repository] = append(groups[e.Repository], e)
+	}
+	return groups, repos
+}
+
+// Order sorts the entries in place. Ordered by tag, tags that can be
+// parsed as version come first in ascending order, all others follow
+// ordered by scan time.
+func Order(entries []Entry, by OrderBy) {
+	byTime := func(a, b Entry) int {
+		return a.ScannedAt.Compare(b.ScannedAt)
+	}
+
+	if by == OrderByTime {
+		slices.SortStableFunc(entries, byTime)
+		return
+	}
+
+	slices.SortStableFunc(entries, func(a, b Entry) int {
+		cmp, err := semver.Compare(a.Tag, b.Tag)
+		if err == nil {
+			if cmp != 0 {
+				return cmp
+			}
+			return byTime(a, b)
+		}
+
+		_, errA := semver.Compare(a.Tag, a.Tag)
+		_, errB := semver.Compare(b.Tag, b.Tag)
+		switch {
+		case errA == nil:
+			return -1
+		case errB == nil:
+			return 1
+		default:
+			return byTime(a, b)
+		}
+	})
+}
+
+// Loader provides the SBOM and the technical lag of an entry.
+// A nil lag without error means the lag wasn't calculated.
+type Loader interface {
+	Sbom(e Entry) (*sbom.CyclonedxSbom, error)
+	Lag(e Entry) (*lag.Aggregate, error)
+}
+
+// Build orders the entries and compares each scan with its predecessor.
+// Only two SBOMs are kept in memory at a time.
+func Build(repository string, entries []Entry, by OrderBy, matchBy sbom.MatchBy, loader Loader) (*Timeline, error) {
+	entries = slices.Clone(entries)
+	Order(entries, by)
+
+	t := Timeline{
+		Repository: repository,
+		OrderBy:    by,
+		BuiltAt:    time.Now().UTC(),
+		Steps:      make([]Step, 0, len(entries)),
+	}
+
+	var prev *sbom.CyclonedxSbom
+	var prevLag *lag.Aggregate
+
+	for _, e := range entries {
+		curr, err := loader.Sbom(e)
+		if err != nil {
+			return nil, err
+		}
+
+		currLag, err := loader.Lag(e)
+		if err != nil {
+			return nil, err
+		}
+
+		step := Step{
+			Entry:      e,
+			Components: len(curr.Components),
+			Lag:        currLag,
+		}
+
+		if prev != nil {
+			d := sbom.Diff(prev, curr, sbom.DiffOptions{MatchBy: matchBy})
+			step.Churn = &Churn{
+				Added:      len(d.Added),
+				Removed:    len(d.Removed),
+				Upgraded:   len(d.Upgraded),
+				Downgraded: len(d.Downgraded),
+				Changed:    len(d.Changed),
+				Unchanged:  d.Unchanged,
+			}
+		}
+
+		if prevLag != nil && currLag != nil {
+			delta := currLag.MeanMissedReleases - prevLag.MeanMissedReleases
+			step.MeanMissedReleasesDelta = &delta
+		}
+
+		t.Steps = append(t.Steps, step)
+		prev = curr
+		prevLag = currLag
+	}
+
+	return &t, nil
+}
@@ -0,0 +1,122 @@
+package timeline
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"sbom-processor/internal/lag"
+	"sbom-processor/internal/sbom"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestNewEntry(t *testing.T) {
+	tests := []struct {
+		name, repo, tag string
+	}{
+		{"nginx:1.25", "nginx", "1.25"},
+		{"docker.io/library/nginx:1.25", "nginx", "1.25"},
+		{"bitnami/redis", "bitnami/redis", ""},
+		{"localhost:5000/foo:1.0", "localhost:5000/foo", "1.0"},
+		{"ghcr.io/org/app@sha256:abc", "ghcr.io/org/app", ""},
+		{"index.docker.io/library/nginx:1.25", "nginx", "1.25"},
+		{"registry.example.com:443/team/app:2.0", "registry.example.com:443/team/app", "2.0"},
+		// not an image reference
+		{"/tmp/rootfs", "/tmp/rootfs", ""},
+	}
+
+	for _, test := range tests {
+		e := NewEntry(bson.NewObjectID(), sbom.Source{Name: test.name})
+		if e.Repository != test.repo || e.Tag != test.tag {
+			t.Fatalf("unexpected split of %s: %s %s", test.name, e.Repository, e.Tag)
+		}
+	}
+}
+
+func TestOrder(t *testing.T) {
+	now := time.Now()
+	entries := []Entry{
+		{Tag: "latest", ScannedAt: now},
+		{Tag: "1.10.0", ScannedAt: now.Add(time.Minute)},
+		{Tag: "stable", ScannedAt: now.Add(-time.Minute)},
+		{Tag: "1.9.2", ScannedAt: now.Add(2 * time.Minute)},
+	}
+
+	Order(entries, OrderByTag)
+	tags := []string{}
+	for _, e := range entries {
+		tags = append(tags, e.Tag)
+	}
+	if !slices.Equal(tags, []string{"1.9.2", "1.10.0", "stable", "latest"}) {
+		t.Fatalf("unexpected tag order %v", tags)
+	}
+
+	Order(entries, OrderByTime)
+	tags = []string{}
+	for _, e := range entries {
+		tags = append(tags, e.Tag)
+	}
+	if !slices.Equal(tags, []string{"stable", "latest", "1.10.0", "1.9.2"}) {
+		t.Fatalf("unexpected time order %v", tags)
+	}
+}
+
+type mapLoader struct {
+	sboms map[string]*sbom.CyclonedxSbom
+	lags  map[string]*lag.Aggregate
+}
+
+func (m *mapLoader) Sbom(e Entry) (*sbom.CyclonedxSbom, error) {
+	s, ok := m.sboms[e.Tag]
+	if !ok {
+		return nil, fmt.Errorf("unknown sbom %s", e.Tag)
+	}
+	return s, nil
+}
+
+func (m *mapLoader) Lag(e Entry) (*lag.Aggregate, error) {
+	return m.lags[e.Tag], nil
+}
+
+func TestBuild(t *testing.T) {
+	loader := mapLoader{
+		sboms: map[string]*sbom.CyclonedxSbom{
+			"1.0": {Components: []sbom.Component{{Name: "a", Version: "1.0"}, {Name: "b", Version: "1.0"}}},
+			"1.1": {Components: []sbom.Component{{Name: "a", Version: "1.1"}, {Name: "c", Version: "1.0"}}},
+		},
+		lags: map[string]*lag.Aggregate{
+			"1.0": {MeanMissedReleases: 1},
+			"1.1": {MeanMissedReleases: 3.5},
+		},
+	}
+
+	entries := []Entry{{Tag: "1.1"}, {Tag: "1.0"}}
+	tl, err := Build("repo", entries, OrderByTag, sbom.MatchByName, &loader)
+	if err != nil {
+		t.Fatalf("no error expected %s", err)
+	}
+
+	if len(tl.Steps) != 2 || tl.Steps[0].Tag != "1.0" {
+		t.Fatalf("unexpected steps %+v", tl.Steps)
+	}
+
+	if tl.Steps[0].Churn != nil || tl.Steps[0].MeanMissedReleasesDelta != nil {
+		t.Fatalf("first step must not have a predecessor")
+	}
+
+	churn := tl.Steps[1].Churn
+	if churn == nil || churn.Added != 1 || churn.Removed != 1 || churn.Upgraded != 1 {
+		t.Fatalf("unexpected churn %+v", churn)
+	}
+
+	if *tl.Steps[1].MeanMissedReleasesDelta != 2.5 {
+		t.Fatalf("unexpected lag delta %f", *tl.Steps[1].MeanMissedReleasesDelta)
+	}
+
+	entries = append(entries, Entry{Tag: "2.0"})
+	if _, err := Build("repo", entries, OrderByTag, sbom.MatchByName, &loader); err == nil {
+		t.Fatalf("error expected for missing sbom")
+	}
+}
@@ -0,0 +1,424 @@
+package validator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"sbom-processor/internal/purl"
+	"sbom-processor/internal/sbom"
+)
+
+// Severity of a rule. Rules set to Off aren't checked.
+type Severity int
+
+const (
+	Off Severity = iota
+	Info
+	Warning
+	Error
+)
+
+var severities = []string{"off", "info", "warning", "error"}
+
+func (s Severity) String() string {
+	if s < Off || s > Error {
+		return fmt.Sprintf("severity(%d)", int(s))
+	}
+	return severities[s]
+}
+
+func (s Severity) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+func ParseSeverity(s string) (Severity, error) {
+	i := slices.Index(severities, strings.ToLower(strings.TrimSpace(s)))
+	if i < 0 {
+		return Off, fmt.Errorf("unknown severity %q, choose off, info, warning, or error", s)
+	}
+	return Severity(i), nil
+}
+
+// Format of the validated file
+type Format string
+
+const (
+	Syft      Format = "syft"
+	Cyclonedx Format = "cyclonedx"
+)
+
+// Document is the format independent view of an SBOM the rules
+// are checked against
+type Document struct {
+	Format        Format
+	Components    []sbom.Component
+	Relationships []sbom.ArtifactRelationship
+	// ids besides the components relationships may refer to,
+	// i.e., the files of syft SBOMs
+	Files  map[string]bool
+	Source sbom.Source
+	Distro sbom.Distro
+	// top-level arrays that are null or absent
+	Missing []string
+
+	// only set for cyclonedx, used to verify the digest
+	cyclonedx *sbom.CyclonedxSbom
+}
+
+// Decode decodes a syft or cyclonedx SBOM. The format is detected
+// from the top-level arrays if it's empty.
+func Decode(data []byte, format Format) (*Document, error) {
+	if format == "" {
+		var keys struct {
+			Artifacts  json.RawMessage `json:"artifacts"`
+			Components json.RawMessage `json:"components"`
+		}
+		if err := json.Unmarshal(data, &keys); err != nil {
+			return nil, err
+		}
+		switch {
+		case keys.Artifacts != nil:
+			format = Syft
+		case keys.Components != nil:
+			format = Cyclonedx
+		default:
+			return nil, fmt.Errorf("neither syft nor cyclonedx, found no artifacts or components")
+		}
+	}
+
+	switch format {
+	case Syft:
+		var s struct {
+			sbom.SyftSbom
+			Files []struct {
+				Id string `json:"id"`
+			} `json:"files"`
+		}
+		if err := json.Unmarshal(data, &s); err != nil {
+			return nil, err
+		}
+		d := &Document{
+			Format:        Syft,
+			Components:    s.Artifacts,
+			Relationships: s.ArtifactRelationships,
+			Files:         make(map[string]bool, len(s.Files)),
+			Source:        s.Source,
+			Distro:        s.Distro,
+		}
+		for _, f := range s.Files {
+			d.Files[f.Id] = true
+		}
+		if s.Artifacts == nil {
+			d.Missing = append(d.Missing, "artifacts")
+		}
+		if s.ArtifactRelationships == nil {
+			d.Missing = append(d.Missing, "artifactRelationships")
+		}
+		return d, nil
+
+	case Cyclonedx:
+		var c sbom.CyclonedxSbom
+		if err := json.Unmarshal(data, &c); err != nil {
+			return nil, err
+		}
+		d := &Document{
+			Format:     Cyclonedx,
+			Components: c.Components,
+			Source:     c.Source,
+			Distro:     c.Distro,
+			cyclonedx:  &c,
+		}
+		for _, dep := range c.Dependencies {
+			for _, t := range dep.DependsOn {
+				d.Relationships = append(d.Relationships, sbom.ArtifactRelationship{Parent: dep.Ref, Child: t.Child, Type: t.Type})
+			}
+		}
+		if c.Components == nil {
+			d.Missing = append(d.Missing, "components")
+		}
+		if c.Dependencies == nil {
+			d.Missing = append(d.Missing, "dependencies")
+		}
+		return d, nil
+	}
+
+	return nil, fmt.Errorf("unknown format %q, choose syft or cyclonedx", format)
+}
+
+// Finding of a rule, Ref is the id of the component or relationship
+// parent the finding refers to, if any
+type Finding struct {
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	Ref      string   `json:"ref,omitempty"`
+	Message  string   `json:"message"`
+}
+
+type Rule struct {
+	Name        string
+	Severity    Severity
+	Description string
+	// returns the findings without severity
+	check func(d *Document) []Finding
+}
+
+// Rules returns all rules with their default severity
+func Rules() []Rule {
+	return []Rule{
+		{"missing-array", Error, "the components or relationships array is null or absent, the readers reject the file", missingArray},
+		{"empty-components", Warning, "the SBOM contains no components", emptyComponents},
+		{"missing-id", Error, "a component has no id", components(func(c *sbom.Component) string {
+			return message(c.Id == "", "component %s has no id", c.Name)
+		})},
+		{"duplicate-id", Error, "several components share an id", duplicateIds},
+		{"missing-name", Error, "a component has no name", components(func(c *sbom.Component) string {
+			return message(c.Name == "", "component has no name")
+		})},
+		{"missing-version", Warning, "a component has no version", components(func(c *sbom.Component) string {
+			return message(c.Version == "", "component %s has no version", c.Name)
+		})},
+		{"missing-type", Warning, "a component has no type", components(func(c *sbom.Component) string {
+			return message(c.Type == "", "component %s has no type", c.Name)
+		})},
+		{"invalid-purl", Warning, "the purl of a component can't be parsed", components(func(c *sbom.Component) string {
+			if c.Purl == "" {
+				return ""
+			}
+			if _, err := purl.Parse(c.Purl); err != nil {
+				return err.Error()
+			}
+			return ""
+		})},
+		{"dangling-ref", Error, "a relationship refers to an id that is neither a component nor a file", danglingRefs},
+		{"self-reference", Warning, "a relationship refers to its own parent", relationships(func(r *sbom.ArtifactRelationship) string {
+			return message(r.Parent == r.Child, "%s relationship to itself", r.Type)
+		})},
+		{"duplicate-relationship", Info, "the same relationship is listed several times", duplicateRelationships},
+		{"empty-source", Error, "the source block is empty", emptySource},
+		{"missing-source-id", Error, "the source has no id, SBOMs are identified by it", missingSourceId},
+		{"missing-source-name", Warning, "the source has no name", func(d *Document) []Finding {
+			return single(d.Source.Name == "" && d.Source.Id != "", "source %s has no name", d.Source.Id)
+		}},
+		{"missing-distro", Info, "the distro wasn't detected", func(d *Document) []Finding {
+			return single(d.Distro.Id == "", "no distro")
+		}},
+		{"digest-mismatch", Error, "the digest of a cyclonedx SBOM doesn't match its content", digestMismatch},
+	}
+}
+
+// ParseSeverities parses severity overrides given as rule=severity
+// pairs separated by commas, e.g., missing-version=error,missing-distro=off
+func ParseSeverities(s string) (map[string]Severity, error) {
+	res := make(map[string]Severity)
+	if strings.TrimSpace(s) == "" {
+		return res, nil
+	}
+
+	names := make(map[string]bool)
+	for _, r := range Rules() {
+		names[r.Name] = true
+	}
+
+	for _, pair := range strings.Split(s, ",") {
+		name, sev, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || !names[name] {
+			return nil, fmt.Errorf("invalid rule severity %q, expected rule=severity with a known rule", pair)
+		}
+		severity, err := ParseSeverity(sev)
+		if err != nil {
+			return nil, err
+		}
+		res[name] = severity
+	}
+	return res, nil
+}
+
+// Validate checks d against all rules, overrides replace the
+// default severity of the rules
+func Validate(d *Document, overrides map[string]Severity) []Finding {
+	var res []Finding
+	for _, r := range Rules() {
+		severity := r.Severity
+		if s, ok := overrides[r.Name]; ok {
+			severity = s
+		}
+		if severity == Off {
+			continue
+		}
+
+		for _, f := range r.check(d) {
+			f.Rule = r.Name
+			f.Severity = severity
+			res = append(res, f)
+		}
+	}
+	return res
+}
+
+// Highest returns the highest severity of the findings, Off if
+// there are none
+func Highest(findings []Finding) Severity {
+	res := Off
+	for _, f := range findings {
+		res = max(res, f.Severity)
+	}
+	return res
+}
+
+// FileReport is the validation result of a single file
+type FileReport struct {
+	File     string    `json:"file"`
+	Format   Format    `json:"format,omitempty"`
+	Passed   bool      `json:"passed"`
+	Error    string    `json:"error,omitempty"`
+	Findings []Finding `json:"findings"`
+}
+
+// ValidateFile reads and validates the file at p. It passes if
+// no finding reaches failOn, unreadable files never pass.
+func ValidateFile(p string, format Format, overrides map[string]Severity, failOn Severity) *FileReport {
+	res := &FileReport{File: p, Findings: []Finding{}}
+
+	data, err := os.ReadFile(p)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	d, err := Decode(data, format)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+
+	res.Format = d.Format
+	res.Findings = append(res.Findings, Validate(d, overrides)...)
+	res.Passed = Highest(res.Findings) < failOn
+	return res
+}
+
+func message(failed bool, format string, args ...any) string {
+	if !failed {
+		return ""
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+func single(failed bool, format string, args ...any) []Finding {
+	if !failed {
+		return nil
+	}
+	return []Finding{{Message: fmt.Sprintf(format, args...)}}
+}
+
+// components creates a check that reports each component for which
+// check returns a message
+func components(check func(c *sbom.Component) string) func(d *Document) []Finding {
+	return func(d *Document) []Finding {
+		var res []Finding
+		for i := range d.Components {
+			if m := check(&d.Components[i]); m != "" {
+				res = append(res, Finding{Ref: d.Components[i].Id, Message: m})
+			}
+		}
+		return res
+	}
+}
+
+func relationships(check func(r *sbom.ArtifactRelationship) string) func(d *Document) []Finding {
+	return func(d *Document) []Finding {
+		var res []Finding
+		for i := range d.Relationships {
+			if m := check(&d.Relationships[i]); m != "" {
+				res = append(res, Finding{Ref: d.Relationships[i].Parent, Message: m})
+			}
+		}
+		return res
+	}
+}
+
+func missingArray(d *Document) []Finding {
+	var res []Finding
+	for _, m := range d.Missing {
+		res = append(res, Finding{Message: m + " is missing"})
+	}
+	return res
+}
+
+func emptyComponents(d *Document) []Finding {
+	// a missing array is reported by missing-array
+	return single(d.Components != nil && len(d.Components) == 0, "no components")
+}
+
+func duplicateIds(d *Document) []Finding {
+	var res []Finding
+	count := make(map[string]int, len(d.Components))
+	for _, c := range d.Components {
+		if c.Id == "" {
+			continue
+		}
+		count[c.Id]++
+		// report every duplicate id once
+		if count[c.Id] == 2 {
+			res = append(res, Finding{Ref: c.Id, Message: fmt.Sprintf("id %s is used by several components", c.Id)})
+		}
+	}
+	return res
+}
+
+func danglingRefs(d *Document) []Finding {
+	ids := make(map[string]bool, len(d.Components))
+	for _, c := range d.Components {
+		ids[c.Id] = true
+	}
+
+	var res []Finding
+	reported := make(map[string]bool)
+	for _, r := range d.Relationships {
+		for _, id := range []string{r.Parent, r.Child} {
+			if ids[id] || d.Files[id] || reported[id] {
+				continue
+			}
+			reported[id] = true
+			res = append(res, Finding{Ref: id, Message: fmt.Sprintf("%s relationship refers to unknown id %s", r.Type, id)})
+		}
+	}
+	return res
+}
+
+func duplicateRelationships(d *Document) []Finding {
+	var res []Finding
+	count := make(map[sbom.ArtifactRelationship]int, len(d.Relationships))
+	for _, r := range d.Relationships {
+		count[r]++
+		if count[r] == 2 {
+			res = append(res, Finding{Ref: r.Parent, Message: fmt.Sprintf("%s relationship to %s is listed several times", r.Type, r.Child)})
+		}
+	}
+	return res
+}
+
+func emptySource(d *Document) []Finding {
+	s := d.Source
+	return single(s.Id == "" && s.Name == "" && s.Version == "", "source is empty")
+}
+
+func missingSourceId(d *Document) []Finding {
+	s := d.Source
+	// an empty source is reported by empty-source
+	return single(s.Id == "" && (s.Name != "" || s.Version != ""), "source %s has no id", s.Name)
+}
+
+func digestMismatch(d *Document) []Finding {
+	if d.cyclonedx == nil || d.cyclonedx.Digest == "" {
+		return nil
+	}
+
+	content, err := d.cyclonedx.ContentDigest()
+	if err != nil {
+		return []Finding{{Message: err.Error()}}
+	}
+	return single(content != d.cyclonedx.Digest, "digest %s doesn't match the content %s", d.cyclonedx.Digest, content)
+}
@@ -0,0 +1,130 @@
+package validator
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"sbom-processor/internal/sbom"
+)
+
+const brokenSyft = `{
+	"artifacts": [
+		{"id": "a", "name": "openssl", "type": "deb", "version": "3.0.11", "purl": "pkg:deb/debian/openssl@3.0.11"},
+		{"id": "a", "name": "libc", "type": "deb"},
+		{"id": "b", "name": "guava", "type": "java-archive", "version": "32.0.0", "purl": "guava"}
+	],
+	"artifactRelationships": [
+		{"parent": "a", "child": "file-1", "type": "contains"},
+		{"parent": "b", "child": "file-2", "type": "evident-by"},
+		{"parent": "b", "child": "b", "type": "dependency-of"}
+	],
+	"files": [{"id": "file-1"}],
+	"source": {}
+}`
+
+func rules(findings []Finding) []string {
+	var res []string
+	for _, f := range findings {
+		res = append(res, f.Rule)
+	}
+	return res
+}
+
+func TestValidateSyft(t *testing.T) {
+	d, err := Decode([]byte(brokenSyft), "")
+	if err != nil {
+		t.Fatalf("decode failed %s", err)
+	}
+	if d.Format != Syft {
+		t.Fatalf("expected syft, got %s", d.Format)
+	}
+
+	findings := Validate(d, nil)
+	expected := []string{"duplicate-id", "missing-version", "invalid-purl", "dangling-ref", "self-reference", "empty-source", "missing-distro"}
+	if !slices.Equal(rules(findings), expected) {
+		t.Fatalf("expected %v, got %+v", expected, findings)
+	}
+	// file-1 is a syft file
+	if findings[3].Ref != "file-2" {
+		t.Fatalf("expected file-2 to be dangling, got %+v", findings[3])
+	}
+	if Highest(findings) != Error {
+		t.Fatalf("expected error, got %s", Highest(findings))
+	}
+}
+
+func TestValidateOverrides(t *testing.T) {
+	d, _ := Decode([]byte(brokenSyft), Syft)
+	overrides, err := ParseSeverities("duplicate-id=off, dangling-ref=off,empty-source=info,missing-version=error")
+	if err != nil {
+		t.Fatalf("parse failed %s", err)
+	}
+
+	findings := Validate(d, overrides)
+	if slices.Contains(rules(findings), "duplicate-id") || slices.Contains(rules(findings), "dangling-ref") {
+		t.Fatalf("expected the rules to be off, got %+v", findings)
+	}
+	if findings[0].Rule != "missing-version" || findings[0].Severity != Error {
+		t.Fatalf("expected missing-version as error, got %+v", findings[0])
+	}
+
+	for _, invalid := range []string{"unknown=off", "duplicate-id=fatal", "duplicate-id"} {
+		if _, err := ParseSeverities(invalid); err == nil {
+			t.Fatalf("expected an error for %q", invalid)
+		}
+	}
+}
+
+func TestValidateMissingArrays(t *testing.T) {
+	d, err := Decode([]byte(`{"components": null, "source": {"id": "x", "name": "app"}, "distro": {"id": "debian"}}`), "")
+	if err != nil {
+		t.Fatalf("decode failed %s", err)
+	}
+	findings := Validate(d, nil)
+	if !slices.Equal(rules(findings), []string{"missing-array", "missing-array"}) {
+		t.Fatalf("expected components and dependencies to be missing, got %+v", findings)
+	}
+
+	if _, err := Decode([]byte(`{"source": {}}`), ""); err == nil {
+		t.Fatalf("expected an error for an unknown format")
+	}
+}
+
+func TestValidateFileDigest(t *testing.T) {
+	c := &sbom.CyclonedxSbom{
+		Components:   []sbom.Component{{Id: "a", Name: "openssl", Type: "deb", Version: "3.0.11"}},
+		Dependencies: []sbom.Dependency{},
+		Source:       sbom.Source{Id: "sha256:image", Name: "app"},
+		Distro:       sbom.Distro{Id: "debian", Version: "12"},
+	}
+	if err := c.Canonicalize(); err != nil {
+		t.Fatalf("canonicalize failed %s", err)
+	}
+
+	p := filepath.Join(t.TempDir(), c.FileName())
+	write := func() {
+		data, _ := json.Marshal(c)
+		if err := os.WriteFile(p, data, 0o644); err != nil {
+			t.Fatalf("write failed %s", err)
+		}
+	}
+
+	write()
+	if r := ValidateFile(p, "", nil, Warning); !r.Passed || len(r.Findings) != 0 {
+		t.Fatalf("expected the file to pass, got %+v", r)
+	}
+
+	c.Components[0].Version = "3.0.12"
+	write()
+	r := ValidateFile(p, "", nil, Warning)
+	if r.Passed || !slices.Equal(rules(r.Findings), []string{"digest-mismatch"}) {
+		t.Fatalf("expected a digest mismatch, got %+v", r)
+	}
+
+	if r := ValidateFile(filepath.Join(t.TempDir(), "missing.json"), "", nil, Error); r.Passed || r.Error == "" {
+		t.Fatalf("expected unreadable files to fail, got %+v", r)
+	}
+}